@@ -0,0 +1,54 @@
+// Package auth resolves API tokens to users and carries the caller through
+// request contexts, for every transport the service speaks.
+package auth
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "task_manager/data"
+    "task_manager/models"
+)
+
+type userKey struct{}
+
+func WithUser(ctx context.Context, user *models.User) context.Context {
+    return context.WithValue(ctx, userKey{}, user)
+}
+
+// User returns the caller stored by WithUser, if any.
+func User(ctx context.Context) (*models.User, bool) {
+    user, ok := ctx.Value(userKey{}).(*models.User)
+    return user, ok && user != nil
+}
+
+// ClientKey identifies a caller for rate limits and quotas: the user, else
+// the client's IP address. Every transport keys clients this way, so they
+// share their limits.
+func ClientKey(user *models.User, ip string) string {
+    if user != nil {
+        return "user:" + user.ID.String()
+    }
+    return "ip:" + ip
+}
+
+// Authenticate resolves an "Authorization: Bearer <token>" value. An empty
+// value is an anonymous caller and yields no user and no error; a malformed
+// or unknown token is an ErrUnauthorized.
+func Authenticate(ctx context.Context, users *data.UserRepo, header string) (*models.User, error) {
+    if header == "" {
+        return nil, nil
+    }
+    token, ok := strings.CutPrefix(header, "Bearer ")
+    if !ok || token == "" {
+        return nil, models.NewError(models.ErrUnauthorized, "malformed authorization header")
+    }
+    user, err := users.GetByToken(ctx, token)
+    if errors.Is(err, models.ErrNotFound) {
+        return nil, models.NewError(models.ErrUnauthorized, "invalid token")
+    }
+    if err != nil {
+        return nil, err
+    }
+    return user, nil
+}
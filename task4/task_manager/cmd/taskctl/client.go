@@ -0,0 +1,144 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// client calls the task_manager REST API under /api/v1.
+type client struct {
+    base  string
+    token string
+    http  *http.Client
+}
+
+func newClient(server, token string) *client {
+    return &client{
+        base:  strings.TrimRight(server, "/") + "/api/v1",
+        token: token,
+        http:  &http.Client{Timeout: 30 * time.Second},
+    }
+}
+
+// apiError is a problem+json response from the server.
+type apiError struct {
+    Title  string              `json:"title"`
+    Status int                 `json:"status"`
+    Detail string              `json:"detail"`
+    Code   string              `json:"code"`
+    Errors []models.FieldError `json:"errors"`
+}
+
+func (e *apiError) Error() string {
+    msg := e.Title
+    if e.Detail != "" {
+        msg = e.Detail
+    }
+    for _, f := range e.Errors {
+        msg += fmt.Sprintf("\n  %s: %s", f.Field, f.Message)
+    }
+    return msg
+}
+
+// taskInput holds the fields of a task clients may set. Unset fields are
+// left out, so that an update leaves them unchanged.
+type taskInput struct {
+    Name        string            `json:"name,omitempty"`
+    Description *string           `json:"description,omitempty"`
+    Status      models.State      `json:"status,omitempty"`
+    Priority    models.Importance `json:"priority,omitempty"`
+    DueDate     *time.Time        `json:"due_date,omitempty"`
+    Assignees   []uuid.UUID       `json:"assignees,omitempty"`
+    Watchers    []uuid.UUID       `json:"watchers,omitempty"`
+}
+
+func (c *client) ListTasks(ctx context.Context) ([]models.Task, error) {
+    var tasks []models.Task
+    err := c.do(ctx, http.MethodGet, "/tasks", nil, &tasks)
+    return tasks, err
+}
+
+// MyTasks lists the tasks assigned to the token's user.
+func (c *client) MyTasks(ctx context.Context) ([]models.Task, error) {
+    var tasks []models.Task
+    err := c.do(ctx, http.MethodGet, "/me/tasks", nil, &tasks)
+    return tasks, err
+}
+
+func (c *client) GetTask(ctx context.Context, id string) (*models.Task, error) {
+    var task models.Task
+    if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, &task); err != nil {
+        return nil, err
+    }
+    return &task, nil
+}
+
+func (c *client) CreateTask(ctx context.Context, task taskInput) (*models.Task, error) {
+    var created models.Task
+    if err := c.do(ctx, http.MethodPost, "/tasks", task, &created); err != nil {
+        return nil, err
+    }
+    return &created, nil
+}
+
+// UpdateTask applies the non-empty fields of changes.
+func (c *client) UpdateTask(ctx context.Context, id string, changes taskInput) (*models.Task, error) {
+    var updated models.Task
+    if err := c.do(ctx, http.MethodPut, "/tasks/"+url.PathEscape(id), changes, &updated); err != nil {
+        return nil, err
+    }
+    return &updated, nil
+}
+
+func (c *client) DeleteTask(ctx context.Context, id string) error {
+    return c.do(ctx, http.MethodDelete, "/tasks/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *client) do(ctx context.Context, method, path string, in, out any) error {
+    var body io.Reader
+    if in != nil {
+        b, err := json.Marshal(in)
+        if err != nil {
+            return err
+        }
+        body = bytes.NewReader(b)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", "application/json")
+    if in != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    if c.token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.token)
+    }
+    res, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer res.Body.Close()
+
+    if res.StatusCode >= 400 {
+        e := &apiError{}
+        if err := json.NewDecoder(res.Body).Decode(e); err != nil || e.Title == "" {
+            e.Title = res.Status
+        }
+        return e
+    }
+    if out == nil || res.StatusCode == http.StatusNoContent {
+        return nil
+    }
+    return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/spf13/cobra"
+)
+
+var (
+    statuses   = []string{string(models.Pending), string(models.InProgress), string(models.Completed)}
+    priorities = []string{string(models.High), string(models.Medium), string(models.Low)}
+    sortKeys   = []string{"created", "due", "priority", "name", "status"}
+)
+
+func (a *app) addCmd() *cobra.Command {
+    var description, priority, due string
+    var assignees, watchers []string
+    cmd := &cobra.Command{
+        Use:   "add NAME",
+        Short: "Create a task",
+        Args:  cobra.MinimumNArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            task := taskInput{Name: strings.Join(args, " "), Priority: models.Importance(priority)}
+            if description != "" {
+                task.Description = &description
+            }
+            var err error
+            if task.DueDate, err = parseDue(due); err != nil {
+                return err
+            }
+            if task.Assignees, err = parseUUIDs("assignee", assignees); err != nil {
+                return err
+            }
+            if task.Watchers, err = parseUUIDs("watcher", watchers); err != nil {
+                return err
+            }
+            c, err := a.client()
+            if err != nil {
+                return err
+            }
+            created, err := c.CreateTask(cmd.Context(), task)
+            if err != nil {
+                return err
+            }
+            return printTask(a.out, a.output, created)
+        },
+    }
+    f := cmd.Flags()
+    f.StringVarP(&description, "description", "d", "", "description")
+    f.StringVarP(&priority, "priority", "p", string(models.Medium), "priority: "+strings.Join(priorities, ", "))
+    f.StringVar(&due, "due", "", "due date, as YYYY-MM-DD (midnight local time) or RFC 3339")
+    f.StringSliceVar(&assignees, "assignee", nil, "user ID to assign; repeat or separate with commas")
+    f.StringSliceVar(&watchers, "watcher", nil, "user ID to add as a watcher; repeat or separate with commas")
+    cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+    return cmd
+}
+
+func (a *app) listCmd() *cobra.Command {
+    var status, priority, sortBy string
+    var mine, desc bool
+    cmd := &cobra.Command{
+        Use:     "list",
+        Aliases: []string{"ls"},
+        Short:   "List tasks",
+        Args:    cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            less, ok := orderings[sortBy]
+            if !ok {
+                return fmt.Errorf("sort must be one of %s, got %q", strings.Join(sortKeys, ", "), sortBy)
+            }
+            c, err := a.client()
+            if err != nil {
+                return err
+            }
+            var tasks []models.Task
+            if mine {
+                tasks, err = c.MyTasks(cmd.Context())
+            } else {
+                tasks, err = c.ListTasks(cmd.Context())
+            }
+            if err != nil {
+                return err
+            }
+            matched := []models.Task{}
+            for _, t := range tasks {
+                if (status == "" || string(t.Status) == status) && (priority == "" || string(t.Priority) == priority) {
+                    matched = append(matched, t)
+                }
+            }
+            sort.SliceStable(matched, func(i, j int) bool {
+                if desc {
+                    return less(matched[j], matched[i])
+                }
+                return less(matched[i], matched[j])
+            })
+            return printTasks(a.out, a.output, matched)
+        },
+    }
+    f := cmd.Flags()
+    f.StringVar(&status, "status", "", "only tasks with this status: "+strings.Join(statuses, ", "))
+    f.StringVar(&priority, "priority", "", "only tasks with this priority: "+strings.Join(priorities, ", "))
+    f.StringVar(&sortBy, "sort", "created", "sort by "+strings.Join(sortKeys, ", "))
+    f.BoolVar(&desc, "desc", false, "reverse the sort order")
+    f.BoolVar(&mine, "mine", false, "only tasks assigned to the token's user")
+    cmd.RegisterFlagCompletionFunc("status", fixedCompletion(statuses...))
+    cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+    cmd.RegisterFlagCompletionFunc("sort", fixedCompletion(sortKeys...))
+    return cmd
+}
+
+// orderings compare tasks for each --sort key. Tasks without a due date sort
+// after those with one.
+var orderings = map[string]func(a, b models.Task) bool{
+    "created": func(a, b models.Task) bool { return a.CreatedAt.Before(b.CreatedAt) },
+    "due": func(a, b models.Task) bool {
+        if a.DueDate == nil || b.DueDate == nil {
+            return a.DueDate != nil && b.DueDate == nil
+        }
+        return a.DueDate.Before(*b.DueDate)
+    },
+    "priority": func(a, b models.Task) bool { return rank(priorities, string(a.Priority)) < rank(priorities, string(b.Priority)) },
+    "name":     func(a, b models.Task) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+    "status":   func(a, b models.Task) bool { return rank(statuses, string(a.Status)) < rank(statuses, string(b.Status)) },
+}
+
+func rank(values []string, v string) int {
+    for i, value := range values {
+        if value == v {
+            return i
+        }
+    }
+    return len(values)
+}
+
+func (a *app) showCmd() *cobra.Command {
+    return &cobra.Command{
+        Use:               "show ID",
+        Short:             "Show a task",
+        Args:              cobra.ExactArgs(1),
+        ValidArgsFunction: a.completeTasks,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            c, err := a.client()
+            if err != nil {
+                return err
+            }
+            id, err := resolveID(cmd.Context(), c, args[0])
+            if err != nil {
+                return err
+            }
+            task, err := c.GetTask(cmd.Context(), id)
+            if err != nil {
+                return err
+            }
+            return printTask(a.out, a.output, task)
+        },
+    }
+}
+
+func (a *app) updateCmd() *cobra.Command {
+    var name, description, status, priority, due string
+    cmd := &cobra.Command{
+        Use:               "update ID",
+        Short:             "Change a task's fields",
+        Args:              cobra.ExactArgs(1),
+        ValidArgsFunction: a.completeTasks,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            changed := false
+            for _, f := range []string{"name", "description", "status", "priority", "due"} {
+                changed = changed || cmd.Flags().Changed(f)
+            }
+            if !changed {
+                return errors.New("nothing to change; pass at least one of --name, --description, --status, --priority, --due")
+            }
+            changes := taskInput{Name: name, Status: models.State(status), Priority: models.Importance(priority)}
+            if cmd.Flags().Changed("description") {
+                changes.Description = &description
+            }
+            var err error
+            if changes.DueDate, err = parseDue(due); err != nil {
+                return err
+            }
+            return a.update(cmd.Context(), args[0], changes)
+        },
+    }
+    f := cmd.Flags()
+    f.StringVar(&name, "name", "", "new name")
+    f.StringVarP(&description, "description", "d", "", "new description")
+    f.StringVar(&status, "status", "", "new status: "+strings.Join(statuses, ", "))
+    f.StringVarP(&priority, "priority", "p", "", "new priority: "+strings.Join(priorities, ", "))
+    f.StringVar(&due, "due", "", "new due date, as YYYY-MM-DD (midnight local time) or RFC 3339")
+    cmd.RegisterFlagCompletionFunc("status", fixedCompletion(statuses...))
+    cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+    return cmd
+}
+
+func (a *app) doneCmd() *cobra.Command {
+    return &cobra.Command{
+        Use:               "done ID...",
+        Short:             "Mark tasks completed",
+        Args:              cobra.MinimumNArgs(1),
+        ValidArgsFunction: a.completeTasks,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            for _, arg := range args {
+                if err := a.update(cmd.Context(), arg, taskInput{Status: models.Completed}); err != nil {
+                    return err
+                }
+            }
+            return nil
+        },
+    }
+}
+
+func (a *app) update(ctx context.Context, arg string, changes taskInput) error {
+    c, err := a.client()
+    if err != nil {
+        return err
+    }
+    id, err := resolveID(ctx, c, arg)
+    if err != nil {
+        return err
+    }
+    updated, err := c.UpdateTask(ctx, id, changes)
+    if err != nil {
+        return err
+    }
+    return printTask(a.out, a.output, updated)
+}
+
+func (a *app) rmCmd() *cobra.Command {
+    return &cobra.Command{
+        Use:               "rm ID...",
+        Short:             "Delete tasks",
+        Args:              cobra.MinimumNArgs(1),
+        ValidArgsFunction: a.completeTasks,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            c, err := a.client()
+            if err != nil {
+                return err
+            }
+            for _, arg := range args {
+                id, err := resolveID(cmd.Context(), c, arg)
+                if err != nil {
+                    return err
+                }
+                if err := c.DeleteTask(cmd.Context(), id); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.ErrOrStderr(), "deleted %s\n", id)
+            }
+            return nil
+        },
+    }
+}
+
+func (a *app) configCmd() *cobra.Command {
+    cmd := &cobra.Command{
+        Use:   "config",
+        Short: "Manage server profiles",
+    }
+    var server, token string
+    set := &cobra.Command{
+        Use:   "set PROFILE",
+        Short: "Create or change a profile; the first one becomes current",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            p, err := loadProfiles(a.configPath)
+            if err != nil {
+                return err
+            }
+            prof := p.Profiles[args[0]]
+            if cmd.Flags().Changed("server") {
+                prof.Server = server
+            }
+            if cmd.Flags().Changed("token") {
+                prof.Token = token
+            }
+            p.Profiles[args[0]] = prof
+            if p.Current == "" {
+                p.Current = args[0]
+            }
+            return p.save(a.configPath)
+        },
+    }
+    // These shadow the global --server and --token, which would otherwise
+    // be read from the environment.
+    set.Flags().StringVar(&server, "server", "", "server URL, e.g. "+defaultServer)
+    set.Flags().StringVar(&token, "token", "", "API token")
+
+    use := &cobra.Command{
+        Use:               "use PROFILE",
+        Short:             "Make a profile current",
+        Args:              cobra.ExactArgs(1),
+        ValidArgsFunction: a.completeProfiles,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            p, err := loadProfiles(a.configPath)
+            if err != nil {
+                return err
+            }
+            if _, ok := p.Profiles[args[0]]; !ok {
+                return fmt.Errorf("no profile %q", args[0])
+            }
+            p.Current = args[0]
+            return p.save(a.configPath)
+        },
+    }
+    list := &cobra.Command{
+        Use:   "list",
+        Short: "List profiles; the current one is marked with *",
+        Args:  cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            p, err := loadProfiles(a.configPath)
+            if err != nil {
+                return err
+            }
+            for _, name := range p.names() {
+                mark := " "
+                if name == p.Current {
+                    mark = "*"
+                }
+                fmt.Fprintf(a.out, "%s %s\t%s\n", mark, name, p.Profiles[name].Server)
+            }
+            return nil
+        },
+    }
+    cmd.AddCommand(set, use, list)
+    return cmd
+}
+
+// resolveID accepts a full task ID or a prefix that matches exactly one task.
+func resolveID(ctx context.Context, c *client, arg string) (string, error) {
+    if _, err := uuid.Parse(arg); err == nil {
+        return arg, nil
+    }
+    tasks, err := c.ListTasks(ctx)
+    if err != nil {
+        return "", err
+    }
+    var found []string
+    for _, t := range tasks {
+        if strings.HasPrefix(t.ID.String(), strings.ToLower(arg)) {
+            found = append(found, t.ID.String())
+        }
+    }
+    switch len(found) {
+    case 0:
+        return "", fmt.Errorf("no task matches %q", arg)
+    case 1:
+        return found[0], nil
+    }
+    return "", fmt.Errorf("%q matches %d tasks; use more of the ID", arg, len(found))
+}
+
+func parseDue(s string) (*time.Time, error) {
+    if s == "" {
+        return nil, nil
+    }
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return &t, nil
+    }
+    t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+    if err != nil {
+        return nil, fmt.Errorf("due must be YYYY-MM-DD or RFC 3339, got %q", s)
+    }
+    return &t, nil
+}
+
+func parseUUIDs(flag string, values []string) ([]uuid.UUID, error) {
+    var ids []uuid.UUID
+    for _, v := range values {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            return nil, fmt.Errorf("--%s must be a user ID, got %q", flag, v)
+        }
+        ids = append(ids, id)
+    }
+    return ids, nil
+}
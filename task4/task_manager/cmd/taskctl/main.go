@@ -0,0 +1,129 @@
+// Command taskctl manages tasks through the task_manager REST API.
+//
+//	taskctl config set default --server http://localhost:3000 --token <token>
+//	taskctl add "Write docs" --priority high --due 2030-01-02
+//	taskctl list --status pending --sort due
+//	taskctl done 3f2a9c1e
+//
+// Run "taskctl completion --help" for shell completion.
+package main
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+
+    "github.com/spf13/cobra"
+)
+
+// app holds the global flags shared by every command.
+type app struct {
+    configPath string
+    profile    string
+    server     string
+    token      string
+    output     string
+    out        io.Writer
+}
+
+func main() {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+    if err := newRootCmd(os.Stdout).ExecuteContext(ctx); err != nil {
+        os.Exit(1)
+    }
+}
+
+func newRootCmd(out io.Writer) *cobra.Command {
+    a := &app{out: out}
+    root := &cobra.Command{
+        Use:          "taskctl",
+        Short:        "Manage tasks on a task_manager server",
+        SilenceUsage: true,
+        PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+            return checkFormat(a.output)
+        },
+    }
+    root.SetOut(out)
+    f := root.PersistentFlags()
+    f.StringVar(&a.configPath, "config", envOr("TASKCTL_CONFIG", defaultConfigPath()), "config file with profiles (env TASKCTL_CONFIG)")
+    f.StringVar(&a.profile, "profile", os.Getenv("TASKCTL_PROFILE"), "profile to use instead of the current one (env TASKCTL_PROFILE)")
+    f.StringVar(&a.server, "server", os.Getenv("TASKCTL_SERVER"), "server URL, overriding the profile (env TASKCTL_SERVER)")
+    f.StringVar(&a.token, "token", os.Getenv("TASKCTL_TOKEN"), "API token, overriding the profile (env TASKCTL_TOKEN)")
+    f.StringVarP(&a.output, "output", "o", "table", "output format: "+strings.Join(formats, ", "))
+    root.RegisterFlagCompletionFunc("output", fixedCompletion(formats...))
+    root.RegisterFlagCompletionFunc("profile", a.completeProfiles)
+
+    root.AddCommand(
+        a.addCmd(),
+        a.listCmd(),
+        a.showCmd(),
+        a.updateCmd(),
+        a.doneCmd(),
+        a.rmCmd(),
+        a.configCmd(),
+    )
+    return root
+}
+
+// client connects with the chosen profile, overridden by --server and
+// --token.
+func (a *app) client() (*client, error) {
+    p, err := loadProfiles(a.configPath)
+    if err != nil {
+        return nil, err
+    }
+    prof, err := p.resolve(a.profile)
+    if err != nil {
+        return nil, err
+    }
+    if a.server != "" {
+        prof.Server = a.server
+    }
+    if a.token != "" {
+        prof.Token = a.token
+    }
+    return newClient(prof.Server, prof.Token), nil
+}
+
+func envOr(key, def string) string {
+    if v := os.Getenv(key); v != "" {
+        return v
+    }
+    return def
+}
+
+func fixedCompletion(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+    return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+        return values, cobra.ShellCompDirectiveNoFileComp
+    }
+}
+
+func (a *app) completeProfiles(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+    p, err := loadProfiles(a.configPath)
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    return p.names(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTasks offers the short IDs of the server's tasks, described by
+// name.
+func (a *app) completeTasks(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+    c, err := a.client()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    tasks, err := c.ListTasks(cmd.Context())
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    out := make([]string, len(tasks))
+    for i, t := range tasks {
+        out[i] = fmt.Sprintf("%s\t%s", shortID(t), t.Name)
+    }
+    return out, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+    "task_manager/models"
+    "text/tabwriter"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+var formats = []string{"table", "json", "yaml"}
+
+func checkFormat(format string) error {
+    for _, f := range formats {
+        if f == format {
+            return nil
+        }
+    }
+    return fmt.Errorf("output must be one of %s, got %q", strings.Join(formats, ", "), format)
+}
+
+// printTasks writes tasks as a table, or as the API's JSON fields in JSON or
+// YAML.
+func printTasks(w io.Writer, format string, tasks []models.Task) error {
+    if format != "table" {
+        return encode(w, format, tasks)
+    }
+    tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+    fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tPRIORITY\tDUE")
+    for _, t := range tasks {
+        fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", shortID(t), t.Name, t.Status, t.Priority, formatTime(t.DueDate))
+    }
+    return tw.Flush()
+}
+
+func printTask(w io.Writer, format string, t *models.Task) error {
+    if format != "table" {
+        return encode(w, format, t)
+    }
+    tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+    row := func(k, v string) {
+        if v != "" {
+            fmt.Fprintf(tw, "%s:\t%s\n", k, v)
+        }
+    }
+    row("ID", t.ID.String())
+    row("Name", t.Name)
+    if t.Description != nil {
+        row("Description", *t.Description)
+    }
+    row("Status", string(t.Status))
+    row("Priority", string(t.Priority))
+    row("Due", formatTime(t.DueDate))
+    if t.OwnerID != nil {
+        row("Owner", t.OwnerID.String())
+    }
+    row("Assignees", joinIDs(t.Assignees))
+    row("Watchers", joinIDs(t.Watchers))
+    row("Created", formatTime(&t.CreatedAt))
+    row("Updated", formatTime(&t.UpdatedAt))
+    row("Completed", formatTime(t.CompletedAt))
+    return tw.Flush()
+}
+
+func encode(w io.Writer, format string, v any) error {
+    b, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return err
+    }
+    if format == "json" {
+        _, err = fmt.Fprintf(w, "%s\n", b)
+        return err
+    }
+    // Going through JSON keeps the API's field names and order.
+    var node yaml.Node
+    if err := yaml.Unmarshal(b, &node); err != nil {
+        return err
+    }
+    blockStyle(&node)
+    enc := yaml.NewEncoder(w)
+    enc.SetIndent(2)
+    if err := enc.Encode(&node); err != nil {
+        return err
+    }
+    return enc.Close()
+}
+
+// blockStyle drops the flow style and quoting carried over from JSON.
+func blockStyle(n *yaml.Node) {
+    n.Style = 0
+    for _, c := range n.Content {
+        blockStyle(c)
+    }
+}
+
+// shortID is enough of a task's ID to pass to the other commands.
+func shortID(t models.Task) string {
+    return t.ID.String()[:8]
+}
+
+func formatTime(t *time.Time) string {
+    if t == nil || t.IsZero() {
+        return ""
+    }
+    return t.Local().Format("2006-01-02 15:04")
+}
+
+func joinIDs[T fmt.Stringer](ids []T) string {
+    s := make([]string, len(ids))
+    for i, id := range ids {
+        s[i] = id.String()
+    }
+    return strings.Join(s, ", ")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+
+    "gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:3000"
+
+type profile struct {
+    Server string `yaml:"server"`
+    Token  string `yaml:"token,omitempty"`
+}
+
+// profiles is the config file, by default $XDG_CONFIG_HOME/taskctl/config.yaml:
+//
+//	current: default
+//	profiles:
+//	  default:
+//	    server: http://localhost:3000
+//	    token: 4f1c...
+type profiles struct {
+    Current  string             `yaml:"current,omitempty"`
+    Profiles map[string]profile `yaml:"profiles,omitempty"`
+}
+
+func defaultConfigPath() string {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        return ".taskctl.yaml"
+    }
+    return filepath.Join(dir, "taskctl", "config.yaml")
+}
+
+// loadProfiles reads path; a missing file is an empty config.
+func loadProfiles(path string) (*profiles, error) {
+    p := &profiles{Profiles: map[string]profile{}}
+    b, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return p, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := yaml.Unmarshal(b, p); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    if p.Profiles == nil {
+        p.Profiles = map[string]profile{}
+    }
+    return p, nil
+}
+
+// save writes the file readable only by its owner, since it holds tokens.
+func (p *profiles) save(path string) error {
+    b, err := yaml.Marshal(p)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+        return err
+    }
+    return os.WriteFile(path, b, 0o600)
+}
+
+// resolve picks the named profile, else the current one, else "default". A
+// name that is not in the file is an error; the implicit default is not.
+func (p *profiles) resolve(name string) (profile, error) {
+    explicit := name != ""
+    if !explicit {
+        name = p.Current
+    }
+    if name == "" {
+        name = "default"
+    }
+    prof, ok := p.Profiles[name]
+    if !ok && explicit {
+        return profile{}, fmt.Errorf("no profile %q", name)
+    }
+    if prof.Server == "" {
+        prof.Server = defaultServer
+    }
+    return prof, nil
+}
+
+func (p *profiles) names() []string {
+    names := make([]string, 0, len(p.Profiles))
+    for name := range p.Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
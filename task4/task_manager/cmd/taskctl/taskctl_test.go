@@ -0,0 +1,83 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "task_manager/config"
+    "task_manager/controllers"
+    "task_manager/data"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/router"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestTaskctl(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := data.NewRepo(nil, "", true)
+    handler := controllers.SetHandler(service.NewTasks(repo, data.NewUserRepo(nil, "", true), nil))
+    cfg := config.Default()
+    limits := middleware.NewLimits(cfg.RateLimit, cfg.RouteLimits, cfg.TaskQuota)
+    srv := httptest.NewServer(router.NewRouter(handler, controllers.NewHealth(repo, time.Second), cfg, limits))
+    defer srv.Close()
+    for _, name := range []string{"TASKCTL_CONFIG", "TASKCTL_PROFILE", "TASKCTL_SERVER", "TASKCTL_TOKEN"} {
+        t.Setenv(name, "")
+    }
+    t.Setenv("TASKCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+    run := func(args ...string) string {
+        t.Helper()
+        var out bytes.Buffer
+        cmd := newRootCmd(&out)
+        cmd.SetErr(&out)
+        cmd.SetArgs(args)
+        if err := cmd.Execute(); err != nil {
+            t.Fatalf("taskctl %s: %v\n%s", strings.Join(args, " "), err, out.String())
+        }
+        return out.String()
+    }
+
+    run("config", "set", "local", "--server", srv.URL)
+    if out := run("config", "list"); !strings.Contains(out, "* local\t"+srv.URL) {
+        t.Errorf("config list = %q; want local marked current", out)
+    }
+
+    var later, sooner models.Task
+    json.Unmarshal([]byte(run("add", "write", "docs", "--due", "2031-01-01", "-o", "json")), &later)
+    json.Unmarshal([]byte(run("add", "ship", "--due", "2030-06-01", "-p", "high", "-o", "json")), &sooner)
+    run("add", "someday", "-p", "low")
+    if later.Name != "write docs" || later.Status != models.Pending || later.DueDate == nil {
+        t.Fatalf("add = %+v; want a pending task named \"write docs\" with a due date", later)
+    }
+
+    // Short IDs work wherever an ID does.
+    run("done", later.ID.String()[:8])
+
+    var listed []models.Task
+    json.Unmarshal([]byte(run("list", "--status", "pending", "--sort", "due", "-o", "json")), &listed)
+    if len(listed) != 2 || listed[0].ID != sooner.ID || listed[1].Name != "someday" {
+        t.Errorf("list = %+v; want ship, then someday without a due date", listed)
+    }
+
+    if out := run("show", sooner.ID.String(), "-o", "yaml"); !strings.Contains(out, "name: ship\n") || !strings.Contains(out, "priority: high\n") {
+        t.Errorf("show -o yaml = %q", out)
+    }
+    if out := run("list"); !strings.Contains(out, "ID") || !strings.Contains(out, later.ID.String()[:8]) {
+        t.Errorf("list table = %q", out)
+    }
+
+    run("rm", later.ID.String())
+    cmd := newRootCmd(&bytes.Buffer{})
+    cmd.SetErr(&bytes.Buffer{})
+    cmd.SetArgs([]string{"show", later.ID.String()})
+    if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "task not found") {
+        t.Errorf("show deleted task: err = %v; want the server's problem detail", err)
+    }
+}
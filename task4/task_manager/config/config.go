@@ -0,0 +1,418 @@
+package config
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/pelletier/go-toml/v2"
+    "gopkg.in/yaml.v3"
+)
+
+const envPrefix = "TASK_MANAGER_"
+
+type Config struct {
+    Port               int
+    GRPCPort           int
+    MongoURI           string
+    Database           string
+    Storage            string
+    LogLevel           string
+    ConnectTimeout     time.Duration
+    ReadTimeout        time.Duration
+    WriteTimeout       time.Duration
+    IdleTimeout        time.Duration
+    MaxHeaderBytes     int
+    MaxBodyBytes       int
+    ShutdownTimeout    time.Duration
+    ShutdownDelay      time.Duration
+    ReadyTimeout       time.Duration
+    TraceExporter      string
+    TraceFile          string
+    TraceSample        float64
+    RateLimit          Limit
+    RouteLimits        map[string]Limit
+    TaskQuota          int
+    QueryTimeout       time.Duration
+    QueryTimeouts      map[string]time.Duration
+    AutoMigrate        bool
+    Admins             []string
+    EscalationInterval time.Duration
+    RebalanceInterval  time.Duration
+    IdempotencyWindow  time.Duration
+    CacheSize          int
+    CacheTTL           time.Duration
+}
+
+type option struct {
+    key   string
+    usage string
+}
+
+// options lists every setting. Each key doubles as the flag name, the config
+// file key and, upper-cased with the TASK_MANAGER_ prefix, the env variable.
+var options = []option{
+    {"port", "HTTP listen port"},
+    {"grpc_port", "gRPC listen port, 0 to disable"},
+    {"mongo_uri", "MongoDB connection URI"},
+    {"database", "MongoDB database name"},
+    {"storage", "storage backend: mongo or memory"},
+    {"log_level", "log level: debug, info, warn or error"},
+    {"connect_timeout", "MongoDB connect timeout"},
+    {"read_timeout", "HTTP server read timeout"},
+    {"write_timeout", "HTTP server write timeout"},
+    {"idle_timeout", "HTTP server keep-alive idle timeout"},
+    {"max_header_bytes", "HTTP server maximum request header size"},
+    {"max_body_bytes", "maximum request body size"},
+    {"shutdown_timeout", "time allowed to drain requests and release resources on shutdown"},
+    {"shutdown_delay", "time to report not-ready before draining on shutdown"},
+    {"ready_timeout", "timeout for the readiness storage check"},
+    {"trace_exporter", "trace exporter: none, stdout or file"},
+    {"trace_file", "file that receives spans when trace_exporter is file"},
+    {"trace_sample_ratio", "fraction of new traces to sample, between 0 and 1"},
+    {"rate_limit", "default per-client request limit such as 600/1m, or off"},
+    {"rate_limits", "per-route limits such as \"POST /tasks=60/1m;GET /tasks=600/1m\""},
+    {"task_quota", "tasks each client may create per UTC day, 0 for unlimited"},
+    {"query_timeout", "deadline for each repository operation"},
+    {"query_timeouts", "per-operation deadlines such as \"get_all=10s;create=2s\""},
+    {"auto_migrate", "apply pending MongoDB migrations at startup"},
+    {"admins", "comma-separated usernames allowed to manage escalation rules"},
+    {"escalation_interval", "how often escalation rules are evaluated, 0 to disable"},
+    {"rebalance_interval", "how often board columns with long ranks are rebalanced, 0 to disable"},
+    {"idempotency_window", "how long responses are kept for retries with the same Idempotency-Key, 0 to disable"},
+    {"cache_size", "tasks kept in the read cache in front of MongoDB, 0 to disable"},
+    {"cache_ttl", "how long a cached task is served before it is read again"},
+}
+
+func Default() Config {
+    return Config{
+        Port:               3000,
+        GRPCPort:           9090,
+        Database:           "task_manager_db",
+        Storage:            "mongo",
+        LogLevel:           "info",
+        ConnectTimeout:     5 * time.Second,
+        ReadTimeout:        10 * time.Second,
+        WriteTimeout:       15 * time.Second,
+        IdleTimeout:        60 * time.Second,
+        MaxHeaderBytes:     1 << 20,
+        MaxBodyBytes:       1 << 20,
+        ShutdownTimeout:    15 * time.Second,
+        ShutdownDelay:      5 * time.Second,
+        ReadyTimeout:       2 * time.Second,
+        TraceExporter:      "none",
+        TraceFile:          "traces.json",
+        TraceSample:        1,
+        RateLimit:          Limit{Requests: 600, Per: time.Minute},
+        RouteLimits:        map[string]Limit{},
+        TaskQuota:          1000,
+        QueryTimeout:       5 * time.Second,
+        QueryTimeouts:      map[string]time.Duration{},
+        AutoMigrate:        true,
+        Admins:             []string{},
+        EscalationInterval: time.Minute,
+        RebalanceInterval:  10 * time.Minute,
+        IdempotencyWindow:  24 * time.Hour,
+        CacheSize:          1000,
+        CacheTTL:           30 * time.Second,
+    }
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// defaults, an optional YAML or TOML file, the environment and flags.
+func Load(args []string) (Config, error) {
+    type pair struct{ key, value string }
+    var (
+        path  = os.Getenv(envPrefix + "CONFIG")
+        flags []pair
+    )
+    fs := flag.NewFlagSet("task_manager", flag.ContinueOnError)
+    fs.Func("config", "path to a YAML or TOML config file", func(v string) error {
+        path = v
+        return nil
+    })
+    for _, o := range options {
+        key := o.key
+        fs.Func(key, o.usage, func(v string) error {
+            flags = append(flags, pair{key, v})
+            return nil
+        })
+    }
+    if err := fs.Parse(args); err != nil {
+        return Config{}, err
+    }
+
+    cfg := Default()
+    if path != "" {
+        if err := cfg.loadFile(path); err != nil {
+            return Config{}, err
+        }
+    }
+    if err := cfg.loadEnv(); err != nil {
+        return Config{}, err
+    }
+    for _, p := range flags {
+        if err := cfg.set(p.key, p.value); err != nil {
+            return Config{}, fmt.Errorf("flag -%s: %w", p.key, err)
+        }
+    }
+    if err := cfg.Validate(); err != nil {
+        return Config{}, err
+    }
+    return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    values := map[string]any{}
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(raw, &values)
+    case ".toml":
+        err = toml.Unmarshal(raw, &values)
+    default:
+        return fmt.Errorf("unsupported config file type %q", filepath.Ext(path))
+    }
+    if err != nil {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    for key, v := range values {
+        value := fmt.Sprint(v)
+        switch v := v.(type) {
+        case map[string]any:
+            value = formatPairs(v)
+        case []any:
+            items := make([]string, len(v))
+            for i, item := range v {
+                items[i] = fmt.Sprint(item)
+            }
+            value = strings.Join(items, ",")
+        }
+        if err := c.set(key, value); err != nil {
+            return fmt.Errorf("%s: %s: %w", path, key, err)
+        }
+    }
+    return nil
+}
+
+func (c *Config) loadEnv() error {
+    // URI predates the prefixed variables and is still what .env sets.
+    if v, ok := os.LookupEnv("URI"); ok {
+        c.MongoURI = v
+    }
+    for _, o := range options {
+        name := envPrefix + strings.ToUpper(o.key)
+        if v, ok := os.LookupEnv(name); ok {
+            if err := c.set(o.key, v); err != nil {
+                return fmt.Errorf("%s: %w", name, err)
+            }
+        }
+    }
+    return nil
+}
+
+func (c *Config) set(key, value string) error {
+    var err error
+    switch key {
+    case "port":
+        c.Port, err = strconv.Atoi(value)
+    case "grpc_port":
+        c.GRPCPort, err = strconv.Atoi(value)
+    case "mongo_uri":
+        c.MongoURI = value
+    case "database":
+        c.Database = value
+    case "storage":
+        c.Storage = strings.ToLower(value)
+    case "log_level":
+        c.LogLevel = strings.ToLower(value)
+    case "connect_timeout":
+        c.ConnectTimeout, err = time.ParseDuration(value)
+    case "read_timeout":
+        c.ReadTimeout, err = time.ParseDuration(value)
+    case "write_timeout":
+        c.WriteTimeout, err = time.ParseDuration(value)
+    case "idle_timeout":
+        c.IdleTimeout, err = time.ParseDuration(value)
+    case "max_header_bytes":
+        c.MaxHeaderBytes, err = strconv.Atoi(value)
+    case "max_body_bytes":
+        c.MaxBodyBytes, err = strconv.Atoi(value)
+    case "shutdown_timeout":
+        c.ShutdownTimeout, err = time.ParseDuration(value)
+    case "shutdown_delay":
+        c.ShutdownDelay, err = time.ParseDuration(value)
+    case "ready_timeout":
+        c.ReadyTimeout, err = time.ParseDuration(value)
+    case "trace_exporter":
+        c.TraceExporter = strings.ToLower(value)
+    case "trace_file":
+        c.TraceFile = value
+    case "trace_sample_ratio":
+        c.TraceSample, err = strconv.ParseFloat(value, 64)
+    case "rate_limit":
+        c.RateLimit, err = ParseLimit(value)
+    case "rate_limits":
+        c.RouteLimits, err = parseRouteLimits(value)
+    case "task_quota":
+        c.TaskQuota, err = strconv.Atoi(value)
+    case "query_timeout":
+        c.QueryTimeout, err = time.ParseDuration(value)
+    case "query_timeouts":
+        c.QueryTimeouts, err = parseTimeouts(value)
+    case "auto_migrate":
+        c.AutoMigrate, err = strconv.ParseBool(value)
+    case "admins":
+        c.Admins = parseList(value)
+    case "escalation_interval":
+        c.EscalationInterval, err = time.ParseDuration(value)
+    case "rebalance_interval":
+        c.RebalanceInterval, err = time.ParseDuration(value)
+    case "idempotency_window":
+        c.IdempotencyWindow, err = time.ParseDuration(value)
+    case "cache_size":
+        c.CacheSize, err = strconv.Atoi(value)
+    case "cache_ttl":
+        c.CacheTTL, err = time.ParseDuration(value)
+    default:
+        return errors.New("unknown setting")
+    }
+    return err
+}
+
+func (c Config) Validate() error {
+    var errs []error
+    if c.Port < 1 || c.Port > 65535 {
+        errs = append(errs, fmt.Errorf("port %d out of range", c.Port))
+    }
+    if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+        errs = append(errs, fmt.Errorf("grpc_port %d out of range", c.GRPCPort))
+    } else if c.GRPCPort == c.Port {
+        errs = append(errs, errors.New("grpc_port must differ from port"))
+    }
+    switch c.Storage {
+    case "memory":
+    case "mongo":
+        if c.MongoURI == "" {
+            errs = append(errs, errors.New("mongo_uri is required for mongo storage"))
+        }
+        if c.Database == "" {
+            errs = append(errs, errors.New("database is required for mongo storage"))
+        }
+    default:
+        errs = append(errs, fmt.Errorf("unknown storage %q", c.Storage))
+    }
+    switch c.LogLevel {
+    case "debug", "info", "warn", "error":
+    default:
+        errs = append(errs, fmt.Errorf("unknown log level %q", c.LogLevel))
+    }
+    switch c.TraceExporter {
+    case "none", "stdout":
+    case "file":
+        if c.TraceFile == "" {
+            errs = append(errs, errors.New("trace_file is required for the file exporter"))
+        }
+    default:
+        errs = append(errs, fmt.Errorf("unknown trace exporter %q", c.TraceExporter))
+    }
+    if c.TraceSample < 0 || c.TraceSample > 1 {
+        errs = append(errs, errors.New("trace_sample_ratio must be between 0 and 1"))
+    }
+    for _, d := range []struct {
+        name  string
+        value time.Duration
+    }{
+        {"connect_timeout", c.ConnectTimeout},
+        {"read_timeout", c.ReadTimeout},
+        {"write_timeout", c.WriteTimeout},
+        {"idle_timeout", c.IdleTimeout},
+        {"shutdown_timeout", c.ShutdownTimeout},
+        {"ready_timeout", c.ReadyTimeout},
+        {"query_timeout", c.QueryTimeout},
+    } {
+        if d.value <= 0 {
+            errs = append(errs, fmt.Errorf("%s must be positive", d.name))
+        }
+    }
+    for op, d := range c.QueryTimeouts {
+        if d <= 0 {
+            errs = append(errs, fmt.Errorf("query_timeouts: %s must be positive", op))
+        }
+    }
+    if c.ShutdownDelay < 0 {
+        errs = append(errs, errors.New("shutdown_delay must not be negative"))
+    }
+    if c.TaskQuota < 0 {
+        errs = append(errs, errors.New("task_quota must not be negative"))
+    }
+    if c.MaxHeaderBytes <= 0 {
+        errs = append(errs, errors.New("max_header_bytes must be positive"))
+    }
+    if c.MaxBodyBytes <= 0 {
+        errs = append(errs, errors.New("max_body_bytes must be positive"))
+    }
+    if c.EscalationInterval < 0 {
+        errs = append(errs, errors.New("escalation_interval must not be negative"))
+    }
+    if c.RebalanceInterval < 0 {
+        errs = append(errs, errors.New("rebalance_interval must not be negative"))
+    }
+    if c.IdempotencyWindow < 0 {
+        errs = append(errs, errors.New("idempotency_window must not be negative"))
+    }
+    if c.CacheSize < 0 {
+        errs = append(errs, errors.New("cache_size must not be negative"))
+    }
+    if c.CacheSize > 0 && c.CacheTTL <= 0 {
+        errs = append(errs, errors.New("cache_ttl must be positive"))
+    }
+    return errors.Join(errs...)
+}
+
+// parseTimeouts reads "operation=duration" pairs separated by ";".
+func parseTimeouts(s string) (map[string]time.Duration, error) {
+    timeouts := map[string]time.Duration{}
+    for _, entry := range strings.Split(s, ";") {
+        if strings.TrimSpace(entry) == "" {
+            continue
+        }
+        op, value, ok := strings.Cut(entry, "=")
+        if !ok {
+            return nil, fmt.Errorf("timeout %q must look like \"get_all=10s\"", entry)
+        }
+        d, err := time.ParseDuration(strings.TrimSpace(value))
+        if err != nil {
+            return nil, err
+        }
+        timeouts[strings.TrimSpace(op)] = d
+    }
+    return timeouts, nil
+}
+
+// parseList reads comma-separated values, skipping empty ones.
+func parseList(s string) []string {
+    list := []string{}
+    for _, item := range strings.Split(s, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            list = append(list, item)
+        }
+    }
+    return list
+}
+
+func (c Config) Addr() string {
+    return fmt.Sprintf(":%d", c.Port)
+}
+
+func (c Config) GRPCAddr() string {
+    return fmt.Sprintf(":%d", c.GRPCPort)
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+    dir := t.TempDir()
+    yamlPath := filepath.Join(dir, "config.yaml")
+    os.WriteFile(yamlPath, []byte("port: 4000\ndatabase: from_file\nstorage: memory\nconnect_timeout: 2s\n"), 0o600)
+    tomlPath := filepath.Join(dir, "config.toml")
+    os.WriteFile(tomlPath, []byte("port = 4100\nlog_level = \"warn\"\nstorage = \"memory\"\n"), 0o600)
+    adminsPath := filepath.Join(dir, "admins.yaml")
+    os.WriteFile(adminsPath, []byte("storage: memory\nadmins:\n  - ann\n  - bob\nescalation_interval: 5m\n"), 0o600)
+
+    testcases := []struct {
+        cases string
+        env   map[string]string
+        args  []string
+        want  func(*Config)
+    }{
+        {"defaults", map[string]string{"TASK_MANAGER_STORAGE": "memory"}, nil,
+            func(c *Config) { c.Storage = "memory" }},
+        {"yaml file", nil, []string{"-config", yamlPath},
+            func(c *Config) { c.Port, c.Database, c.Storage, c.ConnectTimeout = 4000, "from_file", "memory", 2*time.Second }},
+        {"toml file", nil, []string{"-config", tomlPath},
+            func(c *Config) { c.Port, c.Storage, c.LogLevel = 4100, "memory", "warn" }},
+        {"env over file", map[string]string{"TASK_MANAGER_PORT": "5000", "TASK_MANAGER_CONFIG": yamlPath}, nil,
+            func(c *Config) { c.Port, c.Database, c.Storage, c.ConnectTimeout = 5000, "from_file", "memory", 2*time.Second }},
+        {"flags over env", map[string]string{"TASK_MANAGER_PORT": "5000", "URI": "mongodb://env"}, []string{"-config", yamlPath, "-port", "6000", "-storage", "mongo"},
+            func(c *Config) {
+                c.Port, c.MongoURI, c.Database, c.ConnectTimeout = 6000, "mongodb://env", "from_file", 2*time.Second
+            }},
+        {"query timeouts", nil, []string{"-storage", "memory", "-query_timeout", "1s", "-query_timeouts", "get_all=10s; create=2s"},
+            func(c *Config) {
+                c.Storage, c.QueryTimeout = "memory", time.Second
+                c.QueryTimeouts = map[string]time.Duration{"get_all": 10 * time.Second, "create": 2 * time.Second}
+            }},
+        {"admins list in file", nil, []string{"-config", adminsPath},
+            func(c *Config) { c.Storage, c.Admins, c.EscalationInterval = "memory", []string{"ann", "bob"}, 5*time.Minute }},
+        {"admins flag", nil, []string{"-storage", "memory", "-admins", "ann, ,bob", "-escalation_interval", "0"},
+            func(c *Config) { c.Storage, c.Admins, c.EscalationInterval = "memory", []string{"ann", "bob"}, 0 }},
+        {"rebalance interval", map[string]string{"TASK_MANAGER_REBALANCE_INTERVAL": "1h"}, []string{"-storage", "memory"},
+            func(c *Config) { c.Storage, c.RebalanceInterval = "memory", time.Hour }},
+        {"idempotency window", nil, []string{"-storage", "memory", "-idempotency_window", "0"},
+            func(c *Config) { c.Storage, c.IdempotencyWindow = "memory", 0 }},
+        {"cache", map[string]string{"TASK_MANAGER_CACHE_SIZE": "50"}, []string{"-storage", "memory", "-cache_ttl", "5s"},
+            func(c *Config) { c.Storage, c.CacheSize, c.CacheTTL = "memory", 50, 5*time.Second }},
+    }
+    for _, test := range testcases {
+        t.Run(test.cases, func(t *testing.T) {
+            t.Setenv("URI", "")
+            os.Unsetenv("URI")
+            for k, v := range test.env {
+                t.Setenv(k, v)
+            }
+            got, err := Load(test.args)
+            if err != nil {
+                t.Fatalf("Load(%v) error: %v", test.args, err)
+            }
+            want := Default()
+            test.want(&want)
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("Load(%v) = %+v; want %+v", test.args, got, want)
+            }
+        })
+    }
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+    testcases := []struct {
+        cases string
+        args  []string
+    }{
+        {"bad port", []string{"-storage", "memory", "-port", "70000"}},
+        {"unknown storage", []string{"-storage", "sqlite"}},
+        {"mongo without uri", []string{"-storage", "mongo"}},
+        {"bad log level", []string{"-storage", "memory", "-log_level", "loud"}},
+        {"bad duration", []string{"-storage", "memory", "-connect_timeout", "soon"}},
+        {"zero query timeout", []string{"-storage", "memory", "-query_timeouts", "get_all=0s"}},
+        {"negative escalation interval", []string{"-storage", "memory", "-escalation_interval", "-1m"}},
+        {"negative rebalance interval", []string{"-storage", "memory", "-rebalance_interval", "-1m"}},
+        {"negative idempotency window", []string{"-storage", "memory", "-idempotency_window", "-1h"}},
+        {"negative cache size", []string{"-storage", "memory", "-cache_size", "-1"}},
+        {"cache without ttl", []string{"-storage", "memory", "-cache_ttl", "0s"}},
+        {"grpc port taken by http", []string{"-storage", "memory", "-port", "9090", "-grpc_port", "9090"}},
+    }
+    for _, test := range testcases {
+        t.Setenv("URI", "")
+        os.Unsetenv("URI")
+        if _, err := Load(test.args); err == nil {
+            t.Errorf("%s: Load(%v) succeeded; want error", test.cases, test.args)
+        }
+    }
+}
+
+func TestLoadRateLimits(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    os.WriteFile(path, []byte("storage: memory\nrate_limit: 100/m\nrate_limits:\n  \"POST /tasks\": 10/1m\n  \"GET /tasks\": \"off\"\n"), 0o600)
+    t.Setenv("TASK_MANAGER_RATE_LIMIT", "")
+    os.Unsetenv("TASK_MANAGER_RATE_LIMIT")
+
+    got, err := Load([]string{"-config", path})
+    if err != nil {
+        t.Fatalf("Load error: %v", err)
+    }
+    if want := (Limit{Requests: 100, Per: time.Minute}); got.RateLimit != want {
+        t.Errorf("RateLimit = %v; want %v", got.RateLimit, want)
+    }
+    want := map[string]Limit{"POST /tasks": {Requests: 10, Per: time.Minute}, "GET /tasks": {}}
+    if !reflect.DeepEqual(got.RouteLimits, want) {
+        t.Errorf("RouteLimits = %v; want %v", got.RouteLimits, want)
+    }
+
+    got, err = Load([]string{"-config", path, "-rate_limits", "DELETE /tasks/:id=5/1h"})
+    if err != nil {
+        t.Fatalf("Load error: %v", err)
+    }
+    want = map[string]Limit{"DELETE /tasks/:id": {Requests: 5, Per: time.Hour}}
+    if !reflect.DeepEqual(got.RouteLimits, want) {
+        t.Errorf("flag RouteLimits = %v; want %v", got.RouteLimits, want)
+    }
+}
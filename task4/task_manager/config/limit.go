@@ -0,0 +1,83 @@
+package config
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Limit allows Requests per Per window. The zero Limit means unlimited.
+type Limit struct {
+    Requests int
+    Per      time.Duration
+}
+
+// ParseLimit reads limits written as "100/1m" or "100/m". "off" and "0"
+// disable the limit.
+func ParseLimit(s string) (Limit, error) {
+    s = strings.TrimSpace(s)
+    if s == "off" || s == "0" {
+        return Limit{}, nil
+    }
+    n, per, ok := strings.Cut(s, "/")
+    if !ok {
+        return Limit{}, fmt.Errorf("limit %q must look like 100/1m", s)
+    }
+    requests, err := strconv.Atoi(n)
+    if err != nil || requests < 0 {
+        return Limit{}, fmt.Errorf("limit %q has an invalid request count", s)
+    }
+    if per != "" && (per[0] < '0' || per[0] > '9') {
+        per = "1" + per
+    }
+    window, err := time.ParseDuration(per)
+    if err != nil || window <= 0 {
+        return Limit{}, fmt.Errorf("limit %q has an invalid window", s)
+    }
+    return Limit{Requests: requests, Per: window}, nil
+}
+
+func (l Limit) Enabled() bool {
+    return l.Requests > 0
+}
+
+func (l Limit) String() string {
+    if !l.Enabled() {
+        return "off"
+    }
+    return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// parseRouteLimits reads "METHOD /route=limit" pairs separated by ";", the
+// form used by the flag and environment variable.
+func parseRouteLimits(s string) (map[string]Limit, error) {
+    limits := map[string]Limit{}
+    for _, entry := range strings.Split(s, ";") {
+        if strings.TrimSpace(entry) == "" {
+            continue
+        }
+        route, value, ok := strings.Cut(entry, "=")
+        if !ok {
+            return nil, fmt.Errorf("route limit %q must look like \"POST /tasks=20/1m\"", entry)
+        }
+        l, err := ParseLimit(value)
+        if err != nil {
+            return nil, err
+        }
+        limits[strings.TrimSpace(route)] = l
+    }
+    return limits, nil
+}
+
+// formatPairs turns a config file map back into the "key=value;..." form
+// accepted by the flags.
+func formatPairs(values map[string]any) string {
+    entries := make([]string, 0, len(values))
+    for route, v := range values {
+        entries = append(entries, fmt.Sprintf("%s=%v", route, v))
+    }
+    sort.Strings(entries)
+    return strings.Join(entries, ";")
+}
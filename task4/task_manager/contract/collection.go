@@ -0,0 +1,195 @@
+// Package contract runs a Postman collection against an http.Handler in
+// process, so the collection doubles as an executable spec of the API.
+//
+// Requests run in collection order, folders depth first, with {{variables}}
+// filled in from the collection's variables and whatever earlier scripts
+// set. Test scripts are not run by a JavaScript engine: each line must be
+// one of the statements listed in script.go, and any other line fails the
+// request, so the collection cannot silently test less than it claims.
+package contract
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "regexp"
+    "strings"
+)
+
+// Collection is the part of a Postman v2.1 collection the runner uses.
+type Collection struct {
+    Info struct {
+        Name string `json:"name"`
+    } `json:"info"`
+    Variable []Variable `json:"variable"`
+    Item     []Item     `json:"item"`
+}
+
+type Variable struct {
+    Key   string `json:"key"`
+    Value string `json:"value"`
+}
+
+// Item is a request, or a folder of further items.
+type Item struct {
+    Name    string   `json:"name"`
+    Item    []Item   `json:"item"`
+    Request *Request `json:"request"`
+    Event   []Event  `json:"event"`
+}
+
+type Request struct {
+    Method string   `json:"method"`
+    Header []Header `json:"header"`
+    URL    URL      `json:"url"`
+    Body   *Body    `json:"body"`
+}
+
+type Header struct {
+    Key      string `json:"key"`
+    Value    string `json:"value"`
+    Disabled bool   `json:"disabled"`
+}
+
+// URL is a request URL, which collections give either as a string or as an
+// object with the string in "raw".
+type URL struct {
+    Raw string `json:"raw"`
+}
+
+func (u *URL) UnmarshalJSON(b []byte) error {
+    if len(b) > 0 && b[0] == '"' {
+        return json.Unmarshal(b, &u.Raw)
+    }
+    type plain URL
+    return json.Unmarshal(b, (*plain)(u))
+}
+
+// Body is a request body; only the "raw" mode is supported.
+type Body struct {
+    Mode string `json:"mode"`
+    Raw  string `json:"raw"`
+}
+
+// Event is a script run before ("prerequest") or after ("test") a request.
+type Event struct {
+    Listen string `json:"listen"`
+    Script struct {
+        Exec []string `json:"exec"`
+    } `json:"script"`
+}
+
+// Result is the outcome of one pm.test, or of a request or script that
+// failed outside of one, in which case Test is empty.
+type Result struct {
+    Request string
+    Test    string
+    Err     error
+}
+
+func Load(path string) (*Collection, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    var c Collection
+    if err := json.NewDecoder(f).Decode(&c); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &c, nil
+}
+
+// Run sends every request of c to h in order and returns the result of
+// every test.
+func (c *Collection) Run(h http.Handler) []Result {
+    vars := map[string]string{}
+    for _, v := range c.Variable {
+        vars[v.Key] = v.Value
+    }
+    var results []Result
+    var walk func(prefix string, items []Item)
+    walk = func(prefix string, items []Item) {
+        for _, item := range items {
+            name := prefix + item.Name
+            if item.Request == nil {
+                walk(name+" / ", item.Item)
+                continue
+            }
+            results = append(results, run(h, name, item, vars)...)
+        }
+    }
+    walk("", c.Item)
+    return results
+}
+
+func run(h http.Handler, name string, item Item, vars map[string]string) []Result {
+    fail := func(err error) []Result {
+        return []Result{{Request: name, Err: err}}
+    }
+    var results []Result
+    for _, e := range item.Event {
+        if e.Listen == "prerequest" {
+            r, err := execute(e.Script.Exec, vars, nil)
+            results = append(results, named(name, r)...)
+            if err != nil {
+                return append(results, fail(err)...)
+            }
+        }
+    }
+
+    req := item.Request
+    var body io.Reader
+    if req.Body != nil {
+        if req.Body.Mode != "raw" {
+            return fail(fmt.Errorf("body mode %q is not supported", req.Body.Mode))
+        }
+        body = strings.NewReader(expand(req.Body.Raw, vars))
+    }
+    method := req.Method
+    if method == "" {
+        method = http.MethodGet
+    }
+    r := httptest.NewRequest(method, expand(req.URL.Raw, vars), body)
+    for _, hd := range req.Header {
+        if !hd.Disabled {
+            r.Header.Set(expand(hd.Key, vars), expand(hd.Value, vars))
+        }
+    }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, r)
+
+    for _, e := range item.Event {
+        if e.Listen == "test" {
+            r, err := execute(e.Script.Exec, vars, w.Result())
+            results = append(results, named(name, r)...)
+            if err != nil {
+                return append(results, fail(err)...)
+            }
+        }
+    }
+    return results
+}
+
+func named(request string, results []Result) []Result {
+    for i := range results {
+        results[i].Request = request
+    }
+    return results
+}
+
+var placeholder = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// expand fills in {{name}} placeholders. Unknown names are left as they are,
+// so the request fails visibly rather than going to a half-built URL.
+func expand(s string, vars map[string]string) string {
+    return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+        if v, ok := vars[strings.TrimSpace(m[2:len(m)-2])]; ok {
+            return v
+        }
+        return m
+    })
+}
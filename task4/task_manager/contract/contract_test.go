@@ -0,0 +1,110 @@
+package contract_test
+
+import (
+    "net/http"
+    "strings"
+    "task_manager/config"
+    "task_manager/contract"
+    "task_manager/controllers"
+    "task_manager/data"
+    "task_manager/middleware"
+    "task_manager/router"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// TestCollection runs test_postman.json against the API on the in-memory
+// store.
+func TestCollection(t *testing.T) {
+    c, err := contract.Load("../test_postman.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+    results := c.Run(newRouter())
+    if len(results) == 0 {
+        t.Fatal("the collection ran no tests")
+    }
+    for _, r := range results {
+        if r.Err != nil {
+            t.Errorf("%s: %s: %v", r.Request, r.Test, r.Err)
+        }
+    }
+}
+
+// TestFailures checks that the runner reports what a collection gets wrong
+// instead of passing it.
+func TestFailures(t *testing.T) {
+    h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/") + `","tags":["a"]}`))
+    })
+    c := &contract.Collection{
+        Variable: []contract.Variable{{Key: "id", Value: "42"}},
+        Item: []contract.Item{{
+            Name:    "Get",
+            Request: &contract.Request{URL: contract.URL{Raw: "http://example.com/{{id}}"}},
+            Event:   []contract.Event{{Listen: "test"}},
+        }},
+    }
+    c.Item[0].Event[0].Script.Exec = []string{
+        `var body = pm.response.json();`,
+        `pm.test("passes", function () {`,
+        `    pm.response.to.have.status(200);`,
+        `    pm.expect(body.id).to.eql(pm.environment.get("id"));`,
+        `    pm.expect(body.tags).to.include('a');`,
+        `    pm.expect(body.tags.length).to.equal(1);`,
+        `});`,
+        `pm.test("wrong status", () => { pm.response.to.have.status(404); });`,
+        `pm.test("wrong value", function () {`,
+        `    pm.expect(body).to.not.have.property('id');`,
+        `});`,
+        `pm.test("unsupported", function () {`,
+        `    pm.expect(body.id).to.match(/4/);`,
+        `});`,
+        `console.log(body);`,
+    }
+    results := c.Run(h)
+
+    want := []struct {
+        test string
+        fail string
+    }{
+        {"passes", ""},
+        {"wrong status", "expected response to have status code 404 but got 200"},
+        {"wrong value", `expected {"id":"42","tags":["a"]} to not have property "id"`},
+        {"unsupported", "unsupported assertion"},
+        {"", "unsupported statement"},
+    }
+    if len(results) != len(want) {
+        t.Fatalf("got %d results, want %d: %v", len(results), len(want), results)
+    }
+    for i, w := range want {
+        r := results[i]
+        if r.Request != "Get" || r.Test != w.test {
+            t.Errorf("result %d is %s: %s, want Get: %s", i, r.Request, r.Test, w.test)
+        }
+        switch {
+        case w.fail == "" && r.Err != nil:
+            t.Errorf("%s: %v", w.test, r.Err)
+        case w.fail != "" && (r.Err == nil || !strings.Contains(r.Err.Error(), w.fail)):
+            t.Errorf("%s: got %v, want an error containing %q", w.test, r.Err, w.fail)
+        }
+    }
+}
+
+func newRouter() *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    repo := data.NewRepo(nil, "", true)
+    tasks := service.NewTasks(data.NewCachedRepo(repo, 100, time.Minute), data.NewUserRepo(nil, "", true), nil)
+    handler := controllers.SetHandler(tasks)
+    handler.Escalations = service.NewEscalations(data.NewEscalationRepo(nil, "", true), tasks)
+    handler.Time = service.NewTimeTracking(data.NewTimeRepo(nil, "", true), tasks)
+    tasks.Workspaces = data.NewWorkspaceRepo(nil, "", true)
+    handler.Workspaces = service.NewWorkspaces(tasks.Workspaces, tasks.Users)
+    cfg := config.Default()
+    limits := middleware.NewLimits(cfg.RateLimit, cfg.RouteLimits, cfg.TaskQuota)
+    return router.NewRouter(handler, controllers.NewHealth(repo, time.Second), cfg, limits)
+}
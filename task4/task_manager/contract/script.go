@@ -0,0 +1,505 @@
+package contract
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// The statements a script may use, one per line:
+//
+//	pm.test("name", function () {   // or () => {, closed by });
+//	var x = <value>;                 // also let and const
+//	pm.environment.set("key", <value>);
+//	pm.response.to.have.status(201);
+//	pm.response.to.have.header("ETag");
+//	pm.expect(<value>).to.<assertion>;
+//
+// Values are JSON literals, single-quoted strings, variables with property
+// and index paths (x.a[0].b, x.length), pm.response.json(), .text() and
+// .code, pm.environment.get("key") and Array.isArray(<value>). Assertions
+// may start with "not." and are eql, equal, property, a, an, include,
+// above, below, true, false, null, ok and empty, after any of chai's
+// connecting words such as "be", "have" or "deep". pm.collectionVariables,
+// pm.globals and pm.variables share the environment's variables.
+var (
+    testOpen   = regexp.MustCompile(`^pm\.test\(\s*("(?:[^"\\]|\\.)*"|'[^']*')\s*,\s*(?:function\s*\(\s*\)|\(\s*\)\s*=>)\s*\{(.*)$`)
+    assignment = regexp.MustCompile(`^(?:var|let|const)\s+([A-Za-z_$][\w$]*)\s*=\s*(.+)$`)
+    varCall    = regexp.MustCompile(`^pm\.(?:environment|collectionVariables|globals|variables)\.(get|set)\((.*)\)$`)
+    statusCall = regexp.MustCompile(`^pm\.response\.to\.have\.status\((.*)\)$`)
+    headerCall = regexp.MustCompile(`^pm\.response\.to\.have\.header\((.*)\)$`)
+    pathExpr   = regexp.MustCompile(`^[A-Za-z_$][\w$]*((?:\.[A-Za-z_$][\w$]*|\[\d+\]|\['[^']*'\]|\["[^"]*"\])*)$`)
+    pathStep   = regexp.MustCompile(`\.([A-Za-z_$][\w$]*)|\[(\d+)\]|\['([^']*)'\]|\["([^"]*)"\]`)
+)
+
+// chainWords only make chai assertions read well.
+var chainWords = map[string]bool{
+    "to": true, "be": true, "been": true, "is": true, "that": true, "which": true, "and": true,
+    "has": true, "have": true, "with": true, "at": true, "of": true, "same": true, "deep": true,
+}
+
+var assertions = map[string]bool{
+    "eql": true, "equal": true, "equals": true, "eq": true, "true": true, "false": true,
+    "null": true, "ok": true, "empty": true, "property": true, "a": true, "an": true,
+    "include": true, "contain": true, "includes": true, "contains": true, "above": true, "below": true,
+}
+
+type script struct {
+    vars   map[string]string
+    resp   *http.Response
+    body   []byte
+    read   bool
+    locals map[string]any
+}
+
+// execute runs lines against resp, which is nil before the request. It
+// returns the result of each pm.test, and an error if a statement outside
+// of one failed, which ends the script.
+func execute(lines []string, vars map[string]string, resp *http.Response) ([]Result, error) {
+    s := &script{vars: vars, resp: resp, locals: map[string]any{}}
+    var (
+        results []Result
+        current *Result
+    )
+    for i, line := range lines {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "//") {
+            continue
+        }
+        if m := testOpen.FindStringSubmatch(line); m != nil {
+            if current != nil {
+                return results, fmt.Errorf("line %d: pm.test inside pm.test", i+1)
+            }
+            name, err := s.eval(m[1])
+            if err != nil {
+                return results, fmt.Errorf("line %d: %w", i+1, err)
+            }
+            current = &Result{Test: fmt.Sprint(name)}
+            rest := strings.TrimSpace(m[2])
+            if inner, ok := strings.CutSuffix(strings.TrimSuffix(rest, ";"), "})"); ok {
+                current.Err = s.statements(inner)
+                results = append(results, *current)
+                current = nil
+            } else if rest != "" {
+                current.Err = s.statements(rest)
+            }
+            continue
+        }
+        if line == "});" || line == "})" {
+            if current == nil {
+                return results, fmt.Errorf("line %d: unexpected %s", i+1, line)
+            }
+            results = append(results, *current)
+            current = nil
+            continue
+        }
+        if current != nil {
+            // Like a thrown assertion, the first failure ends the test.
+            if current.Err == nil {
+                current.Err = s.statements(line)
+            }
+            continue
+        }
+        if err := s.statements(line); err != nil {
+            return results, fmt.Errorf("line %d: %w", i+1, err)
+        }
+    }
+    if current != nil {
+        return results, fmt.Errorf("pm.test %q is not closed", current.Test)
+    }
+    return results, nil
+}
+
+// statements runs the statements of line, which are separated by
+// semicolons.
+func (s *script) statements(line string) error {
+    for _, stmt := range split(line, ';') {
+        if stmt = strings.TrimSpace(stmt); stmt == "" {
+            continue
+        }
+        if err := s.statement(stmt); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *script) statement(stmt string) error {
+    if m := assignment.FindStringSubmatch(stmt); m != nil {
+        v, err := s.eval(m[2])
+        if err != nil {
+            return err
+        }
+        s.locals[m[1]] = v
+        return nil
+    }
+    if m := varCall.FindStringSubmatch(stmt); m != nil && m[1] == "set" {
+        args := split(m[2], ',')
+        if len(args) != 2 {
+            return fmt.Errorf("%s: want a key and a value", stmt)
+        }
+        key, err := s.eval(args[0])
+        if err != nil {
+            return err
+        }
+        v, err := s.eval(args[1])
+        if err != nil {
+            return err
+        }
+        s.vars[fmt.Sprint(key)] = str(v)
+        return nil
+    }
+    if m := statusCall.FindStringSubmatch(stmt); m != nil {
+        want, err := s.eval(m[1])
+        if err != nil {
+            return err
+        }
+        if s.resp == nil {
+            return errors.New("no response before the request")
+        }
+        if got := float64(s.resp.StatusCode); !reflect.DeepEqual(got, want) {
+            return fmt.Errorf("expected response to have status code %v but got %v", want, s.resp.StatusCode)
+        }
+        return nil
+    }
+    if m := headerCall.FindStringSubmatch(stmt); m != nil {
+        name, err := s.eval(m[1])
+        if err != nil {
+            return err
+        }
+        if s.resp == nil {
+            return errors.New("no response before the request")
+        }
+        if s.resp.Header.Get(fmt.Sprint(name)) == "" {
+            return fmt.Errorf("expected response to have header %v", name)
+        }
+        return nil
+    }
+    if arg, rest, ok := call(stmt, "pm.expect"); ok {
+        v, err := s.eval(arg)
+        if err != nil {
+            return err
+        }
+        return s.assert(v, rest)
+    }
+    return fmt.Errorf("unsupported statement %q", stmt)
+}
+
+// assert checks v against a chai chain such as ".to.have.property('id')".
+func (s *script) assert(v any, chain string) error {
+    words := strings.Split(strings.TrimPrefix(chain, "."), ".")
+    negate := false
+    i := 0
+    for ; i < len(words) && (chainWords[words[i]] || words[i] == "not"); i++ {
+        if words[i] == "not" {
+            negate = !negate
+        }
+    }
+    final := strings.Join(words[i:], ".")
+    name, arg := final, ""
+    if before, _, ok := strings.Cut(final, "("); ok {
+        a, rest, ok := call(final, before)
+        if !ok || rest != "" {
+            return fmt.Errorf("unsupported assertion %q", chain)
+        }
+        name, arg = before, a
+    }
+    if !assertions[name] {
+        return fmt.Errorf("unsupported assertion %q", chain)
+    }
+    var want any
+    if arg != "" {
+        var err error
+        if want, err = s.eval(arg); err != nil {
+            return err
+        }
+    }
+
+    var (
+        ok   bool
+        what string
+    )
+    switch name {
+    case "eql", "equal", "equals", "eq":
+        ok, what = reflect.DeepEqual(v, want), "equal "+show(want)
+    case "true", "false":
+        ok, what = v == (name == "true"), "be "+name
+    case "null":
+        ok, what = v == nil, "be null"
+    case "ok":
+        ok, what = truthy(v), "be truthy"
+    case "empty":
+        ok, what = empty(v), "be empty"
+    case "property":
+        m, isObject := v.(map[string]any)
+        _, has := m[fmt.Sprint(want)]
+        ok, what = isObject && has, "have property "+show(want)
+    case "a", "an":
+        ok, what = typeOf(v) == want, "be a "+fmt.Sprint(want)
+    case "include", "contain", "includes", "contains":
+        ok, what = includes(v, want), "include "+show(want)
+    case "above", "below":
+        n, isNumber := v.(float64)
+        limit, _ := want.(float64)
+        ok, what = isNumber && (name == "above" && n > limit || name == "below" && n < limit), "be "+name+" "+show(want)
+    }
+    if ok == negate {
+        if negate {
+            what = "not " + what
+        }
+        return fmt.Errorf("expected %s to %s", show(v), what)
+    }
+    return nil
+}
+
+// eval returns the value of expr, decoded as from JSON.
+func (s *script) eval(expr string) (any, error) {
+    expr = strings.TrimSpace(expr)
+    if arg, rest, ok := call(expr, "Array.isArray"); ok && rest == "" {
+        v, err := s.eval(arg)
+        _, isArray := v.([]any)
+        return isArray, err
+    }
+    switch expr {
+    case "pm.response.json()":
+        body, err := s.responseBody()
+        if err != nil {
+            return nil, err
+        }
+        var v any
+        if err := json.Unmarshal(body, &v); err != nil {
+            return nil, fmt.Errorf("response body is not JSON: %w", err)
+        }
+        return v, nil
+    case "pm.response.text()":
+        body, err := s.responseBody()
+        return string(body), err
+    case "pm.response.code":
+        if s.resp == nil {
+            return nil, errors.New("no response before the request")
+        }
+        return float64(s.resp.StatusCode), nil
+    case "true":
+        return true, nil
+    case "false":
+        return false, nil
+    case "null", "undefined":
+        return nil, nil
+    }
+    if m := varCall.FindStringSubmatch(expr); m != nil && m[1] == "get" {
+        key, err := s.eval(m[2])
+        if err != nil {
+            return nil, err
+        }
+        if v, ok := s.vars[fmt.Sprint(key)]; ok {
+            return v, nil
+        }
+        return nil, nil
+    }
+    if strings.HasPrefix(expr, "'") && strings.HasSuffix(expr, "'") && len(expr) > 1 {
+        return strings.ReplaceAll(expr[1:len(expr)-1], `\'`, "'"), nil
+    }
+    if n, err := strconv.ParseFloat(expr, 64); err == nil {
+        return n, nil
+    }
+    if strings.ContainsAny(expr[:1], `"[{`) {
+        var v any
+        if err := json.Unmarshal([]byte(expr), &v); err != nil {
+            return nil, fmt.Errorf("unsupported value %s", expr)
+        }
+        return v, nil
+    }
+    if m := pathExpr.FindStringSubmatch(expr); m != nil {
+        return s.path(expr[:len(expr)-len(m[1])], m[1])
+    }
+    return nil, fmt.Errorf("unsupported value %s", expr)
+}
+
+// path follows steps such as ".a[0]['b'].length" from the local variable
+// name. Missing properties are null, as undefined is in JavaScript.
+func (s *script) path(name, steps string) (any, error) {
+    v, ok := s.locals[name]
+    if !ok {
+        return nil, fmt.Errorf("%s is not defined", name)
+    }
+    for _, m := range pathStep.FindAllStringSubmatch(steps, -1) {
+        key := m[1] + m[3] + m[4]
+        switch x := v.(type) {
+        case map[string]any:
+            v = x[key]
+        case []any:
+            if key == "length" {
+                v = float64(len(x))
+                continue
+            }
+            i, err := strconv.Atoi(m[2])
+            if err != nil || i >= len(x) {
+                v = nil
+                continue
+            }
+            v = x[i]
+        case string:
+            if key != "length" {
+                return nil, fmt.Errorf("cannot read %s of a string", key)
+            }
+            v = float64(len(x))
+        default:
+            return nil, fmt.Errorf("cannot read %s%s of %s", key, m[2], show(x))
+        }
+    }
+    return v, nil
+}
+
+func (s *script) responseBody() ([]byte, error) {
+    if s.resp == nil {
+        return nil, errors.New("no response before the request")
+    }
+    if !s.read {
+        body, err := io.ReadAll(s.resp.Body)
+        if err != nil {
+            return nil, err
+        }
+        s.body, s.read = body, true
+    }
+    return s.body, nil
+}
+
+// call splits "name(args)rest" into args and rest, matching parentheses
+// outside of strings.
+func call(s, name string) (args, rest string, ok bool) {
+    if !strings.HasPrefix(s, name+"(") {
+        return "", "", false
+    }
+    depth := 0
+    var quote byte
+    for i := len(name); i < len(s); i++ {
+        c := s[i]
+        switch {
+        case quote != 0:
+            if c == '\\' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '(':
+            depth++
+        case c == ')':
+            if depth--; depth == 0 {
+                return s[len(name)+1 : i], s[i+1:], true
+            }
+        }
+    }
+    return "", "", false
+}
+
+// split cuts s at each sep outside of strings, parentheses, brackets and
+// braces.
+func split(s string, sep byte) []string {
+    var (
+        parts []string
+        depth int
+        quote byte
+        start int
+    )
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case quote != 0:
+            if c == '\\' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '(' || c == '[' || c == '{':
+            depth++
+        case c == ')' || c == ']' || c == '}':
+            depth--
+        case c == sep && depth == 0:
+            parts = append(parts, s[start:i])
+            start = i + 1
+        }
+    }
+    return append(parts, s[start:])
+}
+
+func str(v any) string {
+    if s, ok := v.(string); ok {
+        return s
+    }
+    return show(v)
+}
+
+func show(v any) string {
+    b, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return string(b)
+}
+
+func typeOf(v any) string {
+    switch v.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return "boolean"
+    case float64:
+        return "number"
+    case string:
+        return "string"
+    case []any:
+        return "array"
+    }
+    return "object"
+}
+
+func truthy(v any) bool {
+    switch x := v.(type) {
+    case nil:
+        return false
+    case bool:
+        return x
+    case float64:
+        return x != 0
+    case string:
+        return x != ""
+    }
+    return true
+}
+
+func empty(v any) bool {
+    switch x := v.(type) {
+    case string:
+        return x == ""
+    case []any:
+        return len(x) == 0
+    case map[string]any:
+        return len(x) == 0
+    }
+    return false
+}
+
+func includes(v, want any) bool {
+    switch x := v.(type) {
+    case string:
+        sub, ok := want.(string)
+        return ok && strings.Contains(x, sub)
+    case []any:
+        for _, item := range x {
+            if reflect.DeepEqual(item, want) {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+    "net/http"
+    "task_manager/models"
+    "task_manager/service"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+type moveRequest struct {
+    Status models.State `json:"status"`
+    After  *uuid.UUID   `json:"after"`
+    Before *uuid.UUID   `json:"before"`
+}
+
+func (h *Handler) GetBoard(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetBoard")
+    defer span.End()
+    board, err := h.Tasks.Board(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Board(board))
+}
+
+func (h *Handler) MoveTask(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.MoveTask")
+    defer span.End()
+    var req moveRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    task, err := h.Tasks.Move(ctx, c.Param("id"), service.Placement{Status: req.Status, After: req.After, Before: req.Before})
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Task(task))
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "strings"
+    "task_manager/middleware"
+    "task_manager/models"
+
+    "github.com/gin-gonic/gin/binding"
+    "github.com/go-playground/validator/v10"
+)
+
+func init() {
+    // Report binding failures under the JSON field names clients send.
+    if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+        v.RegisterTagNameFunc(func(f reflect.StructField) string {
+            name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+            if name == "" || name == "-" {
+                return f.Name
+            }
+            return name
+        })
+    }
+}
+
+// bindError turns a request binding failure into a validation error with
+// per-field details where the cause allows it.
+func bindError(err error) error {
+    var (
+        fields    validator.ValidationErrors
+        typeErr   *json.UnmarshalTypeError
+        syntaxErr *json.SyntaxError
+    )
+    if tooLarge := middleware.TooLarge(err); tooLarge != nil {
+        return tooLarge
+    }
+    switch {
+    case errors.As(err, &fields):
+        v := &models.ValidationError{}
+        for _, f := range fields {
+            v.Add(f.Field(), ruleMessage(f))
+        }
+        return v
+    case errors.As(err, &typeErr):
+        return models.NewValidationError(typeErr.Field, "must be a "+typeErr.Type.String())
+    case errors.As(err, &syntaxErr):
+        return models.NewValidationError("body", "is not valid JSON")
+    }
+    return models.NewValidationError("body", err.Error())
+}
+
+func ruleMessage(f validator.FieldError) string {
+    switch f.Tag() {
+    case "required":
+        return "is required"
+    case "min":
+        return "must have at least " + f.Param() + " items"
+    case "max":
+        return "must have at most " + f.Param() + " items"
+    case "oneof":
+        return "must be one of " + f.Param()
+    }
+    return "failed the " + f.Tag() + " rule"
+}
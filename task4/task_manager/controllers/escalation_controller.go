@@ -0,0 +1,86 @@
+package controllers
+
+import (
+    "net/http"
+    "task_manager/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+type ruleRequest struct {
+    Name      string            `json:"name" binding:"required"`
+    Priority  models.Importance `json:"priority" binding:"required"`
+    OverdueBy string            `json:"overdue_by" binding:"required"`
+    RaiseTo   models.Importance `json:"raise_to" binding:"required"`
+}
+
+func (h *Handler) CreateRule(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.CreateRule")
+    defer span.End()
+    var req ruleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    overdueBy, err := models.ParseDuration(req.OverdueBy)
+    if err != nil {
+        c.Error(models.NewValidationError("overdue_by", "must be a duration such as 36h or 2d"))
+        return
+    }
+    rule, err := h.Escalations.CreateRule(ctx, models.EscalationRule{
+        Name:      req.Name,
+        Priority:  req.Priority,
+        OverdueBy: overdueBy,
+        RaiseTo:   req.RaiseTo,
+    })
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).Rule(rule))
+}
+
+func (h *Handler) GetRules(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetRules")
+    defer span.End()
+    rules, err := h.Escalations.Rules(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Rules(rules))
+}
+
+func (h *Handler) DeleteRule(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.DeleteRule")
+    defer span.End()
+    if err := h.Escalations.DeleteRule(ctx, c.Param("id")); err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// GetEscalations lists every escalation, or those of the task in the
+// "task_id" query parameter.
+func (h *Handler) GetEscalations(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetEscalations")
+    defer span.End()
+    list, err := h.Escalations.List(ctx, c.Query("task_id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Escalations(list))
+}
+
+func (h *Handler) RevertEscalation(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.RevertEscalation")
+    defer span.End()
+    e, err := h.Escalations.Revert(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Escalation(e))
+}
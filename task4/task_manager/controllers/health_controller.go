@@ -0,0 +1,71 @@
+package controllers
+
+import (
+    "context"
+    "net/http"
+    "sync/atomic"
+    "task_manager/data"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+type Health struct {
+    Repo    *data.Repo
+    timeout time.Duration
+    ready   atomic.Bool
+}
+
+type componentStatus struct {
+    Status    string `json:"status"`
+    Backend   string `json:"backend,omitempty"`
+    LatencyMs int64  `json:"latency_ms,omitempty"`
+    Error     string `json:"error,omitempty"`
+}
+
+func NewHealth(repo *data.Repo, timeout time.Duration) *Health {
+    return &Health{Repo: repo, timeout: timeout}
+}
+
+// SetReady controls whether /readyz may report ready. It starts false so
+// traffic is only routed once startup finishes, and goes false again when
+// shutdown begins.
+func (h *Health) SetReady(ready bool) {
+    h.ready.Store(ready)
+}
+
+func (h *Health) Live(c *gin.Context) {
+    c.IndentedJSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Health) Ready(c *gin.Context) {
+    ready := h.ready.Load()
+    server := componentStatus{Status: "up"}
+    if !ready {
+        server.Status = "down"
+        server.Error = "not accepting traffic"
+    }
+
+    ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+    defer cancel()
+    start := time.Now()
+    storage := componentStatus{Status: "up", Backend: h.Repo.Backend()}
+    if err := h.Repo.Ping(ctx); err != nil {
+        ready = false
+        storage.Status = "down"
+        storage.Error = err.Error()
+    }
+    storage.LatencyMs = time.Since(start).Milliseconds()
+
+    status, code := "ready", http.StatusOK
+    if !ready {
+        status, code = "not ready", http.StatusServiceUnavailable
+    }
+    c.IndentedJSON(code, gin.H{
+        "status": status,
+        "components": gin.H{
+            "server":  server,
+            "storage": storage,
+        },
+    })
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Presenter shapes the response bodies of one API version. Handlers run the
+// same service logic for every version and hand their results to the
+// presenter of the version that routed the request, so a new version only
+// needs a new Presenter and a route group.
+type Presenter interface {
+    Task(task *models.Task) any
+    Tasks(tasks []models.Task) any
+    User(user *models.User) any
+    Users(users []models.User) any
+    CreatedUser(user *models.User, token string) any
+    Stats(stats *models.TaskStats) any
+    Rule(rule *models.EscalationRule) any
+    Rules(rules []models.EscalationRule) any
+    Escalation(e *models.Escalation) any
+    Escalations(list []models.Escalation) any
+    TimeEntry(e *models.TimeEntry) any
+    TimeEntries(entries []models.TimeEntry) any
+    TimeReport(report *models.TimeReport) any
+    Board(board map[models.State][]models.Task) any
+    Workspace(ws *models.Workspace) any
+    Workspaces(list []models.Workspace) any
+}
+
+// V1 renders the models as they are stored.
+type V1 struct{}
+
+func (V1) Task(task *models.Task) any {
+    return task
+}
+
+func (V1) Tasks(tasks []models.Task) any {
+    return tasks
+}
+
+func (V1) User(user *models.User) any {
+    return user
+}
+
+func (V1) Users(users []models.User) any {
+    return users
+}
+
+func (V1) CreatedUser(user *models.User, token string) any {
+    return map[string]any{"user": user, "token": token}
+}
+
+func (V1) Stats(stats *models.TaskStats) any {
+    return stats
+}
+
+func (V1) Rule(rule *models.EscalationRule) any {
+    return rule
+}
+
+func (V1) Rules(rules []models.EscalationRule) any {
+    return rules
+}
+
+func (V1) Escalation(e *models.Escalation) any {
+    return e
+}
+
+func (V1) Escalations(list []models.Escalation) any {
+    return list
+}
+
+func (V1) TimeEntry(e *models.TimeEntry) any {
+    return e
+}
+
+func (V1) TimeEntries(entries []models.TimeEntry) any {
+    return entries
+}
+
+func (V1) TimeReport(report *models.TimeReport) any {
+    return report
+}
+
+func (V1) Board(board map[models.State][]models.Task) any {
+    return board
+}
+
+func (V1) Workspace(ws *models.Workspace) any {
+    return ws
+}
+
+func (V1) Workspaces(list []models.Workspace) any {
+    return list
+}
+
+const zoneKey = "zone"
+
+// TimeZone reads the "tz" query parameter, an IANA time zone or "me" for the
+// caller's preference, in which responses then render due dates. It runs
+// after Identify.
+func (h *Handler) TimeZone(c *gin.Context) {
+    name := c.Query("tz")
+    if name == "" {
+        c.Next()
+        return
+    }
+    var loc *time.Location
+    if name == "me" {
+        user, ok := currentUser(c)
+        if !ok {
+            c.Error(models.NewError(models.ErrUnauthorized, "tz=me needs authentication"))
+            c.Abort()
+            return
+        }
+        loc = user.Location()
+    } else {
+        var err error
+        if loc, err = models.LoadZone(name); err != nil {
+            c.Error(models.NewValidationError("tz", "must be an IANA time zone such as Europe/Berlin, or me"))
+            c.Abort()
+            return
+        }
+    }
+    c.Set(zoneKey, loc)
+    c.Next()
+}
+
+// presenter returns h's presenter, rendering due dates in the zone TimeZone
+// found, if any.
+func (h *Handler) presenter(c *gin.Context) Presenter {
+    if loc, ok := zone(c); ok {
+        return zoned{Presenter: h.present, loc: loc}
+    }
+    return h.present
+}
+
+func zone(c *gin.Context) (*time.Location, bool) {
+    v, ok := c.Get(zoneKey)
+    if !ok {
+        return nil, false
+    }
+    loc, ok := v.(*time.Location)
+    return loc, ok
+}
+
+// zoned renders the due dates of tasks in loc and leaves everything else to
+// the wrapped presenter.
+type zoned struct {
+    Presenter
+    loc *time.Location
+}
+
+func (z zoned) Task(task *models.Task) any {
+    t := z.in(*task)
+    return z.Presenter.Task(&t)
+}
+
+func (z zoned) Tasks(tasks []models.Task) any {
+    return z.Presenter.Tasks(z.all(tasks))
+}
+
+func (z zoned) Board(board map[models.State][]models.Task) any {
+    local := make(map[models.State][]models.Task, len(board))
+    for status, col := range board {
+        local[status] = z.all(col)
+    }
+    return z.Presenter.Board(local)
+}
+
+func (z zoned) in(task models.Task) models.Task {
+    if task.DueDate != nil {
+        due := task.DueDate.In(z.loc)
+        task.DueDate = &due
+    }
+    return task
+}
+
+func (z zoned) all(tasks []models.Task) []models.Task {
+    local := make([]models.Task, len(tasks))
+    for i, t := range tasks {
+        local[i] = z.in(t)
+    }
+    return local
+}
@@ -1,97 +1,275 @@
 package controllers
 
 import (
+    "context"
     "net/http"
+    "strconv"
+    "strings"
     "task_manager/data"
     "task_manager/models"
+    "task_manager/service"
     "time"
 
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("task_manager/controllers")
+
 type Handler struct {
-    Repo *data.Repo
+    Tasks       *service.Tasks
+    Users       *data.UserRepo
+    Escalations *service.Escalations
+    Time        *service.TimeTracking
+    Workspaces  *service.Workspaces
+    present     Presenter
+}
+
+// taskRequest is a task as clients send it, whose due_date may also be an
+// expression such as "tomorrow 5pm"; see service.Tasks.DueDate.
+type taskRequest struct {
+    models.Task
+    DueDate *string `json:"due_date"`
+}
+
+type participantsRequest struct {
+    UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1"`
 }
 
-func SetHandler(repo *data.Repo) *Handler {
-    return &Handler{Repo: repo}
+func SetHandler(tasks *service.Tasks) *Handler {
+    return &Handler{Tasks: tasks, Users: tasks.Users, present: V1{}}
+}
+
+// Version returns a handler that shares h's service but renders responses
+// with p.
+func (h *Handler) Version(p Presenter) *Handler {
+    v := *h
+    v.present = p
+    return &v
 }
 
 func (h *Handler) Create(c *gin.Context) {
-    var task models.Task
-    if err := c.ShouldBindJSON(&task); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-        return
-    }
-    task.ID = uuid.New()
-    now := time.Now()
-    task.CreatedAt = now
-    task.UpdatedAt = now
-    task.Status = models.Pending
-    if err := task.Validate(); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    ctx, span := startSpan(c, "Handler.Create")
+    defer span.End()
+    task, err := h.bindTask(ctx, c)
+    if err != nil {
+        c.Error(err)
         return
     }
-    if err := h.Repo.Create(&task); err != nil {
-        c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    created, err := h.Tasks.Create(ctx, task)
+    if err != nil {
+        c.Error(err)
         return
     }
-    c.IndentedJSON(http.StatusCreated, task)
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).Task(created))
 }
 
 func (h *Handler) Update(c *gin.Context) {
-    id := c.Param("id")
-    if _, err := uuid.Parse(id); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID"})
+    ctx, span := startSpan(c, "Handler.Update")
+    defer span.End()
+    if _, err := uuid.Parse(c.Param("id")); err != nil {
+        c.Error(models.NewValidationError("id", "must be a UUID"))
         return
     }
-    var task models.Task
-    if err := c.ShouldBindJSON(&task); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    task, err := h.bindTask(ctx, c)
+    if err != nil {
+        c.Error(err)
         return
     }
-    task.ID = uuid.MustParse(id)
-    task.UpdatedAt = time.Now()
-    if err := h.Repo.Update(id, task); err != nil {
-        c.IndentedJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    updated, err := h.Tasks.Update(ctx, c.Param("id"), task)
+    if err != nil {
+        c.Error(err)
         return
     }
-    c.IndentedJSON(http.StatusOK, task)
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Task(updated))
 }
 
 func (h *Handler) Delete(c *gin.Context) {
-    id := c.Param("id")
-    if _, err := uuid.Parse(id); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID"})
-        return
-    }
-    if err := h.Repo.Delete(id); err != nil {
-        c.IndentedJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    ctx, span := startSpan(c, "Handler.Delete")
+    defer span.End()
+    if err := h.Tasks.Delete(ctx, c.Param("id")); err != nil {
+        c.Error(err)
         return
     }
     c.IndentedJSON(http.StatusNoContent, gin.H{})
 }
 
+// bindTask reads a taskRequest and resolves its due date.
+func (h *Handler) bindTask(ctx context.Context, c *gin.Context) (models.Task, error) {
+    var req taskRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        return models.Task{}, bindError(err)
+    }
+    if req.DueDate != nil {
+        due, err := h.Tasks.DueDate(ctx, *req.DueDate)
+        if err != nil {
+            return models.Task{}, err
+        }
+        req.Task.DueDate = &due
+    }
+    return req.Task, nil
+}
+
+// GetById answers conditional requests with 304 Not Modified when the
+// client's copy is current.
 func (h *Handler) GetById(c *gin.Context) {
-    id := c.Param("id")
-    if _, err := uuid.Parse(id); err != nil {
-        c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID"})
+    ctx, span := startSpan(c, "Handler.GetById")
+    defer span.End()
+    task, err := h.Tasks.Get(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
         return
     }
-    task, err := h.Repo.GetById(id)
-    if err != nil {
-        c.IndentedJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    if notModified(c, task) {
+        c.Status(http.StatusNotModified)
         return
     }
-    c.IndentedJSON(http.StatusOK, task)
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Task(task))
+}
+
+// notModified sets the ETag and Last-Modified of task, both derived from its
+// UpdatedAt, and reports whether the request's If-None-Match or, without
+// one, If-Modified-Since shows the client already has this version. The
+// ETag also names the zone due dates are rendered in, if not UTC.
+func notModified(c *gin.Context, task *models.Task) bool {
+    version := strconv.FormatInt(task.UpdatedAt.UnixNano(), 36)
+    if loc, ok := zone(c); ok && loc != time.UTC {
+        version += "-" + loc.String()
+    }
+    etag := `"` + version + `"`
+    c.Header("ETag", etag)
+    c.Header("Last-Modified", task.UpdatedAt.UTC().Format(http.TimeFormat))
+    if match := c.GetHeader("If-None-Match"); match != "" {
+        for _, candidate := range strings.Split(match, ",") {
+            candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+            if candidate == etag || candidate == "*" {
+                return true
+            }
+        }
+        return false
+    }
+    since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+    return err == nil && !task.UpdatedAt.Truncate(time.Second).After(since)
 }
 
 func (h *Handler) GetAll(c *gin.Context) {
-    tasks, err := h.Repo.GetAll()
+    ctx, span := startSpan(c, "Handler.GetAll")
+    defer span.End()
+    tasks, err := h.Tasks.List(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Tasks(tasks))
+}
+
+// statsDays is the default window of Stats and TimeReport: twelve weeks
+// ending today.
+const statsDays = 84
+
+// Stats takes an optional window as "from" and "to" dates (YYYY-MM-DD, UTC,
+// both inclusive).
+func (h *Handler) Stats(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.Stats")
+    defer span.End()
+    from, to, err := window(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    stats, err := h.Tasks.Stats(ctx, from, to)
     if err != nil {
-        c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.Error(err)
         return
     }
-    c.IndentedJSON(http.StatusOK, tasks)
-}
\ No newline at end of file
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Stats(stats))
+}
+
+// window reads the "from" and "to" query dates, by default the statsDays
+// ending today.
+func window(c *gin.Context) (from, to time.Time, err error) {
+    to = time.Now().UTC().Truncate(24 * time.Hour)
+    v := &models.ValidationError{}
+    if s := c.Query("to"); s != "" {
+        t, err := time.Parse(time.DateOnly, s)
+        if err != nil {
+            v.Add("to", "must be a date as YYYY-MM-DD")
+        }
+        to = t
+    }
+    from = to.AddDate(0, 0, 1-statsDays)
+    if s := c.Query("from"); s != "" {
+        t, err := time.Parse(time.DateOnly, s)
+        if err != nil {
+            v.Add("from", "must be a date as YYYY-MM-DD")
+        }
+        from = t
+    }
+    return from, to, v.Err()
+}
+
+func (h *Handler) Assign(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.Assign")
+    defer span.End()
+    h.addParticipants(ctx, c, h.Tasks.AddAssignees)
+}
+
+func (h *Handler) Unassign(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.Unassign")
+    defer span.End()
+    h.removeParticipant(ctx, c, h.Tasks.RemoveAssignee)
+}
+
+func (h *Handler) Watch(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.Watch")
+    defer span.End()
+    h.addParticipants(ctx, c, h.Tasks.AddWatchers)
+}
+
+func (h *Handler) Unwatch(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.Unwatch")
+    defer span.End()
+    h.removeParticipant(ctx, c, h.Tasks.RemoveWatcher)
+}
+
+func (h *Handler) addParticipants(ctx context.Context, c *gin.Context, add func(context.Context, string, []uuid.UUID) (*models.Task, error)) {
+    if _, err := uuid.Parse(c.Param("id")); err != nil {
+        c.Error(models.NewValidationError("id", "must be a UUID"))
+        return
+    }
+    var req participantsRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    task, err := add(ctx, c.Param("id"), req.UserIDs)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Task(task))
+}
+
+func (h *Handler) removeParticipant(ctx context.Context, c *gin.Context, remove func(context.Context, string, uuid.UUID) (*models.Task, error)) {
+    if _, err := uuid.Parse(c.Param("id")); err != nil {
+        c.Error(models.NewValidationError("id", "must be a UUID"))
+        return
+    }
+    userID, err := uuid.Parse(c.Param("userId"))
+    if err != nil {
+        c.Error(models.NewValidationError("userId", "must be a UUID"))
+        return
+    }
+    task, err := remove(ctx, c.Param("id"), userID)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Task(task))
+}
+
+func startSpan(c *gin.Context, name string) (context.Context, trace.Span) {
+    return tracer.Start(c.Request.Context(), name)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+    "net/http"
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+type timeEntryRequest struct {
+    Start   time.Time  `json:"start" binding:"required"`
+    End     *time.Time `json:"end"`
+    Seconds int64      `json:"seconds"`
+    Note    *string    `json:"note"`
+}
+
+func (h *Handler) StartTimer(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.StartTimer")
+    defer span.End()
+    e, err := h.Time.Start(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).TimeEntry(e))
+}
+
+func (h *Handler) StopTimer(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.StopTimer")
+    defer span.End()
+    e, err := h.Time.Stop(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).TimeEntry(e))
+}
+
+func (h *Handler) MyTimer(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.MyTimer")
+    defer span.End()
+    e, err := h.Time.Running(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).TimeEntry(e))
+}
+
+func (h *Handler) AddTime(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.AddTime")
+    defer span.End()
+    var req timeEntryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    e, err := h.Time.Add(ctx, c.Param("id"), models.TimeEntry{Start: req.Start, End: req.End, Seconds: req.Seconds, Note: req.Note})
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).TimeEntry(e))
+}
+
+func (h *Handler) GetTime(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetTime")
+    defer span.End()
+    entries, err := h.Time.List(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).TimeEntries(entries))
+}
+
+func (h *Handler) DeleteTime(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.DeleteTime")
+    defer span.End()
+    if err := h.Time.Delete(ctx, c.Param("id"), c.Param("entryId")); err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// TimeReport takes the same window as Stats.
+func (h *Handler) TimeReport(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.TimeReport")
+    defer span.End()
+    from, to, err := window(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    report, err := h.Time.Report(ctx, from, to)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).TimeReport(report))
+}
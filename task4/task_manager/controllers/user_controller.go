@@ -0,0 +1,180 @@
+package controllers
+
+import (
+    "net/http"
+    "task_manager/auth"
+    "task_manager/logging"
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+const userKey = "user"
+
+type createUserRequest struct {
+    Username string `json:"username" binding:"required"`
+    TimeZone string `json:"time_zone"`
+}
+
+type profileRequest struct {
+    TimeZone *string `json:"time_zone" binding:"required"`
+}
+
+func (h *Handler) CreateUser(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.CreateUser")
+    defer span.End()
+    var req createUserRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    user, token, err := models.NewUser(req.Username, time.Now())
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    user.TimeZone = req.TimeZone
+    if err := user.Validate(); err != nil {
+        c.Error(err)
+        return
+    }
+    if err := h.Users.Create(ctx, user); err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).CreatedUser(user, token))
+}
+
+func (h *Handler) GetUsers(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetUsers")
+    defer span.End()
+    users, err := h.Users.GetAll(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Users(users))
+}
+
+func (h *Handler) GetUserById(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetUserById")
+    defer span.End()
+    id := c.Param("id")
+    if _, err := uuid.Parse(id); err != nil {
+        c.Error(models.NewValidationError("id", "must be a UUID"))
+        return
+    }
+    user, err := h.Users.GetById(ctx, id)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).User(user))
+}
+
+func (h *Handler) MyTasks(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.MyTasks")
+    defer span.End()
+    user, _ := currentUser(c)
+    tasks, err := h.Tasks.Assigned(ctx, user.ID)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Tasks(tasks))
+}
+
+func (h *Handler) GetMe(c *gin.Context) {
+    user, _ := currentUser(c)
+    c.IndentedJSON(http.StatusOK, h.presenter(c).User(user))
+}
+
+// UpdateMe changes the caller's preferences: for now the time zone due date
+// expressions are read in.
+func (h *Handler) UpdateMe(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.UpdateMe")
+    defer span.End()
+    var req profileRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    user, _ := currentUser(c)
+    if _, err := models.LoadZone(*req.TimeZone); err != nil {
+        c.Error(models.NewValidationError("time_zone", "must be an IANA time zone such as Europe/Berlin"))
+        return
+    }
+    if err := h.Users.SetTimeZone(ctx, user.ID, *req.TimeZone); err != nil {
+        c.Error(err)
+        return
+    }
+    updated := *user
+    updated.TimeZone = *req.TimeZone
+    c.IndentedJSON(http.StatusOK, h.presenter(c).User(&updated))
+}
+
+// Identify resolves the caller from an "Authorization: Bearer <token>" header.
+// Anonymous requests pass through; a token that matches no user is rejected.
+func (h *Handler) Identify(c *gin.Context) {
+    user, err := auth.Authenticate(c.Request.Context(), h.Users, c.GetHeader("Authorization"))
+    if err != nil {
+        c.Error(err)
+        c.Abort()
+        return
+    }
+    if user != nil {
+        c.Set(userKey, user)
+        ctx := logging.With(c.Request.Context(), "user_id", user.ID.String())
+        c.Request = c.Request.WithContext(auth.WithUser(ctx, user))
+    }
+    c.Next()
+}
+
+func (h *Handler) RequireUser(c *gin.Context) {
+    if _, ok := currentUser(c); !ok {
+        c.Error(models.NewError(models.ErrUnauthorized, "authentication required"))
+        c.Abort()
+        return
+    }
+    c.Next()
+}
+
+// RequireAdmin lets through only callers whose username is in admins.
+func RequireAdmin(admins []string) gin.HandlerFunc {
+    allowed := make(map[string]bool, len(admins))
+    for _, name := range admins {
+        allowed[name] = true
+    }
+    return func(c *gin.Context) {
+        user, ok := currentUser(c)
+        if !ok {
+            c.Error(models.NewError(models.ErrUnauthorized, "authentication required"))
+            c.Abort()
+            return
+        }
+        if !allowed[user.Username] {
+            c.Error(models.NewError(models.ErrForbidden, "admin access required"))
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+// ClientKey identifies the caller for rate limiting and quotas: the
+// authenticated user, else the client IP.
+func ClientKey(c *gin.Context) string {
+    user, _ := currentUser(c)
+    return auth.ClientKey(user, c.ClientIP())
+}
+
+func currentUser(c *gin.Context) (*models.User, bool) {
+    v, ok := c.Get(userKey)
+    if !ok {
+        return nil, false
+    }
+    user, ok := v.(*models.User)
+    return user, ok
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+    "net/http"
+    "task_manager/data"
+    "task_manager/logging"
+    "task_manager/models"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+// WorkspaceHeader names the workspace a request works in. Without it
+// requests use the default workspace.
+const WorkspaceHeader = "X-Workspace-ID"
+
+type workspaceRequest struct {
+    Name     string                   `json:"name" binding:"required"`
+    Settings models.WorkspaceSettings `json:"settings"`
+}
+
+type memberRequest struct {
+    UserID uuid.UUID   `json:"user_id" binding:"required"`
+    Role   models.Role `json:"role" binding:"required"`
+}
+
+// Workspace scopes the rest of the request to the workspace in the
+// X-Workspace-ID header, which the caller must be a member of. It runs
+// after Identify.
+func (h *Handler) Workspace(c *gin.Context) {
+    id := c.GetHeader(WorkspaceHeader)
+    if id == "" {
+        c.Next()
+        return
+    }
+    if h.Workspaces == nil {
+        c.Error(models.ErrWorkspaceNotFound)
+        c.Abort()
+        return
+    }
+    ws, err := h.Workspaces.Resolve(c.Request.Context(), id)
+    if err != nil {
+        c.Error(err)
+        c.Abort()
+        return
+    }
+    ctx := logging.With(c.Request.Context(), "workspace_id", ws.ID.String())
+    c.Request = c.Request.WithContext(data.WithWorkspace(ctx, ws))
+    c.Next()
+}
+
+// WorkspaceKey extends ClientKey with the workspace of the request, so that
+// the same idempotency key may be used in different workspaces.
+func WorkspaceKey(c *gin.Context) string {
+    return ClientKey(c) + "|" + c.GetHeader(WorkspaceHeader)
+}
+
+func (h *Handler) CreateWorkspace(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.CreateWorkspace")
+    defer span.End()
+    var req workspaceRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    ws, err := h.Workspaces.Create(ctx, models.Workspace{Name: req.Name, Settings: req.Settings})
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusCreated, h.presenter(c).Workspace(ws))
+}
+
+// GetWorkspaces lists the workspaces the caller is a member of.
+func (h *Handler) GetWorkspaces(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetWorkspaces")
+    defer span.End()
+    list, err := h.Workspaces.List(ctx)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Workspaces(list))
+}
+
+func (h *Handler) GetWorkspace(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.GetWorkspace")
+    defer span.End()
+    ws, err := h.Workspaces.Get(ctx, c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Workspace(ws))
+}
+
+func (h *Handler) UpdateWorkspace(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.UpdateWorkspace")
+    defer span.End()
+    var req workspaceRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    ws, err := h.Workspaces.Update(ctx, c.Param("id"), models.Workspace{Name: req.Name, Settings: req.Settings})
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Workspace(ws))
+}
+
+func (h *Handler) SetMember(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.SetMember")
+    defer span.End()
+    var req memberRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(bindError(err))
+        return
+    }
+    ws, err := h.Workspaces.SetMember(ctx, c.Param("id"), models.Member{UserID: req.UserID, Role: req.Role})
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Workspace(ws))
+}
+
+func (h *Handler) RemoveMember(c *gin.Context) {
+    ctx, span := startSpan(c, "Handler.RemoveMember")
+    defer span.End()
+    userID, err := uuid.Parse(c.Param("userId"))
+    if err != nil {
+        c.Error(models.NewValidationError("userId", "must be a UUID"))
+        return
+    }
+    ws, err := h.Workspaces.RemoveMember(ctx, c.Param("id"), userID)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.IndentedJSON(http.StatusOK, h.presenter(c).Workspace(ws))
+}
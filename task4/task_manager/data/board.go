@@ -0,0 +1,126 @@
+package data
+
+import (
+    "bytes"
+    "context"
+    "sort"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LastRank returns the highest rank in the status column, or "" if it has
+// no ranked task.
+func (r *Repo) LastRank(ctx context.Context, status models.State) (_ string, err error) {
+    ctx, done := r.instrument(ctx, "last_rank")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        last := ""
+        for _, t := range r.tasks {
+            if t.WorkspaceID == ws && t.Status == status && t.Rank > last {
+                last = t.Rank
+            }
+        }
+        return last, nil
+    }
+    var t models.Task
+    opts := options.FindOne().SetSort(bson.D{{Key: "rank", Value: -1}}).SetProjection(bson.M{"rank": 1})
+    err = r.collection("tasks").FindOne(ctx, scoped(ctx, bson.M{"status": status}), opts).Decode(&t)
+    if err == mongo.ErrNoDocuments {
+        return "", nil
+    }
+    if err != nil {
+        return "", storeError(err)
+    }
+    return t.Rank, nil
+}
+
+// Column returns the tasks with status in board order: by rank, then by id
+// for ranks that tie.
+func (r *Repo) Column(ctx context.Context, status models.State) (_ []models.Task, err error) {
+    ctx, done := r.instrument(ctx, "column")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        tasks := []models.Task{}
+        for _, t := range r.tasks {
+            if t.WorkspaceID == ws && t.Status == status {
+                tasks = append(tasks, t)
+            }
+        }
+        sort.Slice(tasks, func(i, j int) bool {
+            if tasks[i].Rank != tasks[j].Rank {
+                return tasks[i].Rank < tasks[j].Rank
+            }
+            return bytes.Compare(tasks[i].ID[:], tasks[j].ID[:]) < 0
+        })
+        return tasks, nil
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "id", Value: 1}})
+    cursor, err := r.collection("tasks").Find(ctx, scoped(ctx, bson.M{"status": status}), opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    tasks := []models.Task{}
+    if err := cursor.All(ctx, &tasks); err != nil {
+        return nil, storeError(err)
+    }
+    return tasks, nil
+}
+
+// RankChange gives a task a new rank, provided it still has the old one.
+type RankChange struct {
+    ID  uuid.UUID
+    Old string
+    New string
+}
+
+// SetRanks applies changes and returns how many applied. A task moved or
+// deleted since its change was computed keeps its state.
+func (r *Repo) SetRanks(ctx context.Context, changes []RankChange, at time.Time) (_ int, err error) {
+    ctx, done := r.instrument(ctx, "set_ranks")
+    defer done(&err)
+    if len(changes) == 0 {
+        return 0, nil
+    }
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        applied := 0
+        for _, c := range changes {
+            for i := range r.tasks {
+                if r.tasks[i].ID == c.ID && r.tasks[i].WorkspaceID == ws && r.tasks[i].Rank == c.Old {
+                    r.tasks[i].Rank = c.New
+                    r.tasks[i].UpdatedAt = at
+                    applied++
+                }
+            }
+        }
+        return applied, nil
+    }
+    writes := make([]mongo.WriteModel, len(changes))
+    for i, c := range changes {
+        filter := scoped(ctx, bson.M{"id": c.ID, "rank": c.Old})
+        if c.Old == "" {
+            filter["rank"] = bson.M{"$exists": false}
+        }
+        writes[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": bson.M{"rank": c.New, "updated_at": at}})
+    }
+    res, err := r.collection("tasks").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+    if err != nil {
+        return 0, storeError(err)
+    }
+    return int(res.ModifiedCount), nil
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+    "container/list"
+    "context"
+    "slices"
+    "sync"
+    "task_manager/metrics"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// CachedRepo keeps up to size recently read tasks of another TaskRepository
+// in memory, so GetById rarely reaches the store. Every method that changes
+// a task drops it from the cache, and entries expire after ttl so that
+// changes made through other instances show within it. Every other method
+// goes straight to the wrapped repository.
+type CachedRepo struct {
+    TaskRepository
+    size int
+    ttl  time.Duration
+
+    mu      sync.Mutex
+    order   *list.List // of *cacheEntry, most recently used first
+    entries map[uuid.UUID]*list.Element
+    // generation counts invalidations, so that a read which raced with a
+    // write does not cache what it read.
+    generation uint64
+    hits       uint64
+    misses     uint64
+}
+
+type cacheEntry struct {
+    task    models.Task
+    expires time.Time
+}
+
+// CacheStats counts the lookups a CachedRepo answered from memory and those
+// it passed on.
+type CacheStats struct {
+    Hits    uint64
+    Misses  uint64
+    Entries int
+}
+
+func NewCachedRepo(repo TaskRepository, size int, ttl time.Duration) *CachedRepo {
+    return &CachedRepo{
+        TaskRepository: repo,
+        size:           size,
+        ttl:            ttl,
+        order:          list.New(),
+        entries:        map[uuid.UUID]*list.Element{},
+    }
+}
+
+// CacheStats returns the lookups so far and the number of cached tasks.
+func (c *CachedRepo) CacheStats() CacheStats {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+func (c *CachedRepo) GetById(ctx context.Context, id string) (*models.Task, error) {
+    key, err := uuid.Parse(id)
+    if err != nil {
+        return c.TaskRepository.GetById(ctx, id)
+    }
+    now := time.Now()
+    c.mu.Lock()
+    // A task cached for another workspace is left to the store to refuse.
+    if el, ok := c.entries[key]; ok && el.Value.(*cacheEntry).task.WorkspaceID == workspaceID(ctx) {
+        e := el.Value.(*cacheEntry)
+        if now.Before(e.expires) {
+            c.order.MoveToFront(el)
+            c.hits++
+            task := clone(e.task)
+            c.mu.Unlock()
+            metrics.ObserveCache(true)
+            return &task, nil
+        }
+        c.remove(key)
+    }
+    c.misses++
+    generation := c.generation
+    c.mu.Unlock()
+    metrics.ObserveCache(false)
+
+    task, err := c.TaskRepository.GetById(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.generation == generation {
+        c.add(clone(*task), now.Add(c.ttl))
+    }
+    return task, nil
+}
+
+func (c *CachedRepo) Update(ctx context.Context, id string, task models.Task) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.Update(ctx, id, task)
+}
+
+func (c *CachedRepo) Delete(ctx context.Context, id string) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.Delete(ctx, id)
+}
+
+func (c *CachedRepo) AddTracked(ctx context.Context, id uuid.UUID, seconds int64, at time.Time) error {
+    defer c.invalidate(id.String())
+    return c.TaskRepository.AddTracked(ctx, id, seconds, at)
+}
+
+func (c *CachedRepo) SetPriority(ctx context.Context, id uuid.UUID, from, to models.Importance, at time.Time) error {
+    defer c.invalidate(id.String())
+    return c.TaskRepository.SetPriority(ctx, id, from, to, at)
+}
+
+func (c *CachedRepo) AddAssignees(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.AddAssignees(ctx, id, userIDs, at)
+}
+
+func (c *CachedRepo) RemoveAssignee(ctx context.Context, id string, userID uuid.UUID, at time.Time) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.RemoveAssignee(ctx, id, userID, at)
+}
+
+func (c *CachedRepo) AddWatchers(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.AddWatchers(ctx, id, userIDs, at)
+}
+
+func (c *CachedRepo) RemoveWatcher(ctx context.Context, id string, userID uuid.UUID, at time.Time) error {
+    defer c.invalidate(id)
+    return c.TaskRepository.RemoveWatcher(ctx, id, userID, at)
+}
+
+func (c *CachedRepo) SetRanks(ctx context.Context, changes []RankChange, at time.Time) (int, error) {
+    ids := make([]string, len(changes))
+    for i, change := range changes {
+        ids[i] = change.ID.String()
+    }
+    defer c.invalidate(ids...)
+    return c.TaskRepository.SetRanks(ctx, changes, at)
+}
+
+// invalidate drops the tasks with ids. It runs after the write, whatever its
+// outcome, since a write that failed, e.g. by timing out, may still have
+// been applied.
+func (c *CachedRepo) invalidate(ids ...string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.generation++
+    for _, id := range ids {
+        if key, err := uuid.Parse(id); err == nil {
+            c.remove(key)
+        }
+    }
+}
+
+func (c *CachedRepo) add(task models.Task, expires time.Time) {
+    if el, ok := c.entries[task.ID]; ok {
+        el.Value = &cacheEntry{task: task, expires: expires}
+        c.order.MoveToFront(el)
+        return
+    }
+    c.entries[task.ID] = c.order.PushFront(&cacheEntry{task: task, expires: expires})
+    for c.order.Len() > c.size {
+        c.remove(c.order.Back().Value.(*cacheEntry).task.ID)
+    }
+}
+
+func (c *CachedRepo) remove(id uuid.UUID) {
+    if el, ok := c.entries[id]; ok {
+        c.order.Remove(el)
+        delete(c.entries, id)
+    }
+}
+
+// clone copies a task, down to what its pointers and slices refer to, so
+// that callers cannot change the cached one.
+func clone(task models.Task) models.Task {
+    task.CompletedAt = clonePtr(task.CompletedAt)
+    task.Description = clonePtr(task.Description)
+    task.DueDate = clonePtr(task.DueDate)
+    task.OwnerID = clonePtr(task.OwnerID)
+    task.Assignees = slices.Clone(task.Assignees)
+    task.Watchers = slices.Clone(task.Watchers)
+    task.Project = clonePtr(task.Project)
+    task.EstimateSeconds = clonePtr(task.EstimateSeconds)
+    return task
+}
+
+func clonePtr[T any](p *T) *T {
+    if p == nil {
+        return nil
+    }
+    v := *p
+    return &v
+}
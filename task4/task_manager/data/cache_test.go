@@ -0,0 +1,92 @@
+package data
+
+import (
+    "context"
+    "errors"
+    "task_manager/models"
+    "testing"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+func TestCachedRepo(t *testing.T) {
+    ctx := context.Background()
+    repo := NewRepo(nil, "", true)
+    cache := NewCachedRepo(repo, 2, time.Minute)
+    var tasks []*models.Task
+    for _, name := range []string{"a", "b", "c"} {
+        task := models.NewTask(name, "", models.Pending, models.Low, nil, time.Now())
+        if err := cache.Create(ctx, task); err != nil {
+            t.Fatal(err)
+        }
+        tasks = append(tasks, task)
+    }
+    get := func(task *models.Task) *models.Task {
+        t.Helper()
+        got, err := cache.GetById(ctx, task.ID.String())
+        if err != nil {
+            t.Fatal(err)
+        }
+        return got
+    }
+
+    get(tasks[0])
+    get(tasks[0])
+    get(tasks[1])
+    get(tasks[2]) // evicts a, the least recently used
+    get(tasks[0])
+    if got, want := cache.CacheStats(), (CacheStats{Hits: 1, Misses: 4, Entries: 2}); got != want {
+        t.Errorf("CacheStats = %+v; want %+v", got, want)
+    }
+
+    // Callers get copies, and writes drop the cached task.
+    get(tasks[0]).Name = "changed by a caller"
+    if got := get(tasks[0]); got.Name != "a" {
+        t.Errorf("cached task changed through a returned copy: %q", got.Name)
+    }
+    due, desc, project, estimate, owner := time.Now().Add(time.Hour).UTC(), "d", "p", int64(60), uuid.New()
+    full := models.NewTask("full", desc, models.Pending, models.Low, &due, time.Now())
+    full.OwnerID, full.Project, full.EstimateSeconds = &owner, &project, &estimate
+    if err := repo.Create(ctx, full); err != nil {
+        t.Fatal(err)
+    }
+    get(full) // caches it
+    changed := get(full)
+    *changed.DueDate = due.Add(time.Hour)
+    *changed.Description = "changed"
+    *changed.Project = "changed"
+    *changed.EstimateSeconds = 1
+    *changed.OwnerID = uuid.New()
+    got := get(full)
+    if !got.DueDate.Equal(due) || *got.Description != desc || *got.Project != project || *got.EstimateSeconds != estimate || *got.OwnerID != owner {
+        t.Errorf("cached task changed through the pointers of a returned copy: %+v", got)
+    }
+    if err := cache.Update(ctx, tasks[0].ID.String(), models.Task{Name: "renamed"}); err != nil {
+        t.Fatal(err)
+    }
+    if got := get(tasks[0]); got.Name != "renamed" {
+        t.Errorf("name after Update = %q; want renamed", got.Name)
+    }
+    if err := cache.AddTracked(ctx, tasks[0].ID, 60, time.Now()); err != nil {
+        t.Fatal(err)
+    }
+    if got := get(tasks[0]); got.TrackedSeconds != 60 {
+        t.Errorf("tracked after AddTracked = %d; want 60", got.TrackedSeconds)
+    }
+    if err := cache.Delete(ctx, tasks[0].ID.String()); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := cache.GetById(ctx, tasks[0].ID.String()); !errors.Is(err, models.ErrTaskNotFound) {
+        t.Errorf("GetById after Delete error = %v; want ErrTaskNotFound", err)
+    }
+
+    // Entries expire after the TTL.
+    short := NewCachedRepo(repo, 2, time.Nanosecond)
+    short.GetById(ctx, tasks[1].ID.String())
+    time.Sleep(time.Millisecond)
+    short.GetById(ctx, tasks[1].ID.String())
+    if got := short.CacheStats(); got.Hits != 0 || got.Misses != 2 {
+        t.Errorf("CacheStats with an expired entry = %+v; want two misses", got)
+    }
+}
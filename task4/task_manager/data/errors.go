@@ -0,0 +1,34 @@
+package data
+
+import (
+    "context"
+    "errors"
+    "task_manager/models"
+
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// storeError classifies driver errors into the models error kinds so callers
+// can tell an outage, a slow query or a duplicate from a bug. An unreachable
+// server is checked first since selection also fails once the deadline hits.
+func storeError(err error) error {
+    var selection topology.ServerSelectionError
+    switch {
+    case err == nil:
+        return nil
+    case errors.As(err, &selection),
+        errors.Is(err, mongo.ErrClientDisconnected):
+        return models.Wrap(models.ErrUnavailable, "storage unavailable", err)
+    case errors.Is(err, context.DeadlineExceeded),
+        mongo.IsTimeout(err):
+        return models.Wrap(models.ErrTimeout, "storage timed out", err)
+    case errors.Is(err, context.Canceled):
+        return models.Wrap(models.ErrCanceled, "request canceled", err)
+    case mongo.IsNetworkError(err):
+        return models.Wrap(models.ErrUnavailable, "storage unavailable", err)
+    case mongo.IsDuplicateKeyError(err):
+        return models.Wrap(models.ErrConflict, "record already exists", err)
+    }
+    return err
+}
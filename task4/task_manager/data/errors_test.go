@@ -0,0 +1,45 @@
+package data
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "task_manager/models"
+    "testing"
+    "time"
+
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestStoreError(t *testing.T) {
+    testcases := []struct {
+        cases string
+        err   error
+        kind  error
+    }{
+        {"deadline", fmt.Errorf("find: %w", context.DeadlineExceeded), models.ErrTimeout},
+        {"server down", topology.ServerSelectionError{Wrapped: context.DeadlineExceeded}, models.ErrUnavailable},
+        {"disconnected", mongo.ErrClientDisconnected, models.ErrUnavailable},
+        {"client gone", context.Canceled, models.ErrCanceled},
+        {"duplicate", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, models.ErrConflict},
+    }
+    for _, test := range testcases {
+        got := storeError(test.err)
+        if !errors.Is(got, test.kind) {
+            t.Errorf("%s: storeError(%v) = %v; want kind %v", test.cases, test.err, got, test.kind)
+        }
+    }
+    if err := storeError(nil); err != nil {
+        t.Errorf("storeError(nil) = %v", err)
+    }
+}
+
+func TestTimeoutsValidate(t *testing.T) {
+    if err := (Timeouts{Ops: map[string]time.Duration{"get_all": time.Second}}).Validate(); err != nil {
+        t.Errorf("known operation rejected: %v", err)
+    }
+    if err := (Timeouts{Ops: map[string]time.Duration{"getall": time.Second}}).Validate(); err == nil {
+        t.Error("unknown operation accepted")
+    }
+}
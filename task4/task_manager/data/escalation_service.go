@@ -0,0 +1,296 @@
+package data
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EscalationRepo stores the escalation rules and the record of every change
+// they made.
+type EscalationRepo struct {
+    Client      *mongo.Client
+    dbName      string
+    isMemory    bool
+    timeouts    Timeouts
+    mu          sync.RWMutex
+    rules       []models.EscalationRule
+    escalations []models.Escalation
+}
+
+func NewEscalationRepo(client *mongo.Client, dbName string, isMemory bool) *EscalationRepo {
+    return &EscalationRepo{Client: client, dbName: dbName, isMemory: isMemory}
+}
+
+// SetTimeouts bounds each operation; see Timeouts.
+func (r *EscalationRepo) SetTimeouts(t Timeouts) {
+    r.timeouts = t
+}
+
+func (r *EscalationRepo) collection(coll string) *mongo.Collection {
+    return r.Client.Database(r.dbName).Collection(coll)
+}
+
+func (r *EscalationRepo) CreateRule(ctx context.Context, rule *models.EscalationRule) (err error) {
+    ctx, done := r.instrument(ctx, "rule_create")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.rules = append(r.rules, *rule)
+        return nil
+    }
+    _, err = r.collection("escalation_rules").InsertOne(ctx, rule)
+    return storeError(err)
+}
+
+// Rules lists every rule, oldest first.
+func (r *EscalationRepo) Rules(ctx context.Context) (_ []models.EscalationRule, err error) {
+    ctx, done := r.instrument(ctx, "rule_get_all")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        return append([]models.EscalationRule{}, r.rules...), nil
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+    cursor, err := r.collection("escalation_rules").Find(ctx, bson.M{}, opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    rules := []models.EscalationRule{}
+    if err := cursor.All(ctx, &rules); err != nil {
+        return nil, storeError(err)
+    }
+    return rules, nil
+}
+
+// DeleteRule removes a rule but keeps the escalations it made.
+func (r *EscalationRepo) DeleteRule(ctx context.Context, id uuid.UUID) (err error) {
+    ctx, done := r.instrument(ctx, "rule_delete")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for i, rule := range r.rules {
+            if rule.ID == id {
+                r.rules = append(r.rules[:i], r.rules[i+1:]...)
+                return nil
+            }
+        }
+        return models.ErrRuleNotFound
+    }
+    res, err := r.collection("escalation_rules").DeleteOne(ctx, bson.M{"id": id})
+    if err != nil {
+        return storeError(err)
+    }
+    if res.DeletedCount == 0 {
+        return models.ErrRuleNotFound
+    }
+    return nil
+}
+
+// Record stores an escalation. A rule escalates each task at most once, so a
+// second record for the same rule and task is an ErrAlreadyEscalated.
+func (r *EscalationRepo) Record(ctx context.Context, e *models.Escalation) (err error) {
+    ctx, done := r.instrument(ctx, "escalation_create")
+    defer done(&err)
+    e.WorkspaceID = workspaceID(ctx)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for _, stored := range r.escalations {
+            if stored.RuleID == e.RuleID && stored.TaskID == e.TaskID {
+                return models.ErrAlreadyEscalated
+            }
+        }
+        r.escalations = append(r.escalations, *e)
+        return nil
+    }
+    _, err = r.collection("escalations").InsertOne(ctx, e)
+    if mongo.IsDuplicateKeyError(err) {
+        return models.ErrAlreadyEscalated
+    }
+    return storeError(err)
+}
+
+// Forget deletes an escalation whose priority change could not be applied.
+func (r *EscalationRepo) Forget(ctx context.Context, id uuid.UUID) (err error) {
+    ctx, done := r.instrument(ctx, "escalation_delete")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i, e := range r.escalations {
+            if e.ID == id && e.WorkspaceID == ws {
+                r.escalations = append(r.escalations[:i], r.escalations[i+1:]...)
+                return nil
+            }
+        }
+        return models.ErrEscalationNotFound
+    }
+    res, err := r.collection("escalations").DeleteOne(ctx, scoped(ctx, bson.M{"id": id}))
+    if err != nil {
+        return storeError(err)
+    }
+    if res.DeletedCount == 0 {
+        return models.ErrEscalationNotFound
+    }
+    return nil
+}
+
+// Escalations lists the escalations of one task, or of every task when
+// taskID is nil, newest first.
+func (r *EscalationRepo) Escalations(ctx context.Context, taskID *uuid.UUID) (_ []models.Escalation, err error) {
+    ctx, done := r.instrument(ctx, "escalation_get_all")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        list := []models.Escalation{}
+        for _, e := range r.escalations {
+            if e.WorkspaceID == ws && (taskID == nil || e.TaskID == *taskID) {
+                list = append(list, e)
+            }
+        }
+        sort.SliceStable(list, func(i, j int) bool { return list[i].At.After(list[j].At) })
+        return list, nil
+    }
+    filter := scoped(ctx, bson.M{})
+    if taskID != nil {
+        filter["task_id"] = *taskID
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}})
+    cursor, err := r.collection("escalations").Find(ctx, filter, opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    list := []models.Escalation{}
+    if err := cursor.All(ctx, &list); err != nil {
+        return nil, storeError(err)
+    }
+    return list, nil
+}
+
+func (r *EscalationRepo) GetEscalation(ctx context.Context, id uuid.UUID) (_ *models.Escalation, err error) {
+    ctx, done := r.instrument(ctx, "escalation_get_by_id")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        for _, e := range r.escalations {
+            if e.ID == id && e.WorkspaceID == ws {
+                return &e, nil
+            }
+        }
+        return nil, models.ErrEscalationNotFound
+    }
+    var e models.Escalation
+    err = r.collection("escalations").FindOne(ctx, scoped(ctx, bson.M{"id": id})).Decode(&e)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrEscalationNotFound
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    return &e, nil
+}
+
+// EscalatedTasks returns the IDs of the tasks ruleID has escalated, whether
+// or not the change was reverted since.
+func (r *EscalationRepo) EscalatedTasks(ctx context.Context, ruleID uuid.UUID) (_ map[uuid.UUID]bool, err error) {
+    ctx, done := r.instrument(ctx, "escalation_tasks")
+    defer done(&err)
+    ids := map[uuid.UUID]bool{}
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        for _, e := range r.escalations {
+            if e.RuleID == ruleID {
+                ids[e.TaskID] = true
+            }
+        }
+        return ids, nil
+    }
+    opts := options.Find().SetProjection(bson.M{"task_id": 1})
+    cursor, err := r.collection("escalations").Find(ctx, bson.M{"rule_id": ruleID}, opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    var found []struct {
+        TaskID uuid.UUID `bson:"task_id"`
+    }
+    if err := cursor.All(ctx, &found); err != nil {
+        return nil, storeError(err)
+    }
+    for _, f := range found {
+        ids[f.TaskID] = true
+    }
+    return ids, nil
+}
+
+// MarkReverted records that by reverted the escalation id at at. Only the
+// first revert succeeds; later ones are an ErrAlreadyReverted.
+func (r *EscalationRepo) MarkReverted(ctx context.Context, id uuid.UUID, at time.Time, by uuid.UUID) (err error) {
+    ctx, done := r.instrument(ctx, "escalation_revert")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i := range r.escalations {
+            e := &r.escalations[i]
+            if e.ID != id || e.WorkspaceID != ws {
+                continue
+            }
+            if e.RevertedAt != nil {
+                return models.ErrAlreadyReverted
+            }
+            e.RevertedAt, e.RevertedBy = &at, &by
+            return nil
+        }
+        return models.ErrEscalationNotFound
+    }
+    res, err := r.collection("escalations").UpdateOne(ctx,
+        scoped(ctx, bson.M{"id": id, "reverted_at": bson.M{"$exists": false}}),
+        bson.M{"$set": bson.M{"reverted_at": at, "reverted_by": by}},
+    )
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        n, err := r.collection("escalations").CountDocuments(ctx, scoped(ctx, bson.M{"id": id}))
+        if err != nil {
+            return storeError(err)
+        }
+        if n == 0 {
+            return models.ErrEscalationNotFound
+        }
+        return models.ErrAlreadyReverted
+    }
+    return nil
+}
+
+func (r *EscalationRepo) instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+    backend := "mongo"
+    if r.isMemory {
+        backend = "memory"
+    }
+    return instrument(ctx, backend, operation, r.timeouts)
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "strings"
+    "task_manager/metrics"
+    "time"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("task_manager/data")
+
+// Operations names every repository operation, as used in metrics, spans and
+// Timeouts.Ops.
+var Operations = []string{
+    "create", "update", "delete", "get_all", "get_by_id", "get_by_ids", "get_by_assignee", "find", "stats",
+    "add_tracked", "set_priority", "last_rank", "column", "set_ranks",
+    "add_assignees", "remove_assignees", "add_watchers", "remove_watchers",
+    "user_create", "user_get_all", "user_get_by_id", "user_get_by_token", "user_missing", "user_set_time_zone",
+    "rule_create", "rule_get_all", "rule_delete",
+    "escalation_create", "escalation_delete", "escalation_get_all", "escalation_get_by_id",
+    "escalation_tasks", "escalation_revert",
+    "time_start", "time_stop", "time_running", "time_add", "time_delete", "time_get_all", "time_report",
+    "workspace_create", "workspace_get_by_id", "workspace_get_all", "workspace_for_user", "workspace_update",
+    "workspace_set_member", "workspace_remove_member",
+}
+
+// Timeouts bounds how long a repository operation may run on top of any
+// deadline the caller's context already carries. Operations without an entry
+// in Ops use Default; zero means no extra bound.
+type Timeouts struct {
+    Default time.Duration
+    Ops     map[string]time.Duration
+}
+
+// Validate rejects entries in Ops that name no operation.
+func (t Timeouts) Validate() error {
+    var unknown []string
+    for op := range t.Ops {
+        if !slices.Contains(Operations, op) {
+            unknown = append(unknown, op)
+        }
+    }
+    if len(unknown) > 0 {
+        slices.Sort(unknown)
+        return fmt.Errorf("query_timeouts: unknown operations %s; known: %s", strings.Join(unknown, ", "), strings.Join(Operations, ", "))
+    }
+    return nil
+}
+
+func (t Timeouts) of(operation string) time.Duration {
+    if d, ok := t.Ops[operation]; ok {
+        return d
+    }
+    return t.Default
+}
+
+// instrument starts a span for a repository operation and applies its
+// timeout. The returned function ends both and records the operation's
+// latency and error in metrics.
+func instrument(ctx context.Context, backend, operation string, timeouts Timeouts) (context.Context, func(*error)) {
+    start := time.Now()
+    ctx, span := tracer.Start(ctx, "Repo."+operation, trace.WithAttributes(
+        attribute.String("db.system", backend),
+        attribute.String("db.operation", operation),
+    ))
+    cancel := context.CancelFunc(func() {})
+    if d := timeouts.of(operation); d > 0 {
+        ctx, cancel = context.WithTimeout(ctx, d)
+    }
+    return ctx, func(err *error) {
+        cancel()
+        metrics.ObserveRepo(operation, start, *err)
+        if *err != nil {
+            span.RecordError(*err)
+            span.SetStatus(codes.Error, (*err).Error())
+        }
+        span.End()
+    }
+}
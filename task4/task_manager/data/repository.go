@@ -0,0 +1,33 @@
+package data
+
+import (
+    "context"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// TaskRepository is what the services need from a task store. Repo
+// implements it against MongoDB or memory, and CachedRepo in front of
+// another TaskRepository.
+type TaskRepository interface {
+    Create(ctx context.Context, task *models.Task) error
+    Update(ctx context.Context, id string, task models.Task) error
+    Delete(ctx context.Context, id string) error
+    GetAll(ctx context.Context) ([]models.Task, error)
+    GetById(ctx context.Context, id string) (*models.Task, error)
+    GetByIds(ctx context.Context, ids []uuid.UUID) ([]models.Task, error)
+    GetByAssignee(ctx context.Context, userID uuid.UUID) ([]models.Task, error)
+    Find(ctx context.Context, f TaskFilter) ([]models.Task, int, error)
+    Stats(ctx context.Context, w StatsWindow) (*models.TaskStats, error)
+    AddTracked(ctx context.Context, id uuid.UUID, seconds int64, at time.Time) error
+    SetPriority(ctx context.Context, id uuid.UUID, from, to models.Importance, at time.Time) error
+    AddAssignees(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error
+    RemoveAssignee(ctx context.Context, id string, userID uuid.UUID, at time.Time) error
+    AddWatchers(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error
+    RemoveWatcher(ctx context.Context, id string, userID uuid.UUID, at time.Time) error
+    LastRank(ctx context.Context, status models.State) (string, error)
+    Column(ctx context.Context, status models.State) ([]models.Task, error)
+    SetRanks(ctx context.Context, changes []RankChange, at time.Time) (int, error)
+}
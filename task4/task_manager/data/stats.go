@@ -0,0 +1,238 @@
+package data
+
+import (
+    "context"
+    "math"
+    "sort"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+)
+
+// StatsWindow bounds the time-based statistics to the UTC days From through
+// To. Now decides which tasks are overdue.
+type StatsWindow struct {
+    From time.Time
+    To   time.Time
+    Now  time.Time
+}
+
+func (w StatsWindow) start() time.Time {
+    return w.From.UTC().Truncate(24 * time.Hour)
+}
+
+func (w StatsWindow) end() time.Time {
+    return w.To.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+}
+
+// tally is what both backends count; stats turns it into models.TaskStats
+// the same way for each.
+type tally struct {
+    total      int
+    byStatus   map[models.State]int
+    byPriority map[models.Importance]int
+    overdue    int
+    created    map[string]int
+    completed  map[string]int
+    leadTimes  []time.Duration
+}
+
+func newTally() *tally {
+    return &tally{
+        byStatus:   map[models.State]int{},
+        byPriority: map[models.Importance]int{},
+        created:    map[string]int{},
+        completed:  map[string]int{},
+    }
+}
+
+// Stats counts tasks by status and priority, the overdue ones, and, within
+// w, lead times and the tasks created and completed per day and week.
+func (r *Repo) Stats(ctx context.Context, w StatsWindow) (_ *models.TaskStats, err error) {
+    ctx, done := r.instrument(ctx, "stats")
+    defer done(&err)
+    var t *tally
+    if r.isMemory {
+        t = r.memoryTally(workspaceID(ctx), w)
+    } else if t, err = r.mongoTally(ctx, w); err != nil {
+        return nil, err
+    }
+    return t.stats(w), nil
+}
+
+func (r *Repo) memoryTally(ws uuid.UUID, w StatsWindow) *tally {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    t := newTally()
+    start, end := w.start(), w.end()
+    in := func(at time.Time) bool {
+        return !at.Before(start) && at.Before(end)
+    }
+    for _, task := range r.tasks {
+        if task.WorkspaceID != ws {
+            continue
+        }
+        t.total++
+        t.byStatus[task.Status]++
+        t.byPriority[task.Priority]++
+        if task.Status != models.Completed && task.DueDate != nil && task.DueDate.Before(w.Now) {
+            t.overdue++
+        }
+        if in(task.CreatedAt) {
+            t.created[task.CreatedAt.UTC().Format(time.DateOnly)]++
+        }
+        if task.CompletedAt != nil && in(*task.CompletedAt) {
+            t.completed[task.CompletedAt.UTC().Format(time.DateOnly)]++
+            t.leadTimes = append(t.leadTimes, task.CompletedAt.Sub(task.CreatedAt))
+        }
+    }
+    return t
+}
+
+// mongoTally runs one aggregation whose facets mirror memoryTally.
+func (r *Repo) mongoTally(ctx context.Context, w StatsWindow) (*tally, error) {
+    start, end := w.start(), w.end()
+    count := func(field string) bson.A {
+        return bson.A{bson.M{"$group": bson.M{"_id": "$" + field, "n": bson.M{"$sum": 1}}}}
+    }
+    perDay := func(field string) bson.A {
+        return bson.A{
+            bson.M{"$match": bson.M{field: bson.M{"$gte": start, "$lt": end}}},
+            bson.M{"$group": bson.M{
+                "_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + field, "timezone": "UTC"}},
+                "n":   bson.M{"$sum": 1},
+            }},
+        }
+    }
+    pipeline := bson.A{bson.M{"$match": scoped(ctx, bson.M{})}, bson.M{"$facet": bson.M{
+        "status":   count("status"),
+        "priority": count("priority"),
+        "overdue": bson.A{
+            bson.M{"$match": bson.M{"status": bson.M{"$ne": models.Completed}, "due_date": bson.M{"$lt": w.Now}}},
+            bson.M{"$count": "n"},
+        },
+        "created":   perDay("created_at"),
+        "completed": perDay("completed_at"),
+        "lead": bson.A{
+            bson.M{"$match": bson.M{"completed_at": bson.M{"$gte": start, "$lt": end}}},
+            bson.M{"$project": bson.M{"_id": 0, "ms": bson.M{"$subtract": bson.A{"$completed_at", "$created_at"}}}},
+        },
+    }}}
+    cursor, err := r.collection("tasks").Aggregate(ctx, pipeline)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    type group struct {
+        ID string `bson:"_id"`
+        N  int    `bson:"n"`
+    }
+    var res []struct {
+        Status    []group `bson:"status"`
+        Priority  []group `bson:"priority"`
+        Overdue   []group `bson:"overdue"`
+        Created   []group `bson:"created"`
+        Completed []group `bson:"completed"`
+        Lead      []struct {
+            MS int64 `bson:"ms"`
+        } `bson:"lead"`
+    }
+    if err := cursor.All(ctx, &res); err != nil {
+        return nil, storeError(err)
+    }
+    t := newTally()
+    if len(res) == 0 {
+        return t, nil
+    }
+    f := res[0]
+    for _, g := range f.Status {
+        t.total += g.N
+        t.byStatus[models.State(g.ID)] += g.N
+    }
+    for _, g := range f.Priority {
+        t.byPriority[models.Importance(g.ID)] += g.N
+    }
+    for _, g := range f.Overdue {
+        t.overdue += g.N
+    }
+    for _, g := range f.Created {
+        t.created[g.ID] += g.N
+    }
+    for _, g := range f.Completed {
+        t.completed[g.ID] += g.N
+    }
+    for _, l := range f.Lead {
+        t.leadTimes = append(t.leadTimes, time.Duration(l.MS)*time.Millisecond)
+    }
+    return t, nil
+}
+
+func (t *tally) stats(w StatsWindow) *models.TaskStats {
+    s := &models.TaskStats{
+        From:       w.start().Format(time.DateOnly),
+        To:         w.end().AddDate(0, 0, -1).Format(time.DateOnly),
+        Total:      t.total,
+        ByStatus:   map[models.State]int{},
+        ByPriority: map[models.Importance]int{},
+        Overdue:    t.overdue,
+        LeadTime:   leadTime(t.leadTimes),
+        Throughput: []models.WeekCount{},
+        Series:     []models.DayCount{},
+    }
+    // Every known value is present, even at zero.
+    for state := range models.ValidStates {
+        s.ByStatus[state] = 0
+    }
+    for p := range models.ValidPriorities {
+        s.ByPriority[p] = 0
+    }
+    for k, n := range t.byStatus {
+        s.ByStatus[k] += n
+    }
+    for k, n := range t.byPriority {
+        s.ByPriority[k] += n
+    }
+
+    for d := w.start(); d.Before(w.end()); d = d.AddDate(0, 0, 1) {
+        key := d.Format(time.DateOnly)
+        completed := t.completed[key]
+        s.Series = append(s.Series, models.DayCount{Date: key, Created: t.created[key], Completed: completed})
+        week := d.AddDate(0, 0, -(int(d.Weekday())+6)%7).Format(time.DateOnly)
+        if n := len(s.Throughput); n == 0 || s.Throughput[n-1].WeekStart != week {
+            s.Throughput = append(s.Throughput, models.WeekCount{WeekStart: week})
+        }
+        s.Throughput[len(s.Throughput)-1].Completed += completed
+    }
+    return s
+}
+
+// leadTime uses the nearest-rank method for percentiles.
+func leadTime(ds []time.Duration) models.LeadTime {
+    lt := models.LeadTime{Completed: len(ds)}
+    if len(ds) == 0 {
+        return lt
+    }
+    sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+    var sum time.Duration
+    for _, d := range ds {
+        sum += d
+    }
+    rank := func(p float64) float64 {
+        i := int(math.Ceil(p/100*float64(len(ds)))) - 1
+        return hours(ds[max(i, 0)])
+    }
+    lt.AverageHours = hours(sum / time.Duration(len(ds)))
+    lt.P50Hours = rank(50)
+    lt.P90Hours = rank(90)
+    lt.P95Hours = rank(95)
+    return lt
+}
+
+// hours rounds to hundredths, which also hides the millisecond precision
+// MongoDB stores times with.
+func hours(d time.Duration) float64 {
+    return math.Round(d.Hours()*100) / 100
+}
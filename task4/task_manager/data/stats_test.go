@@ -0,0 +1,59 @@
+package data
+
+import (
+    "context"
+    "reflect"
+    "task_manager/models"
+    "testing"
+    "time"
+)
+
+func TestStats(t *testing.T) {
+    ctx := context.Background()
+    repo := NewRepo(nil, "", true)
+    at := func(s string) *time.Time {
+        v, _ := time.Parse(time.RFC3339, s)
+        return &v
+    }
+    add := func(created string, status models.State, priority models.Importance, due *time.Time) {
+        task := models.NewTask("t", "", models.Pending, priority, due, *at(created))
+        if err := repo.Create(ctx, task); err != nil {
+            t.Fatal(err)
+        }
+        if status != models.Pending {
+            // The nth task is updated n days after it was created.
+            changes := models.Task{Status: status}
+            changes.UpdatedAt = task.CreatedAt.Add(time.Duration(len(repo.tasks)) * 24 * time.Hour)
+            if err := repo.Update(ctx, task.ID.String(), changes); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    add("2030-01-06T10:00:00Z", models.Completed, models.High, nil) // done 01-07 after 24h
+    add("2030-01-07T10:00:00Z", models.Completed, models.Low, nil)  // done 01-09 after 48h
+    add("2030-01-08T10:00:00Z", models.Completed, models.Low, nil)  // done 01-11 after 72h
+    add("2030-01-08T12:00:00Z", models.InProgress, models.Medium, at("2030-01-09T00:00:00Z"))
+    add("2029-12-01T00:00:00Z", models.Pending, models.High, at("2030-02-01T00:00:00Z"))
+
+    stats, err := repo.Stats(ctx, StatsWindow{From: *at("2030-01-05T00:00:00Z"), To: *at("2030-01-12T00:00:00Z"), Now: *at("2030-01-10T00:00:00Z")})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if stats.Total != 5 || stats.Overdue != 1 || stats.From != "2030-01-05" || stats.To != "2030-01-12" {
+        t.Errorf("stats = %+v", stats)
+    }
+    if want := (map[models.State]int{models.Pending: 1, models.InProgress: 1, models.Completed: 3}); !reflect.DeepEqual(stats.ByStatus, want) {
+        t.Errorf("by status = %v; want %v", stats.ByStatus, want)
+    }
+    if want := (models.LeadTime{Completed: 3, AverageHours: 48, P50Hours: 48, P90Hours: 72, P95Hours: 72}); stats.LeadTime != want {
+        t.Errorf("lead time = %+v; want %+v", stats.LeadTime, want)
+    }
+    // 2030-01-05 is a Saturday, so the window starts in the week of 2029-12-31.
+    want := []models.WeekCount{{WeekStart: "2029-12-31", Completed: 0}, {WeekStart: "2030-01-07", Completed: 3}}
+    if len(stats.Series) != 8 || !reflect.DeepEqual(stats.Throughput, want) {
+        t.Errorf("throughput = %+v over %d days; want %+v over 8", stats.Throughput, len(stats.Series), want)
+    }
+    if d := stats.Series[3]; d.Date != "2030-01-08" || d.Created != 2 || d.Completed != 0 {
+        t.Errorf("series[3] = %+v; want two created on 2030-01-08", d)
+    }
+}
@@ -2,45 +2,82 @@ package data
 
 import (
     "context"
-    "errors"
+    "sync"
     "task_manager/models"
+    "time"
 
     "github.com/google/uuid"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+    "go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// Repo stores tasks. Every method works within the workspace of its context;
+// see WithWorkspace.
 type Repo struct {
     Client   *mongo.Client
+    dbName   string
     isMemory bool
+    timeouts Timeouts
+    mu       sync.RWMutex
     tasks    []models.Task
 }
 
-func NewRepo(client *mongo.Client, isMemory bool) *Repo {
-    return &Repo{Client: client, isMemory: isMemory, tasks: []models.Task{}}
+func NewRepo(client *mongo.Client, dbName string, isMemory bool) *Repo {
+    return &Repo{Client: client, dbName: dbName, isMemory: isMemory, tasks: []models.Task{}}
+}
+
+// SetTimeouts bounds each operation; see Timeouts.
+func (r *Repo) SetTimeouts(t Timeouts) {
+    r.timeouts = t
+}
+
+func (r *Repo) Ping(ctx context.Context) error {
+    if r.isMemory {
+        return nil
+    }
+    return storeError(r.Client.Ping(ctx, readpref.Primary()))
+}
+
+func (r *Repo) Backend() string {
+    if r.isMemory {
+        return "memory"
+    }
+    return "mongo"
 }
 
 func (r *Repo) collection(coll string) *mongo.Collection {
-    return r.Client.Database("task_manager_db").Collection(coll)
+    return r.Client.Database(r.dbName).Collection(coll)
 }
 
-func (r *Repo) Create(task *models.Task) error {
+func (r *Repo) Create(ctx context.Context, task *models.Task) (err error) {
+    ctx, done := r.instrument(ctx, "create")
+    defer done(&err)
+    task.WorkspaceID = workspaceID(ctx)
     if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
         r.tasks = append(r.tasks, *task)
         return nil
     }
-    _, err := r.collection("tasks").InsertOne(context.Background(), task)
-    return err
+    _, err = r.collection("tasks").InsertOne(ctx, task)
+    return storeError(err)
 }
 
-func (r *Repo) Update(id string, task models.Task) error {
+func (r *Repo) Update(ctx context.Context, id string, task models.Task) (err error) {
+    ctx, done := r.instrument(ctx, "update")
+    defer done(&err)
     u, err := uuid.Parse(id)
     if err != nil {
-        return errors.New("invalid UUID")
+        return models.NewValidationError("id", "must be a UUID")
     }
     if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
         for i, t := range r.tasks {
-            if t.ID == u {
+            if t.ID == u && t.WorkspaceID == ws {
                 if task.Name != "" {
                     r.tasks[i].Name = task.Name
                 }
@@ -48,6 +85,7 @@ func (r *Repo) Update(id string, task models.Task) error {
                     r.tasks[i].Description = task.Description
                 }
                 if task.Status != "" {
+                    r.tasks[i].CompletedAt = completedAt(t, task)
                     r.tasks[i].Status = task.Status
                 }
                 if task.Priority != "" {
@@ -56,14 +94,29 @@ func (r *Repo) Update(id string, task models.Task) error {
                 if task.DueDate != nil {
                     r.tasks[i].DueDate = task.DueDate
                 }
+                if task.Assignees != nil {
+                    r.tasks[i].Assignees = task.Assignees
+                }
+                if task.Watchers != nil {
+                    r.tasks[i].Watchers = task.Watchers
+                }
+                if task.Project != nil {
+                    r.tasks[i].Project = task.Project
+                }
+                if task.EstimateSeconds != nil {
+                    r.tasks[i].EstimateSeconds = task.EstimateSeconds
+                }
+                if task.Rank != "" {
+                    r.tasks[i].Rank = task.Rank
+                }
                 r.tasks[i].UpdatedAt = task.UpdatedAt
                 return nil
             }
         }
-        return errors.New("task not found")
+        return models.ErrTaskNotFound
     }
 
-    filter := bson.M{"id": u}
+    filter := scoped(ctx, bson.M{"id": u})
     updateData := bson.M{"updated_at": task.UpdatedAt}
     if task.Name != "" {
         updateData["name"] = task.Name
@@ -80,85 +133,495 @@ func (r *Repo) Update(id string, task models.Task) error {
     if task.DueDate != nil {
         updateData["due_date"] = task.DueDate
     }
+    if task.Assignees != nil {
+        updateData["assignees"] = task.Assignees
+    }
+    if task.Watchers != nil {
+        updateData["watchers"] = task.Watchers
+    }
+    if task.Project != nil {
+        updateData["project"] = task.Project
+    }
+    if task.EstimateSeconds != nil {
+        updateData["estimate_seconds"] = task.EstimateSeconds
+    }
+    if task.Rank != "" {
+        updateData["rank"] = task.Rank
+    }
 
-    res, err := r.collection("tasks").UpdateOne(context.Background(), filter, bson.M{"$set": updateData})
+    // A pipeline update can compare against the stored status; user values
+    // are wrapped in $literal so none is read as an expression.
+    set := bson.M{}
+    for k, v := range updateData {
+        set[k] = bson.M{"$literal": v}
+    }
+    update := bson.A{bson.M{"$set": set}}
+    switch {
+    case task.Status == models.Completed:
+        set["completed_at"] = bson.M{"$cond": bson.A{
+            bson.M{"$eq": bson.A{"$status", models.Completed}}, "$completed_at", task.UpdatedAt,
+        }}
+    case task.Status != "":
+        update = append(update, bson.M{"$unset": "completed_at"})
+    }
+    res, err := r.collection("tasks").UpdateOne(ctx, filter, update)
     if err != nil {
-        return err
+        return storeError(err)
     }
     if res.MatchedCount == 0 {
-        return errors.New("task not found")
+        return models.ErrTaskNotFound
     }
     return nil
 }
 
-func (r *Repo) Delete(id string) error {
+func (r *Repo) Delete(ctx context.Context, id string) (err error) {
+    ctx, done := r.instrument(ctx, "delete")
+    defer done(&err)
     u, err := uuid.Parse(id)
     if err != nil {
-        return errors.New("invalid UUID")
+        return models.NewValidationError("id", "must be a UUID")
     }
     if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
         for i, t := range r.tasks {
-            if t.ID == u {
+            if t.ID == u && t.WorkspaceID == ws {
                 r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
                 return nil
             }
         }
-        return errors.New("task not found")
+        return models.ErrTaskNotFound
     }
-    res, err := r.collection("tasks").DeleteOne(context.Background(), bson.M{"id": u})
+    res, err := r.collection("tasks").DeleteOne(ctx, scoped(ctx, bson.M{"id": u}))
     if err != nil {
-        return err
+        return storeError(err)
     }
     if res.DeletedCount == 0 {
-        return errors.New("task not found")
+        return models.ErrTaskNotFound
     }
     return nil
 }
 
-func (r *Repo) GetAll() ([]models.Task, error) {
+func (r *Repo) GetAll(ctx context.Context) (_ []models.Task, err error) {
+    ctx, done := r.instrument(ctx, "get_all")
+    defer done(&err)
     if r.isMemory {
-        return r.tasks, nil
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        tasks := []models.Task{}
+        for _, t := range r.tasks {
+            if t.WorkspaceID == ws {
+                tasks = append(tasks, t)
+            }
+        }
+        return tasks, nil
     }
-    cursor, err := r.collection("tasks").Find(context.Background(), bson.M{})
+    cursor, err := r.collection("tasks").Find(ctx, scoped(ctx, bson.M{}))
     if err != nil {
-        return nil, err
+        return nil, storeError(err)
     }
-    defer cursor.Close(context.Background())
+    defer cursor.Close(ctx)
 
     var tasks []models.Task
-    for cursor.Next(context.Background()) {
+    for cursor.Next(ctx) {
         var t models.Task
         if err := cursor.Decode(&t); err != nil {
-            return nil, err
+            return nil, storeError(err)
         }
         tasks = append(tasks, t)
     }
     if err := cursor.Err(); err != nil {
-        return nil, err
+        return nil, storeError(err)
     }
     return tasks, nil
 }
 
-func (r *Repo) GetById(id string) (*models.Task, error) {
+func (r *Repo) GetById(ctx context.Context, id string) (_ *models.Task, err error) {
+    ctx, done := r.instrument(ctx, "get_by_id")
+    defer done(&err)
     u, err := uuid.Parse(id)
     if err != nil {
-        return nil, errors.New("invalid UUID")
+        return nil, models.NewValidationError("id", "must be a UUID")
     }
     if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
         for _, t := range r.tasks {
-            if t.ID == u {
+            if t.ID == u && t.WorkspaceID == ws {
                 return &t, nil
             }
         }
-        return nil, errors.New("task not found")
+        return nil, models.ErrTaskNotFound
     }
     var t models.Task
-    err = r.collection("tasks").FindOne(context.Background(), bson.M{"id": u}).Decode(&t)
+    err = r.collection("tasks").FindOne(ctx, scoped(ctx, bson.M{"id": u})).Decode(&t)
     if err == mongo.ErrNoDocuments {
-        return nil, errors.New("task not found")
+        return nil, models.ErrTaskNotFound
     }
     if err != nil {
-        return nil, err
+        return nil, storeError(err)
     }
     return &t, nil
-}
\ No newline at end of file
+}
+
+func (r *Repo) GetByAssignee(ctx context.Context, userID uuid.UUID) (_ []models.Task, err error) {
+    ctx, done := r.instrument(ctx, "get_by_assignee")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        tasks := []models.Task{}
+        for _, t := range r.tasks {
+            if t.WorkspaceID == ws && containsID(t.Assignees, userID) {
+                tasks = append(tasks, t)
+            }
+        }
+        return tasks, nil
+    }
+    cursor, err := r.collection("tasks").Find(ctx, scoped(ctx, bson.M{"assignees": userID}))
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    tasks := []models.Task{}
+    if err := cursor.All(ctx, &tasks); err != nil {
+        return nil, storeError(err)
+    }
+    return tasks, nil
+}
+
+// GetByIds returns the tasks among ids that exist, in no particular order.
+func (r *Repo) GetByIds(ctx context.Context, ids []uuid.UUID) (_ []models.Task, err error) {
+    ctx, done := r.instrument(ctx, "get_by_ids")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        tasks := []models.Task{}
+        for _, t := range r.tasks {
+            if t.WorkspaceID == ws && containsID(ids, t.ID) {
+                tasks = append(tasks, t)
+            }
+        }
+        return tasks, nil
+    }
+    cursor, err := r.collection("tasks").Find(ctx, scoped(ctx, bson.M{"id": bson.M{"$in": ids}}))
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    tasks := []models.Task{}
+    if err := cursor.All(ctx, &tasks); err != nil {
+        return nil, storeError(err)
+    }
+    return tasks, nil
+}
+
+// AddTracked adds seconds, which may be negative, to a task's tracked time.
+func (r *Repo) AddTracked(ctx context.Context, id uuid.UUID, seconds int64, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "add_tracked")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i := range r.tasks {
+            if r.tasks[i].ID == id && r.tasks[i].WorkspaceID == ws {
+                r.tasks[i].TrackedSeconds += seconds
+                r.tasks[i].UpdatedAt = at
+                return nil
+            }
+        }
+        return models.ErrTaskNotFound
+    }
+    res, err := r.collection("tasks").UpdateOne(ctx, scoped(ctx, bson.M{"id": id}), bson.M{
+        "$inc": bson.M{"tracked_seconds": seconds},
+        "$set": bson.M{"updated_at": at},
+    })
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        return models.ErrTaskNotFound
+    }
+    return nil
+}
+
+// SetPriority changes a task's priority from from to to, and fails with
+// ErrPriorityChanged if it is no longer from.
+func (r *Repo) SetPriority(ctx context.Context, id uuid.UUID, from, to models.Importance, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "set_priority")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i := range r.tasks {
+            if r.tasks[i].ID == id && r.tasks[i].WorkspaceID == ws {
+                if r.tasks[i].Priority != from {
+                    return models.ErrPriorityChanged
+                }
+                r.tasks[i].Priority = to
+                r.tasks[i].UpdatedAt = at
+                return nil
+            }
+        }
+        return models.ErrTaskNotFound
+    }
+    res, err := r.collection("tasks").UpdateOne(ctx, scoped(ctx, bson.M{"id": id, "priority": from}), bson.M{
+        "$set": bson.M{"priority": to, "updated_at": at},
+    })
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount > 0 {
+        return nil
+    }
+    n, err := r.collection("tasks").CountDocuments(ctx, scoped(ctx, bson.M{"id": id}))
+    if err != nil {
+        return storeError(err)
+    }
+    if n == 0 {
+        return models.ErrTaskNotFound
+    }
+    return models.ErrPriorityChanged
+}
+
+// TaskFilter selects and pages tasks for Find. Zero fields match every task;
+// a zero Limit returns every match after Offset. Open leaves out completed
+// tasks unless Status is set.
+type TaskFilter struct {
+    Status    models.State
+    Open      bool
+    Priority  models.Importance
+    Assignee  *uuid.UUID
+    DueBefore *time.Time
+    DueAfter  *time.Time
+    Offset    int
+    Limit     int
+}
+
+func (f TaskFilter) match(t models.Task) bool {
+    switch {
+    case f.Status != "" && t.Status != f.Status,
+        f.Status == "" && f.Open && t.Status == models.Completed,
+        f.Priority != "" && t.Priority != f.Priority,
+        f.Assignee != nil && !containsID(t.Assignees, *f.Assignee):
+        return false
+    case f.DueBefore == nil && f.DueAfter == nil:
+        return true
+    case t.DueDate == nil:
+        return false
+    }
+    return (f.DueBefore == nil || t.DueDate.Before(*f.DueBefore)) &&
+        (f.DueAfter == nil || !t.DueDate.Before(*f.DueAfter))
+}
+
+func (f TaskFilter) query() bson.M {
+    q := bson.M{}
+    if f.Status != "" {
+        q["status"] = f.Status
+    } else if f.Open {
+        q["status"] = bson.M{"$ne": models.Completed}
+    }
+    if f.Priority != "" {
+        q["priority"] = f.Priority
+    }
+    if f.Assignee != nil {
+        q["assignees"] = *f.Assignee
+    }
+    due := bson.M{}
+    if f.DueBefore != nil {
+        due["$lt"] = *f.DueBefore
+    }
+    if f.DueAfter != nil {
+        due["$gte"] = *f.DueAfter
+    }
+    if len(due) > 0 {
+        q["due_date"] = due
+    }
+    return q
+}
+
+// Find returns one page of the tasks matching f, oldest first, and how many
+// match in total.
+func (r *Repo) Find(ctx context.Context, f TaskFilter) (_ []models.Task, total int, err error) {
+    ctx, done := r.instrument(ctx, "find")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        tasks := []models.Task{}
+        for _, t := range r.tasks {
+            if t.WorkspaceID != ws || !f.match(t) {
+                continue
+            }
+            if total >= f.Offset && (f.Limit == 0 || len(tasks) < f.Limit) {
+                tasks = append(tasks, t)
+            }
+            total++
+        }
+        return tasks, total, nil
+    }
+    q := scoped(ctx, f.query())
+    n, err := r.collection("tasks").CountDocuments(ctx, q)
+    if err != nil {
+        return nil, 0, storeError(err)
+    }
+    opts := options.Find().
+        SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}).
+        SetSkip(int64(f.Offset))
+    if f.Limit > 0 {
+        opts.SetLimit(int64(f.Limit))
+    }
+    cursor, err := r.collection("tasks").Find(ctx, q, opts)
+    if err != nil {
+        return nil, 0, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    tasks := []models.Task{}
+    if err := cursor.All(ctx, &tasks); err != nil {
+        return nil, 0, storeError(err)
+    }
+    return tasks, int(n), nil
+}
+
+func (r *Repo) AddAssignees(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error {
+    return r.addToSet(ctx, id, "assignees", userIDs, at)
+}
+
+func (r *Repo) RemoveAssignee(ctx context.Context, id string, userID uuid.UUID, at time.Time) error {
+    return r.pull(ctx, id, "assignees", userID, at)
+}
+
+func (r *Repo) AddWatchers(ctx context.Context, id string, userIDs []uuid.UUID, at time.Time) error {
+    return r.addToSet(ctx, id, "watchers", userIDs, at)
+}
+
+func (r *Repo) RemoveWatcher(ctx context.Context, id string, userID uuid.UUID, at time.Time) error {
+    return r.pull(ctx, id, "watchers", userID, at)
+}
+
+func (r *Repo) addToSet(ctx context.Context, id, field string, userIDs []uuid.UUID, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "add_"+field)
+    defer done(&err)
+    u, err := uuid.Parse(id)
+    if err != nil {
+        return models.NewValidationError("id", "must be a UUID")
+    }
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i, t := range r.tasks {
+            if t.ID == u && t.WorkspaceID == ws {
+                list := participants(&r.tasks[i], field)
+                merged := append([]uuid.UUID{}, *list...)
+                for _, uid := range userIDs {
+                    if !containsID(merged, uid) {
+                        merged = append(merged, uid)
+                    }
+                }
+                *list = merged
+                r.tasks[i].UpdatedAt = at
+                return nil
+            }
+        }
+        return models.ErrTaskNotFound
+    }
+    update := bson.M{
+        "$addToSet": bson.M{field: bson.M{"$each": userIDs}},
+        "$set":      bson.M{"updated_at": at},
+    }
+    res, err := r.collection("tasks").UpdateOne(ctx, scoped(ctx, bson.M{"id": u}), update)
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        return models.ErrTaskNotFound
+    }
+    return nil
+}
+
+func (r *Repo) pull(ctx context.Context, id, field string, userID uuid.UUID, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "remove_"+field)
+    defer done(&err)
+    u, err := uuid.Parse(id)
+    if err != nil {
+        return models.NewValidationError("id", "must be a UUID")
+    }
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i, t := range r.tasks {
+            if t.ID == u && t.WorkspaceID == ws {
+                list := participants(&r.tasks[i], field)
+                kept := []uuid.UUID{}
+                for _, uid := range *list {
+                    if uid != userID {
+                        kept = append(kept, uid)
+                    }
+                }
+                *list = kept
+                r.tasks[i].UpdatedAt = at
+                return nil
+            }
+        }
+        return models.ErrTaskNotFound
+    }
+    update := bson.M{
+        "$pull": bson.M{field: userID},
+        "$set":  bson.M{"updated_at": at},
+    }
+    res, err := r.collection("tasks").UpdateOne(ctx, scoped(ctx, bson.M{"id": u}), update)
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        return models.ErrTaskNotFound
+    }
+    return nil
+}
+
+func (r *Repo) instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+    return instrument(ctx, r.Backend(), operation, r.timeouts)
+}
+
+// completedAt is when stored was completed after applying changes: kept if
+// it already was, changes.UpdatedAt if it is now, else nil.
+func completedAt(stored, changes models.Task) *time.Time {
+    switch {
+    case changes.Status != models.Completed:
+        return nil
+    case stored.Status == models.Completed:
+        return stored.CompletedAt
+    }
+    at := changes.UpdatedAt
+    return &at
+}
+
+func participants(t *models.Task, field string) *[]uuid.UUID {
+    if field == "watchers" {
+        return &t.Watchers
+    }
+    return &t.Assignees
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+    for _, v := range ids {
+        if v == id {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,290 @@
+package data
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TimeRepo stores the time entries of every task.
+type TimeRepo struct {
+    Client   *mongo.Client
+    dbName   string
+    isMemory bool
+    timeouts Timeouts
+    mu       sync.RWMutex
+    entries  []models.TimeEntry
+}
+
+func NewTimeRepo(client *mongo.Client, dbName string, isMemory bool) *TimeRepo {
+    return &TimeRepo{Client: client, dbName: dbName, isMemory: isMemory}
+}
+
+// SetTimeouts bounds each operation; see Timeouts.
+func (r *TimeRepo) SetTimeouts(t Timeouts) {
+    r.timeouts = t
+}
+
+func (r *TimeRepo) collection() *mongo.Collection {
+    return r.Client.Database(r.dbName).Collection("time_entries")
+}
+
+// Start stores a running entry, unless its user already has one running.
+func (r *TimeRepo) Start(ctx context.Context, e *models.TimeEntry) (err error) {
+    ctx, done := r.instrument(ctx, "time_start")
+    defer done(&err)
+    e.WorkspaceID = workspaceID(ctx)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for _, stored := range r.entries {
+            if stored.UserID == e.UserID && stored.Running {
+                return models.ErrTimerRunning
+            }
+        }
+        r.entries = append(r.entries, *e)
+        return nil
+    }
+    n, err := r.collection().CountDocuments(ctx, bson.M{"user_id": e.UserID, "running": true})
+    if err != nil {
+        return storeError(err)
+    }
+    if n > 0 {
+        return models.ErrTimerRunning
+    }
+    _, err = r.collection().InsertOne(ctx, e)
+    if mongo.IsDuplicateKeyError(err) {
+        // Another request started a timer between the count and the insert.
+        return models.ErrTimerRunning
+    }
+    return storeError(err)
+}
+
+// Stop ends userID's timer on taskID at end and returns the finished entry.
+// Its task must be in the workspace of ctx.
+func (r *TimeRepo) Stop(ctx context.Context, userID, taskID uuid.UUID, end time.Time) (_ *models.TimeEntry, err error) {
+    ctx, done := r.instrument(ctx, "time_stop")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i := range r.entries {
+            e := &r.entries[i]
+            if e.UserID == userID && e.TaskID == taskID && e.WorkspaceID == ws && e.Running {
+                stop(e, end)
+                stopped := *e
+                return &stopped, nil
+            }
+        }
+        return nil, models.ErrNoTimer
+    }
+    var e models.TimeEntry
+    err = r.collection().FindOne(ctx, scoped(ctx, bson.M{"user_id": userID, "task_id": taskID, "running": true})).Decode(&e)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrNoTimer
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    stop(&e, end)
+    res, err := r.collection().UpdateOne(ctx,
+        bson.M{"id": e.ID, "running": true},
+        bson.M{"$set": bson.M{"end": e.End, "seconds": e.Seconds}, "$unset": bson.M{"running": ""}},
+    )
+    if err != nil {
+        return nil, storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        // A concurrent request stopped it first.
+        return nil, models.ErrNoTimer
+    }
+    return &e, nil
+}
+
+// Running returns userID's running entry, in whichever workspace.
+func (r *TimeRepo) Running(ctx context.Context, userID uuid.UUID) (_ *models.TimeEntry, err error) {
+    ctx, done := r.instrument(ctx, "time_running")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        for _, e := range r.entries {
+            if e.UserID == userID && e.Running {
+                return &e, nil
+            }
+        }
+        return nil, models.ErrNoTimer
+    }
+    var e models.TimeEntry
+    err = r.collection().FindOne(ctx, bson.M{"user_id": userID, "running": true}).Decode(&e)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrNoTimer
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    return &e, nil
+}
+
+// Add stores a finished entry.
+func (r *TimeRepo) Add(ctx context.Context, e *models.TimeEntry) (err error) {
+    ctx, done := r.instrument(ctx, "time_add")
+    defer done(&err)
+    e.WorkspaceID = workspaceID(ctx)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.entries = append(r.entries, *e)
+        return nil
+    }
+    _, err = r.collection().InsertOne(ctx, e)
+    return storeError(err)
+}
+
+// Delete removes an entry and returns it.
+func (r *TimeRepo) Delete(ctx context.Context, id uuid.UUID) (_ *models.TimeEntry, err error) {
+    ctx, done := r.instrument(ctx, "time_delete")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        ws := workspaceID(ctx)
+        for i, e := range r.entries {
+            if e.ID == id && e.WorkspaceID == ws {
+                r.entries = append(r.entries[:i], r.entries[i+1:]...)
+                return &e, nil
+            }
+        }
+        return nil, models.ErrEntryNotFound
+    }
+    var e models.TimeEntry
+    err = r.collection().FindOneAndDelete(ctx, scoped(ctx, bson.M{"id": id})).Decode(&e)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrEntryNotFound
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    return &e, nil
+}
+
+// ForTask lists the entries of a task, latest start first.
+func (r *TimeRepo) ForTask(ctx context.Context, taskID uuid.UUID) (_ []models.TimeEntry, err error) {
+    ctx, done := r.instrument(ctx, "time_get_all")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        ws := workspaceID(ctx)
+        list := []models.TimeEntry{}
+        for _, e := range r.entries {
+            if e.TaskID == taskID && e.WorkspaceID == ws {
+                list = append(list, e)
+            }
+        }
+        sort.SliceStable(list, func(i, j int) bool { return list[i].Start.After(list[j].Start) })
+        return list, nil
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "start", Value: -1}})
+    cursor, err := r.collection().Find(ctx, scoped(ctx, bson.M{"task_id": taskID}), opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    list := []models.TimeEntry{}
+    if err := cursor.All(ctx, &list); err != nil {
+        return nil, storeError(err)
+    }
+    return list, nil
+}
+
+// TimeTotal is the time tracked on one task on one UTC day.
+type TimeTotal struct {
+    TaskID  uuid.UUID
+    Date    string
+    Seconds int64
+}
+
+// Totals sums the finished entries that started within w per task and UTC
+// day of their start.
+func (r *TimeRepo) Totals(ctx context.Context, w StatsWindow) (_ []TimeTotal, err error) {
+    ctx, done := r.instrument(ctx, "time_report")
+    defer done(&err)
+    start, end := w.start(), w.end()
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        type key struct {
+            task uuid.UUID
+            date string
+        }
+        ws := workspaceID(ctx)
+        sums := map[key]int64{}
+        for _, e := range r.entries {
+            if e.WorkspaceID != ws || e.Running || e.Start.Before(start) || !e.Start.Before(end) {
+                continue
+            }
+            sums[key{e.TaskID, e.Start.UTC().Format(time.DateOnly)}] += e.Seconds
+        }
+        totals := []TimeTotal{}
+        for k, n := range sums {
+            totals = append(totals, TimeTotal{TaskID: k.task, Date: k.date, Seconds: n})
+        }
+        return totals, nil
+    }
+    pipeline := bson.A{
+        bson.M{"$match": scoped(ctx, bson.M{"start": bson.M{"$gte": start, "$lt": end}, "running": bson.M{"$exists": false}})},
+        bson.M{"$group": bson.M{
+            "_id": bson.M{
+                "task_id": "$task_id",
+                "date":    bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$start", "timezone": "UTC"}},
+            },
+            "seconds": bson.M{"$sum": "$seconds"},
+        }},
+    }
+    cursor, err := r.collection().Aggregate(ctx, pipeline)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    var groups []struct {
+        ID struct {
+            TaskID uuid.UUID `bson:"task_id"`
+            Date   string    `bson:"date"`
+        } `bson:"_id"`
+        Seconds int64 `bson:"seconds"`
+    }
+    if err := cursor.All(ctx, &groups); err != nil {
+        return nil, storeError(err)
+    }
+    totals := make([]TimeTotal, len(groups))
+    for i, g := range groups {
+        totals[i] = TimeTotal{TaskID: g.ID.TaskID, Date: g.ID.Date, Seconds: g.Seconds}
+    }
+    return totals, nil
+}
+
+// stop finishes a running entry at end, counting whole seconds.
+func stop(e *models.TimeEntry, end time.Time) {
+    e.End = &end
+    e.Seconds = int64(end.Sub(e.Start) / time.Second)
+    e.Running = false
+}
+
+func (r *TimeRepo) instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+    backend := "mongo"
+    if r.isMemory {
+        backend = "memory"
+    }
+    return instrument(ctx, backend, operation, r.timeouts)
+}
@@ -0,0 +1,207 @@
+package data
+
+import (
+    "context"
+    "sync"
+    "task_manager/models"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserRepo struct {
+    Client   *mongo.Client
+    dbName   string
+    isMemory bool
+    timeouts Timeouts
+    mu       sync.RWMutex
+    users    []models.User
+}
+
+func NewUserRepo(client *mongo.Client, dbName string, isMemory bool) *UserRepo {
+    return &UserRepo{Client: client, dbName: dbName, isMemory: isMemory, users: []models.User{}}
+}
+
+// SetTimeouts bounds each operation; see Timeouts.
+func (r *UserRepo) SetTimeouts(t Timeouts) {
+    r.timeouts = t
+}
+
+func (r *UserRepo) collection() *mongo.Collection {
+    return r.Client.Database(r.dbName).Collection("users")
+}
+
+func (r *UserRepo) Create(ctx context.Context, user *models.User) (err error) {
+    ctx, done := r.instrument(ctx, "user_create")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for _, u := range r.users {
+            if u.Username == user.Username {
+                return models.ErrUsernameTaken
+            }
+        }
+        r.users = append(r.users, *user)
+        return nil
+    }
+    n, err := r.collection().CountDocuments(ctx, bson.M{"username": user.Username})
+    if err != nil {
+        return storeError(err)
+    }
+    if n > 0 {
+        return models.ErrUsernameTaken
+    }
+    _, err = r.collection().InsertOne(ctx, user)
+    if mongo.IsDuplicateKeyError(err) {
+        // Another request took the name between the count and the insert.
+        return models.ErrUsernameTaken
+    }
+    return storeError(err)
+}
+
+func (r *UserRepo) GetAll(ctx context.Context) (_ []models.User, err error) {
+    ctx, done := r.instrument(ctx, "user_get_all")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        return append([]models.User{}, r.users...), nil
+    }
+    cursor, err := r.collection().Find(ctx, bson.M{})
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    users := []models.User{}
+    if err := cursor.All(ctx, &users); err != nil {
+        return nil, storeError(err)
+    }
+    return users, nil
+}
+
+func (r *UserRepo) GetById(ctx context.Context, id string) (_ *models.User, err error) {
+    ctx, done := r.instrument(ctx, "user_get_by_id")
+    defer done(&err)
+    u, err := uuid.Parse(id)
+    if err != nil {
+        return nil, models.NewValidationError("id", "must be a UUID")
+    }
+    return r.findOne(ctx, bson.M{"id": u}, func(user models.User) bool { return user.ID == u })
+}
+
+func (r *UserRepo) GetByToken(ctx context.Context, token string) (_ *models.User, err error) {
+    ctx, done := r.instrument(ctx, "user_get_by_token")
+    defer done(&err)
+    hash := models.HashToken(token)
+    return r.findOne(ctx, bson.M{"token_hash": hash}, func(user models.User) bool { return user.TokenHash == hash })
+}
+
+// Missing returns the subset of ids that do not belong to a known user.
+func (r *UserRepo) Missing(ctx context.Context, ids []uuid.UUID) (_ []uuid.UUID, err error) {
+    ctx, done := r.instrument(ctx, "user_missing")
+    defer done(&err)
+    missing := []uuid.UUID{}
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        for _, id := range ids {
+            found := false
+            for _, u := range r.users {
+                if u.ID == id {
+                    found = true
+                    break
+                }
+            }
+            if !found {
+                missing = append(missing, id)
+            }
+        }
+        return missing, nil
+    }
+    cursor, err := r.collection().Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    var found []models.User
+    if err := cursor.All(ctx, &found); err != nil {
+        return nil, storeError(err)
+    }
+    for _, id := range ids {
+        known := false
+        for _, u := range found {
+            if u.ID == id {
+                known = true
+                break
+            }
+        }
+        if !known {
+            missing = append(missing, id)
+        }
+    }
+    return missing, nil
+}
+
+// SetTimeZone changes the time zone preference of the user with id; an empty
+// zone means UTC.
+func (r *UserRepo) SetTimeZone(ctx context.Context, id uuid.UUID, zone string) (err error) {
+    ctx, done := r.instrument(ctx, "user_set_time_zone")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for i := range r.users {
+            if r.users[i].ID == id {
+                r.users[i].TimeZone = zone
+                return nil
+            }
+        }
+        return models.ErrUserNotFound
+    }
+    update := bson.M{"$set": bson.M{"time_zone": zone}}
+    if zone == "" {
+        update = bson.M{"$unset": bson.M{"time_zone": ""}}
+    }
+    res, err := r.collection().UpdateOne(ctx, bson.M{"id": id}, update)
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        return models.ErrUserNotFound
+    }
+    return nil
+}
+
+func (r *UserRepo) findOne(ctx context.Context, filter bson.M, match func(models.User) bool) (*models.User, error) {
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        for _, u := range r.users {
+            if match(u) {
+                return &u, nil
+            }
+        }
+        return nil, models.ErrUserNotFound
+    }
+    var u models.User
+    err := r.collection().FindOne(ctx, filter).Decode(&u)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrUserNotFound
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    return &u, nil
+}
+
+func (r *UserRepo) instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+    backend := "mongo"
+    if r.isMemory {
+        backend = "memory"
+    }
+    return instrument(ctx, backend, operation, r.timeouts)
+}
@@ -0,0 +1,40 @@
+package data
+
+import (
+    "context"
+    "task_manager/models"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+)
+
+type workspaceKey struct{}
+
+// WithWorkspace scopes every task, time entry and escalation read or written
+// with ctx to ws. Repositories add the scope to each query themselves, so
+// data of another workspace cannot be reached through them.
+func WithWorkspace(ctx context.Context, ws *models.Workspace) context.Context {
+    return context.WithValue(ctx, workspaceKey{}, ws)
+}
+
+// Workspace returns the workspace ctx is scoped to, by default the default
+// workspace.
+func Workspace(ctx context.Context) *models.Workspace {
+    if ws, ok := ctx.Value(workspaceKey{}).(*models.Workspace); ok && ws != nil {
+        return ws
+    }
+    return models.DefaultWorkspace()
+}
+
+func workspaceID(ctx context.Context) uuid.UUID {
+    if ws, ok := ctx.Value(workspaceKey{}).(*models.Workspace); ok && ws != nil {
+        return ws.ID
+    }
+    return uuid.Nil
+}
+
+// scoped adds the workspace of ctx to a query filter.
+func scoped(ctx context.Context, filter bson.M) bson.M {
+    filter["workspace_id"] = workspaceID(ctx)
+    return filter
+}
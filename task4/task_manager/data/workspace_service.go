@@ -0,0 +1,261 @@
+package data
+
+import (
+    "context"
+    "slices"
+    "sort"
+    "sync"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkspaceRepo stores the workspaces and their members. The default
+// workspace is not stored.
+type WorkspaceRepo struct {
+    Client     *mongo.Client
+    dbName     string
+    isMemory   bool
+    timeouts   Timeouts
+    mu         sync.RWMutex
+    workspaces []models.Workspace
+}
+
+func NewWorkspaceRepo(client *mongo.Client, dbName string, isMemory bool) *WorkspaceRepo {
+    return &WorkspaceRepo{Client: client, dbName: dbName, isMemory: isMemory}
+}
+
+// SetTimeouts bounds each operation; see Timeouts.
+func (r *WorkspaceRepo) SetTimeouts(t Timeouts) {
+    r.timeouts = t
+}
+
+func (r *WorkspaceRepo) collection() *mongo.Collection {
+    return r.Client.Database(r.dbName).Collection("workspaces")
+}
+
+func (r *WorkspaceRepo) Create(ctx context.Context, ws *models.Workspace) (err error) {
+    ctx, done := r.instrument(ctx, "workspace_create")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.workspaces = append(r.workspaces, *ws)
+        return nil
+    }
+    _, err = r.collection().InsertOne(ctx, ws)
+    return storeError(err)
+}
+
+func (r *WorkspaceRepo) GetById(ctx context.Context, id uuid.UUID) (_ *models.Workspace, err error) {
+    ctx, done := r.instrument(ctx, "workspace_get_by_id")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        for _, ws := range r.workspaces {
+            if ws.ID == id {
+                return &ws, nil
+            }
+        }
+        return nil, models.ErrWorkspaceNotFound
+    }
+    var ws models.Workspace
+    err = r.collection().FindOne(ctx, bson.M{"id": id}).Decode(&ws)
+    if err == mongo.ErrNoDocuments {
+        return nil, models.ErrWorkspaceNotFound
+    }
+    if err != nil {
+        return nil, storeError(err)
+    }
+    return &ws, nil
+}
+
+// All lists every stored workspace, oldest first, for work done on behalf
+// of all of them.
+func (r *WorkspaceRepo) All(ctx context.Context) (_ []models.Workspace, err error) {
+    ctx, done := r.instrument(ctx, "workspace_get_all")
+    defer done(&err)
+    return r.find(ctx, func(models.Workspace) bool { return true }, bson.M{})
+}
+
+// ForUser lists the workspaces userID is a member of, oldest first.
+func (r *WorkspaceRepo) ForUser(ctx context.Context, userID uuid.UUID) (_ []models.Workspace, err error) {
+    ctx, done := r.instrument(ctx, "workspace_for_user")
+    defer done(&err)
+    return r.find(ctx, func(ws models.Workspace) bool {
+        _, ok := ws.Role(userID)
+        return ok
+    }, bson.M{"members.user_id": userID})
+}
+
+func (r *WorkspaceRepo) find(ctx context.Context, match func(models.Workspace) bool, filter bson.M) ([]models.Workspace, error) {
+    if r.isMemory {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        list := []models.Workspace{}
+        for _, ws := range r.workspaces {
+            if match(ws) {
+                list = append(list, ws)
+            }
+        }
+        sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+        return list, nil
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+    cursor, err := r.collection().Find(ctx, filter, opts)
+    if err != nil {
+        return nil, storeError(err)
+    }
+    defer cursor.Close(ctx)
+
+    list := []models.Workspace{}
+    if err := cursor.All(ctx, &list); err != nil {
+        return nil, storeError(err)
+    }
+    return list, nil
+}
+
+// Update replaces the name and settings of the workspace ws.ID.
+func (r *WorkspaceRepo) Update(ctx context.Context, ws *models.Workspace) (err error) {
+    ctx, done := r.instrument(ctx, "workspace_update")
+    defer done(&err)
+    if r.isMemory {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for i := range r.workspaces {
+            if r.workspaces[i].ID == ws.ID {
+                r.workspaces[i].Name = ws.Name
+                r.workspaces[i].Settings = ws.Settings
+                r.workspaces[i].UpdatedAt = ws.UpdatedAt
+                return nil
+            }
+        }
+        return models.ErrWorkspaceNotFound
+    }
+    res, err := r.collection().UpdateOne(ctx, bson.M{"id": ws.ID}, bson.M{"$set": bson.M{
+        "name":       ws.Name,
+        "settings":   ws.Settings,
+        "updated_at": ws.UpdatedAt,
+    }})
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount == 0 {
+        return models.ErrWorkspaceNotFound
+    }
+    return nil
+}
+
+// SetMember adds m to the workspace id, or changes the role of an existing
+// member. Demoting the last owner is an ErrLastOwner.
+func (r *WorkspaceRepo) SetMember(ctx context.Context, id uuid.UUID, m models.Member, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "workspace_set_member")
+    defer done(&err)
+    if r.isMemory {
+        return r.changeMembers(id, m.UserID, at, func(members []models.Member) []models.Member {
+            i := slices.IndexFunc(members, func(x models.Member) bool { return x.UserID == m.UserID })
+            if i < 0 {
+                return append(members, m)
+            }
+            members[i] = m
+            return members
+        })
+    }
+    filter := bson.M{"id": id, "members.user_id": m.UserID}
+    if m.Role != models.RoleOwner {
+        filter["$and"] = bson.A{otherOwner(m.UserID)}
+    }
+    res, err := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"members.$.role": m.Role, "updated_at": at}})
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount > 0 {
+        return nil
+    }
+    res, err = r.collection().UpdateOne(ctx,
+        bson.M{"id": id, "members.user_id": bson.M{"$ne": m.UserID}},
+        bson.M{"$push": bson.M{"members": m}, "$set": bson.M{"updated_at": at}},
+    )
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount > 0 {
+        return nil
+    }
+    return r.whyUnchanged(ctx, id)
+}
+
+// RemoveMember removes userID from the workspace id. Removing the last
+// owner is an ErrLastOwner; removing a non-member does nothing.
+func (r *WorkspaceRepo) RemoveMember(ctx context.Context, id, userID uuid.UUID, at time.Time) (err error) {
+    ctx, done := r.instrument(ctx, "workspace_remove_member")
+    defer done(&err)
+    if r.isMemory {
+        return r.changeMembers(id, userID, at, func(members []models.Member) []models.Member {
+            return slices.DeleteFunc(members, func(x models.Member) bool { return x.UserID == userID })
+        })
+    }
+    res, err := r.collection().UpdateOne(ctx,
+        bson.M{"id": id, "$or": bson.A{bson.M{"members.user_id": bson.M{"$ne": userID}}, otherOwner(userID)}},
+        bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}, "$set": bson.M{"updated_at": at}},
+    )
+    if err != nil {
+        return storeError(err)
+    }
+    if res.MatchedCount > 0 {
+        return nil
+    }
+    return r.whyUnchanged(ctx, id)
+}
+
+// changeMembers applies change to a copy of the members of the workspace id,
+// unless that leaves it without an owner.
+func (r *WorkspaceRepo) changeMembers(id, userID uuid.UUID, at time.Time, change func([]models.Member) []models.Member) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i := range r.workspaces {
+        ws := &r.workspaces[i]
+        if ws.ID != id {
+            continue
+        }
+        members := change(slices.Clone(ws.Members))
+        if !slices.ContainsFunc(members, func(m models.Member) bool { return m.Role == models.RoleOwner }) {
+            return models.ErrLastOwner
+        }
+        ws.Members = members
+        ws.UpdatedAt = at
+        return nil
+    }
+    return models.ErrWorkspaceNotFound
+}
+
+// otherOwner matches workspaces with an owner besides userID.
+func otherOwner(userID uuid.UUID) bson.M {
+    return bson.M{"members": bson.M{"$elemMatch": bson.M{"role": models.RoleOwner, "user_id": bson.M{"$ne": userID}}}}
+}
+
+// whyUnchanged tells a missing workspace from one a member change would
+// have left without an owner.
+func (r *WorkspaceRepo) whyUnchanged(ctx context.Context, id uuid.UUID) error {
+    n, err := r.collection().CountDocuments(ctx, bson.M{"id": id})
+    if err != nil {
+        return storeError(err)
+    }
+    if n == 0 {
+        return models.ErrWorkspaceNotFound
+    }
+    return models.ErrLastOwner
+}
+
+func (r *WorkspaceRepo) instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+    backend := "mongo"
+    if r.isMemory {
+        backend = "memory"
+    }
+    return instrument(ctx, backend, operation, r.timeouts)
+}
@@ -0,0 +1,192 @@
+// Package duedate reads due dates written the way people say them, such as
+// "tomorrow 5pm", "next friday" or "in 3 days", as well as RFC 3339.
+//
+// An expression is a day, a time of day, or both:
+//
+//   - days: "today", "tomorrow", a weekday ("friday", the next one from
+//     today on), "next" and a weekday (that day of the following week,
+//     weeks starting on Monday), or a date as YYYY-MM-DD;
+//   - times: "5pm", "5:30pm", "17:00" or "noon", optionally after "at";
+//   - or, on their own, "in" a number from 1 or "a"/"an" and minutes, hours,
+//     days, weeks or months.
+//
+// Days and times are read in the given location. A day without a time means
+// the end of that day; a time without a day means its next occurrence.
+package duedate
+
+import (
+    "errors"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var ErrUnknown = errors.New("duedate: not a due date expression")
+
+// ErrAmount is returned for "in" expressions counting less than one unit, or
+// so many that the time would overflow, about 292 years.
+var ErrAmount = errors.New("duedate: amount out of range")
+
+var weekdays = map[string]time.Weekday{
+    "sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+    "thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+    "sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+    "thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse returns the instant s stands for, in UTC, with relative expressions
+// taken from now and read in loc.
+func Parse(s string, now time.Time, loc *time.Location) (time.Time, error) {
+    s = strings.TrimSpace(s)
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return t.UTC(), nil
+    }
+    words := strings.Fields(strings.ToLower(s))
+    if len(words) == 0 {
+        return time.Time{}, ErrUnknown
+    }
+    now = now.In(loc)
+    if words[0] == "in" {
+        return in(words[1:], now)
+    }
+    day, rest, haveDay := parseDay(words, now)
+    if len(rest) > 0 && rest[0] == "at" {
+        rest = rest[1:]
+    }
+    hour, minute, haveTime := 23, 59, false
+    if len(rest) > 0 {
+        var ok bool
+        if hour, minute, ok = parseClock(strings.Join(rest, "")); !ok {
+            return time.Time{}, ErrUnknown
+        }
+        haveTime = true
+    }
+    if !haveDay && !haveTime {
+        return time.Time{}, ErrUnknown
+    }
+    second := 0
+    if !haveTime {
+        second = 59
+    }
+    t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, loc)
+    if !haveDay && !t.After(now) {
+        t = time.Date(day.Year(), day.Month(), day.Day()+1, hour, minute, 0, 0, loc)
+    }
+    return t.UTC(), nil
+}
+
+// in reads "N unit" after "in".
+func in(words []string, now time.Time) (time.Time, error) {
+    if len(words) != 2 {
+        return time.Time{}, ErrUnknown
+    }
+    n := int64(1)
+    if words[0] != "a" && words[0] != "an" {
+        var err error
+        if n, err = strconv.ParseInt(words[0], 10, 64); err != nil && !errors.Is(err, strconv.ErrRange) {
+            return time.Time{}, ErrUnknown
+        }
+        if err != nil || n < 1 {
+            return time.Time{}, ErrAmount
+        }
+    }
+    // unit is the longest a unit can be, which bounds n; days are 25 hours
+    // long when clocks go back.
+    var unit time.Duration
+    var add func(n int) time.Time
+    switch strings.TrimSuffix(words[1], "s") {
+    case "minute", "min":
+        unit, add = time.Minute, func(n int) time.Time { return now.Add(time.Duration(n) * time.Minute) }
+    case "hour", "hr":
+        unit, add = time.Hour, func(n int) time.Time { return now.Add(time.Duration(n) * time.Hour) }
+    case "day":
+        unit, add = 25*time.Hour, func(n int) time.Time { return now.AddDate(0, 0, n) }
+    case "week":
+        unit, add = 7*25*time.Hour, func(n int) time.Time { return now.AddDate(0, 0, 7*n) }
+    case "month":
+        unit, add = 31*25*time.Hour, func(n int) time.Time { return now.AddDate(0, n, 0) }
+    default:
+        return time.Time{}, ErrUnknown
+    }
+    if n > math.MaxInt64/int64(unit) {
+        return time.Time{}, ErrAmount
+    }
+    return add(int(n)).UTC(), nil
+}
+
+// parseDay reads a day from the start of words and returns it with the
+// words left over. Without one it returns today.
+func parseDay(words []string, now time.Time) (time.Time, []string, bool) {
+    switch w := words[0]; {
+    case w == "today":
+        return now, words[1:], true
+    case w == "tomorrow":
+        return now.AddDate(0, 0, 1), words[1:], true
+    case w == "next" && len(words) > 1:
+        if d, ok := weekdays[words[1]]; ok {
+            monday := now.AddDate(0, 0, daysUntil(now.Weekday(), time.Monday, 1))
+            return monday.AddDate(0, 0, daysUntil(time.Monday, d, 0)), words[2:], true
+        }
+    default:
+        if d, ok := weekdays[w]; ok {
+            return now.AddDate(0, 0, daysUntil(now.Weekday(), d, 0)), words[1:], true
+        }
+        if t, err := time.Parse(time.DateOnly, w); err == nil {
+            return t, words[1:], true
+        }
+    }
+    return now, words, false
+}
+
+// daysUntil counts the days from one weekday to the next to, at least least.
+func daysUntil(from, to time.Weekday, least int) int {
+    n := (int(to) - int(from) + 7) % 7
+    if n < least {
+        n += 7
+    }
+    return n
+}
+
+// parseClock reads a time of day such as "5pm", "5:30pm", "17:00" or "noon",
+// with spaces removed.
+func parseClock(s string) (hour, minute int, ok bool) {
+    if s == "noon" {
+        return 12, 0, true
+    }
+    suffix := ""
+    if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+        s, suffix = s[:len(s)-2], s[len(s)-2:]
+    }
+    h, m, hasMinutes := strings.Cut(s, ":")
+    hour, err := strconv.Atoi(h)
+    if err != nil || hour < 0 {
+        return 0, 0, false
+    }
+    if hasMinutes {
+        if len(m) != 2 {
+            return 0, 0, false
+        }
+        if minute, err = strconv.Atoi(m); err != nil || minute < 0 || minute > 59 {
+            return 0, 0, false
+        }
+    } else if suffix == "" {
+        // A bare number is too ambiguous to be a time.
+        return 0, 0, false
+    }
+    switch suffix {
+    case "":
+        if hour > 23 {
+            return 0, 0, false
+        }
+    default:
+        if hour < 1 || hour > 12 {
+            return 0, 0, false
+        }
+        hour %= 12
+        if suffix == "pm" {
+            hour += 12
+        }
+    }
+    return hour, minute, true
+}
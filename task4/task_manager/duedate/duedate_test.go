@@ -0,0 +1,66 @@
+package duedate
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestParse(t *testing.T) {
+    berlin, err := time.LoadLocation("Europe/Berlin")
+    if err != nil {
+        t.Skip("no time zone database:", err)
+    }
+    // Wednesday, 10:00 in Berlin.
+    now := time.Date(2026, time.October, 21, 10, 0, 0, 0, berlin)
+    testcases := []struct {
+        in   string
+        want time.Time
+    }{
+        {"2026-11-01T12:00:00Z", time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC)},
+        {"tomorrow 5pm", time.Date(2026, time.October, 22, 17, 0, 0, 0, berlin)},
+        {"Tomorrow at 5:30 PM", time.Date(2026, time.October, 22, 17, 30, 0, 0, berlin)},
+        {"today", time.Date(2026, time.October, 21, 23, 59, 59, 0, berlin)},
+        {"friday", time.Date(2026, time.October, 23, 23, 59, 59, 0, berlin)},
+        {"wednesday noon", time.Date(2026, time.October, 21, 12, 0, 0, 0, berlin)},
+        {"next wednesday 09:15", time.Date(2026, time.October, 28, 9, 15, 0, 0, berlin)},
+        {"next fri", time.Date(2026, time.October, 30, 23, 59, 59, 0, berlin)},
+        {"next sunday", time.Date(2026, time.November, 1, 23, 59, 59, 0, berlin)},
+        {"9am", time.Date(2026, time.October, 22, 9, 0, 0, 0, berlin)},
+        {"at 12pm", time.Date(2026, time.October, 21, 12, 0, 0, 0, berlin)},
+        {"2026-12-24 18:00", time.Date(2026, time.December, 24, 18, 0, 0, 0, berlin)},
+        {"in 3 days", now.AddDate(0, 0, 3)},
+        {"in an hour", now.Add(time.Hour)},
+        {"in 2 weeks", now.AddDate(0, 0, 14)},
+        // Berlin leaves summer time on October 25th.
+        {"next monday 8am", time.Date(2026, time.October, 26, 8, 0, 0, 0, berlin)},
+    }
+    for _, tc := range testcases {
+        got, err := Parse(tc.in, now, berlin)
+        if err != nil {
+            t.Errorf("Parse(%q): %v", tc.in, err)
+            continue
+        }
+        if !got.Equal(tc.want) || got.Location() != time.UTC {
+            t.Errorf("Parse(%q) = %v; want %v in UTC", tc.in, got, tc.want.UTC())
+        }
+    }
+
+    // Days are counted in loc, whatever the location of now: five days on
+    // is 10:00 again after Berlin leaves summer time.
+    if got, err := Parse("in 5 days", now.UTC(), berlin); err != nil || !got.Equal(time.Date(2026, time.October, 26, 10, 0, 0, 0, berlin)) {
+        t.Errorf("Parse(%q) from UTC = %v, %v; want 10:00 in Berlin", "in 5 days", got, err)
+    }
+
+    for _, in := range []string{"in 0 days", "in -1 hours", "in 300000 days", "in 3000000 hours", "in 99999999999999999999 minutes"} {
+        if got, err := Parse(in, now, berlin); !errors.Is(err, ErrAmount) {
+            t.Errorf("Parse(%q) = %v, %v; want ErrAmount", in, got, err)
+        }
+    }
+
+    for _, in := range []string{"", "soon", "in 3", "in three days", "5", "13pm", "25:00", "10:7", "next", "tomorrow 5pm sharp"} {
+        if got, err := Parse(in, now, berlin); err == nil {
+            t.Errorf("Parse(%q) = %v; want an error", in, got)
+        }
+    }
+}
@@ -0,0 +1,112 @@
+// Package events fans task changes out to in-process subscribers such as
+// streaming API clients.
+package events
+
+import (
+    "errors"
+    "sync"
+    "task_manager/models"
+    "time"
+)
+
+type Type string
+
+const (
+    Created Type = "created"
+    Updated Type = "updated"
+    Deleted Type = "deleted"
+)
+
+// Event reports a change to Task. For Deleted it holds the task as it was
+// before deletion.
+type Event struct {
+    Type Type
+    Task models.Task
+    At   time.Time
+}
+
+var (
+    ErrClosed = errors.New("event broker closed")
+    ErrSlow   = errors.New("subscriber fell behind")
+)
+
+// Subscription receives events on C until it is cancelled, the broker
+// closes, or it falls more than its buffer behind. Err reports why C closed.
+type Subscription struct {
+    C      <-chan Event
+    ch     chan Event
+    broker *Broker
+    err    error
+}
+
+func (s *Subscription) Err() error {
+    s.broker.mu.Lock()
+    defer s.broker.mu.Unlock()
+    return s.err
+}
+
+// Cancel stops delivery and closes C. It is safe to call more than once.
+func (s *Subscription) Cancel() {
+    s.broker.mu.Lock()
+    defer s.broker.mu.Unlock()
+    s.broker.drop(s, nil)
+}
+
+type Broker struct {
+    mu     sync.Mutex
+    subs   map[*Subscription]struct{}
+    closed bool
+}
+
+func NewBroker() *Broker {
+    return &Broker{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe registers a subscriber that may lag at most buffer events.
+func (b *Broker) Subscribe(buffer int) *Subscription {
+    ch := make(chan Event, buffer)
+    s := &Subscription{C: ch, ch: ch, broker: b}
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        s.err = ErrClosed
+        close(ch)
+        return s
+    }
+    b.subs[s] = struct{}{}
+    return s
+}
+
+// Publish delivers e to every subscriber without blocking. Subscribers with a
+// full buffer are dropped rather than silently missing the event.
+func (b *Broker) Publish(e Event) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for s := range b.subs {
+        select {
+        case s.ch <- e:
+        default:
+            b.drop(s, ErrSlow)
+        }
+    }
+}
+
+// Close ends every subscription, which lets streaming requests finish during
+// shutdown.
+func (b *Broker) Close() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.closed = true
+    for s := range b.subs {
+        b.drop(s, ErrClosed)
+    }
+}
+
+func (b *Broker) drop(s *Subscription, err error) {
+    if _, ok := b.subs[s]; !ok {
+        return
+    }
+    delete(b.subs, s)
+    s.err = err
+    close(s.ch)
+}
@@ -0,0 +1,28 @@
+package graphqlapi
+
+import "task_manager/middleware"
+
+// problemError reports a service error with the stable problem code of the
+// REST API in its extensions, so clients can branch on the same codes.
+type problemError struct {
+    p middleware.Problem
+}
+
+func resolverError(err error) error {
+    return &problemError{middleware.NewProblem(err)}
+}
+
+func (e *problemError) Error() string {
+    if e.p.Detail != "" {
+        return e.p.Detail
+    }
+    return e.p.Title
+}
+
+func (e *problemError) Extensions() map[string]any {
+    ext := map[string]any{"code": e.p.Code}
+    if len(e.p.Errors) > 0 {
+        ext["fields"] = e.p.Errors
+    }
+    return ext
+}
@@ -0,0 +1,122 @@
+// Package graphqlapi serves tasks over GraphQL at /graphql on top of the same
+// service layer as the REST handlers. Subscriptions are delivered as
+// server-sent events.
+package graphqlapi
+
+import (
+    "context"
+    _ "embed"
+    "encoding/json"
+    "mime"
+    "net/http"
+    "strings"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/service"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxDepth bounds query nesting so a single request cannot fan out without
+// limit through owner, assignees and watchers.
+const maxDepth = 8
+
+type request struct {
+    Query         string         `json:"query"`
+    OperationName string         `json:"operationName"`
+    Variables     map[string]any `json:"variables"`
+}
+
+// Handler executes GraphQL requests sent as JSON with POST, or as query
+// parameters with GET. Requests that accept text/event-stream are answered
+// with a stream of "next" events ending in "complete", which is how
+// subscriptions are delivered. Mutations must use POST; over GET they fail
+// with 405 so that links and prefetches cannot change data. createTask counts against the daily quota in
+// limits of the client named by key, as POST /tasks does. It panics if the
+// schema does not match the resolvers.
+func Handler(tasks *service.Tasks, limits *middleware.Limits, key func(*gin.Context) string) gin.HandlerFunc {
+    s := graphql.MustParseSchema(schema, &resolver{tasks: tasks, limits: limits},
+        graphql.UseStringDescriptions(),
+        graphql.MaxDepth(maxDepth),
+    )
+    return func(c *gin.Context) {
+        req, err := parse(c)
+        if err != nil {
+            c.Error(err)
+            return
+        }
+        if c.Request.Method == http.MethodGet && operationType(req.Query, req.OperationName) == "mutation" {
+            c.Header("Allow", http.MethodPost)
+            c.Error(models.NewError(models.ErrMethod, "mutations must be sent with POST"))
+            return
+        }
+        ctx := context.WithValue(c.Request.Context(), clientKey{}, key(c))
+        if !acceptsEvents(c.Request) {
+            c.IndentedJSON(http.StatusOK, s.Exec(ctx, req.Query, req.OperationName, req.Variables))
+            return
+        }
+
+        ctx, cancel := context.WithCancel(ctx)
+        defer cancel()
+        responses, err := s.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+        if err != nil {
+            c.Error(err)
+            return
+        }
+        // Streams outlive the server's write timeout.
+        http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+        c.Header("Cache-Control", "no-cache")
+        c.Header("X-Accel-Buffering", "no")
+        c.Status(http.StatusOK)
+        c.Writer.Flush()
+        for resp := range responses {
+            c.SSEvent("next", resp)
+            c.Writer.Flush()
+        }
+        c.SSEvent("complete", "")
+        c.Writer.Flush()
+    }
+}
+
+type clientKey struct{}
+
+func client(ctx context.Context) string {
+    key, _ := ctx.Value(clientKey{}).(string)
+    return key
+}
+
+func parse(c *gin.Context) (request, error) {
+    var req request
+    if c.Request.Method == http.MethodGet {
+        req.Query = c.Query("query")
+        req.OperationName = c.Query("operationName")
+        if v := c.Query("variables"); v != "" {
+            if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+                return req, models.NewValidationError("variables", "must be a JSON object")
+            }
+        }
+    } else if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+        if tooLarge := middleware.TooLarge(err); tooLarge != nil {
+            return req, tooLarge
+        }
+        return req, models.NewValidationError("body", "must be a JSON object with a query")
+    }
+    if strings.TrimSpace(req.Query) == "" {
+        return req, models.NewValidationError("query", "is required")
+    }
+    return req, nil
+}
+
+func acceptsEvents(r *http.Request) bool {
+    for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+        if t, _, err := mime.ParseMediaType(strings.TrimSpace(v)); err == nil && t == "text/event-stream" {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,199 @@
+package graphqlapi
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "task_manager/config"
+    "task_manager/data"
+    "task_manager/events"
+    "task_manager/middleware"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+type response struct {
+    Data   map[string]any
+    Errors []struct {
+        Message    string
+        Extensions map[string]any
+    }
+}
+
+func TestGraphQL(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    tasks := service.NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), events.NewBroker())
+    r := gin.New()
+    r.Use(middleware.Problems())
+    r.POST("/graphql", Handler(tasks, middleware.NewLimits(config.Limit{}, nil, 0), func(*gin.Context) string { return "test" }))
+    srv := httptest.NewServer(r)
+    defer srv.Close()
+
+    exec := func(query string, vars map[string]any) response {
+        t.Helper()
+        body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+        res, err := http.Post(srv.URL+"/graphql", "application/json", strings.NewReader(string(body)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer res.Body.Close()
+        var out response
+        if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+            t.Fatal(err)
+        }
+        return out
+    }
+
+    // The subscription is in place once the stream's headers arrive.
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/graphql",
+        strings.NewReader(`{"query":"subscription { taskChanged { type task { name status } } }"}`))
+    req.Header.Set("Accept", "text/event-stream")
+    stream, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer stream.Body.Close()
+
+    const create = `mutation($name: String!, $p: Priority!) { createTask(input: {name: $name, priority: $p}) { id status } }`
+    var ids []string
+    for _, c := range []struct{ name, priority string }{{"a", "HIGH"}, {"b", "LOW"}, {"c", "HIGH"}} {
+        out := exec(create, map[string]any{"name": c.name, "p": c.priority})
+        if len(out.Errors) > 0 {
+            t.Fatalf("createTask: %v", out.Errors)
+        }
+        ids = append(ids, out.Data["createTask"].(map[string]any)["id"].(string))
+    }
+    out := exec(`mutation($id: ID!) { updateTask(id: $id, input: {status: IN_PROGRESS}) { status } }`, map[string]any{"id": ids[2]})
+    if got := out.Data["updateTask"].(map[string]any)["status"]; got != "IN_PROGRESS" {
+        t.Errorf("updateTask status = %v; want IN_PROGRESS", got)
+    }
+
+    out = exec(`{ tasks(filter: {priority: HIGH}, limit: 1, offset: 1) { total items { name } } }`, nil)
+    page := out.Data["tasks"].(map[string]any)
+    items := page["items"].([]any)
+    if page["total"] != float64(2) || len(items) != 1 || items[0].(map[string]any)["name"] != "c" {
+        t.Errorf("tasks = %v; want the second of two high priority tasks", page)
+    }
+
+    testcases := []struct {
+        cases string
+        query string
+        code  string
+    }{
+        {"bad id", `{ task(id: "42") { id } }`, "validation_failed"},
+        {"limit too large", `{ tasks(limit: 1000) { total } }`, "validation_failed"},
+        {"missing task", `mutation { deleteTask(id: "0b7e7a4e-58c4-4e0e-9a37-3c44e6e1d2f1") }`, "not_found"},
+    }
+    for _, test := range testcases {
+        out := exec(test.query, nil)
+        if len(out.Errors) != 1 || out.Errors[0].Extensions["code"] != test.code {
+            t.Errorf("%s: errors = %+v; want code %q", test.cases, out.Errors, test.code)
+        }
+    }
+
+    var got []string
+    scanner := bufio.NewScanner(stream.Body)
+    for len(got) < 4 && scanner.Scan() {
+        line, ok := strings.CutPrefix(scanner.Text(), "data:")
+        if !ok {
+            continue
+        }
+        var e struct{ Data struct{ TaskChanged struct{ Type string; Task struct{ Name string } } } }
+        if err := json.Unmarshal([]byte(line), &e); err != nil {
+            t.Fatal(err)
+        }
+        got = append(got, e.Data.TaskChanged.Type+" "+e.Data.TaskChanged.Task.Name)
+    }
+    want := []string{"CREATED a", "CREATED b", "CREATED c", "UPDATED c"}
+    if strings.Join(got, ",") != strings.Join(want, ",") {
+        t.Errorf("events = %v; want %v", got, want)
+    }
+}
+
+// TestQuota checks that createTask counts against the same daily quota as
+// POST /tasks.
+func TestQuota(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    tasks := service.NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), nil)
+    limits := middleware.NewLimits(config.Limit{}, nil, 1)
+    r := gin.New()
+    r.Use(middleware.Problems())
+    r.POST("/graphql", Handler(tasks, limits, func(*gin.Context) string { return "ip:192.0.2.1" }))
+
+    create := func(name string) response {
+        t.Helper()
+        body, _ := json.Marshal(map[string]any{"query": `mutation($name: String!) { createTask(input: {name: $name, priority: LOW}) { id } }`, "variables": map[string]any{"name": name}})
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+        var out response
+        if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+            t.Fatal(err)
+        }
+        return out
+    }
+    // A failed creation does not count.
+    if out := create(""); len(out.Errors) == 0 {
+        t.Fatal("createTask without a name succeeded")
+    }
+    if out := create("a"); len(out.Errors) > 0 {
+        t.Fatalf("createTask: %v", out.Errors)
+    }
+    out := create("b")
+    if len(out.Errors) != 1 || out.Errors[0].Extensions["code"] != "rate_limited" {
+        t.Errorf("createTask over quota = %+v; want a rate_limited error", out.Errors)
+    }
+    if _, _, ok := limits.Reserve("ip:192.0.2.1", time.Now()); ok {
+        t.Error("REST creations were allowed after GraphQL used up the quota")
+    }
+}
+
+// TestGetMutation checks that GET runs queries but refuses mutations, even
+// when they hide behind comments, strings or a second operation.
+func TestGetMutation(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    tasks := service.NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), nil)
+    r := gin.New()
+    r.Use(middleware.Problems())
+    r.GET("/graphql", Handler(tasks, middleware.NewLimits(config.Limit{}, nil, 0), func(*gin.Context) string { return "" }))
+
+    create := `mutation Create { createTask(input: {name: "a", priority: LOW}) { id } }`
+    testcases := []struct {
+        query, operation string
+        want             int
+    }{
+        {`{ tasks { total } }`, "", http.StatusOK},
+        {`query List { tasks(filter: {status: PENDING}) { total } }`, "", http.StatusOK},
+        {"# mutation {\n" + `query { tasks { total } }`, "", http.StatusOK},
+        {create, "", http.StatusMethodNotAllowed},
+        {`mutation { deleteTask(id: "x") }`, "", http.StatusMethodNotAllowed},
+        {`query List { tasks { total } } ` + create, "Create", http.StatusMethodNotAllowed},
+        {`query List { tasks { total } } ` + create, "List", http.StatusOK},
+        {`"""description""" mutation @skip(if: false) { deleteTask(id: "x") }`, "", http.StatusMethodNotAllowed},
+    }
+    for _, tc := range testcases {
+        q := url.Values{"query": {tc.query}}
+        if tc.operation != "" {
+            q.Set("operationName", tc.operation)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil))
+        if w.Code != tc.want {
+            t.Errorf("GET %q as %q = %d; want %d: %s", tc.query, tc.operation, w.Code, tc.want, w.Body)
+        }
+        if tc.want == http.StatusMethodNotAllowed && w.Header().Get("Allow") != http.MethodPost {
+            t.Errorf("GET %q: Allow = %q; want POST", tc.query, w.Header().Get("Allow"))
+        }
+    }
+    if list, err := tasks.List(context.Background()); err != nil || len(list) > 0 {
+        t.Errorf("GET created %d tasks: %v", len(list), err)
+    }
+}
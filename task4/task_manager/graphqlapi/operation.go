@@ -0,0 +1,97 @@
+package graphqlapi
+
+import "strings"
+
+type operation struct {
+    typ, name string
+}
+
+// operationType returns the type of the operation named name in doc, or of
+// its only operation when name is empty: "query", "mutation" or
+// "subscription". It returns "" when there is no such operation, and leaves
+// reporting that to the executor.
+func operationType(doc, name string) string {
+    var found []operation
+    for _, op := range operations(doc) {
+        if op.typ != "fragment" && (name == "" || op.name == name) {
+            found = append(found, op)
+        }
+    }
+    if len(found) != 1 {
+        return ""
+    }
+    return found[0].typ
+}
+
+// operations scans the top level of doc for its definitions. It only reads
+// their headers, skipping strings, comments, arguments and selection sets,
+// and leaves checking the document to the executor.
+func operations(doc string) []operation {
+    var ops []operation
+    var cur *operation
+    depth, parens := 0, 0
+    for i := 0; i < len(doc); {
+        c := doc[i]
+        switch {
+        case c == '#':
+            for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+                i++
+            }
+            continue
+        case strings.HasPrefix(doc[i:], `"""`):
+            i += 3
+            for i < len(doc) && !strings.HasPrefix(doc[i:], `"""`) {
+                if strings.HasPrefix(doc[i:], `\"""`) {
+                    i += 3
+                }
+                i++
+            }
+            i += 3
+            continue
+        case c == '"':
+            for i++; i < len(doc) && doc[i] != '"' && doc[i] != '\n'; i++ {
+                if doc[i] == '\\' {
+                    i++
+                }
+            }
+        case c == '(':
+            parens++
+        case c == ')':
+            parens--
+        case parens > 0:
+        case c == '{':
+            if depth == 0 {
+                if cur == nil {
+                    // A bare selection set is a query.
+                    cur = &operation{typ: "query"}
+                }
+                ops = append(ops, *cur)
+                cur = nil
+            }
+            depth++
+        case c == '}':
+            depth--
+        case depth == 0 && (c == '@' || nameStart(c)):
+            j := i + 1
+            for j < len(doc) && (nameStart(doc[j]) || doc[j] >= '0' && doc[j] <= '9') {
+                j++
+            }
+            switch word := doc[i:j]; {
+            case c == '@':
+                // Directives are not names.
+            case cur == nil:
+                cur = &operation{typ: word}
+            case cur.name == "":
+                cur.name = word
+            }
+            i = j
+            continue
+        }
+        i++
+    }
+    return ops
+}
+
+func nameStart(c byte) bool {
+    return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,388 @@
+package graphqlapi
+
+import (
+    "context"
+    "errors"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/events"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/service"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/graph-gophers/graphql-go"
+)
+
+// subscriptionBuffer is how many events a taskChanged subscriber may lag
+// behind before its stream completes.
+const subscriptionBuffer = 64
+
+type resolver struct {
+    tasks  *service.Tasks
+    limits *middleware.Limits
+}
+
+func (r *resolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+    task, err := r.tasks.Get(ctx, string(args.ID))
+    if errors.Is(err, models.ErrNotFound) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, resolverError(err)
+    }
+    return r.task(*task), nil
+}
+
+type taskFilter struct {
+    Status    *string
+    Priority  *string
+    Assignee  *graphql.ID
+    DueBefore *graphql.Time
+    DueAfter  *graphql.Time
+}
+
+func (r *resolver) Tasks(ctx context.Context, args struct {
+    Filter *taskFilter
+    Limit  int32
+    Offset int32
+}) (*pageResolver, error) {
+    f := data.TaskFilter{Limit: int(args.Limit), Offset: int(args.Offset)}
+    if in := args.Filter; in != nil {
+        if in.Status != nil {
+            f.Status = states[*in.Status]
+        }
+        if in.Priority != nil {
+            f.Priority = priorities[*in.Priority]
+        }
+        if in.Assignee != nil {
+            id, err := uuid.Parse(string(*in.Assignee))
+            if err != nil {
+                return nil, resolverError(models.NewValidationError("assignee", "must be a UUID"))
+            }
+            f.Assignee = &id
+        }
+        f.DueBefore = fromTime(in.DueBefore)
+        f.DueAfter = fromTime(in.DueAfter)
+    }
+    tasks, total, err := r.tasks.Find(ctx, f)
+    if err != nil {
+        return nil, resolverError(err)
+    }
+    page := &pageResolver{total: int32(total)}
+    for _, t := range tasks {
+        page.items = append(page.items, r.task(t))
+    }
+    return page, nil
+}
+
+func (r *resolver) Me(ctx context.Context) *userResolver {
+    user, ok := auth.User(ctx)
+    if !ok {
+        return nil
+    }
+    return &userResolver{*user}
+}
+
+type newTask struct {
+    Name        string
+    Description *string
+    Priority    string
+    DueDate     *graphql.Time
+    Assignees   *[]graphql.ID
+    Watchers    *[]graphql.ID
+}
+
+func (r *resolver) CreateTask(ctx context.Context, args struct{ Input newTask }) (*taskResolver, error) {
+    assignees, err := parseIDs("assignees", args.Input.Assignees)
+    if err != nil {
+        return nil, resolverError(err)
+    }
+    watchers, err := parseIDs("watchers", args.Input.Watchers)
+    if err != nil {
+        return nil, resolverError(err)
+    }
+    release, _, ok := r.limits.Reserve(client(ctx), time.Now())
+    if !ok {
+        return nil, resolverError(models.NewError(models.ErrRateLimited, "daily quota exceeded"))
+    }
+    task, err := r.tasks.Create(ctx, models.Task{
+        Name:        args.Input.Name,
+        Description: args.Input.Description,
+        Priority:    priorities[args.Input.Priority],
+        DueDate:     fromTime(args.Input.DueDate),
+        Assignees:   assignees,
+        Watchers:    watchers,
+    })
+    if err != nil {
+        release()
+        return nil, resolverError(err)
+    }
+    return r.task(*task), nil
+}
+
+type taskChanges struct {
+    Name        *string
+    Description *string
+    Status      *string
+    Priority    *string
+    DueDate     *graphql.Time
+}
+
+func (r *resolver) UpdateTask(ctx context.Context, args struct {
+    ID    graphql.ID
+    Input taskChanges
+}) (*taskResolver, error) {
+    changes := models.Task{
+        Description: args.Input.Description,
+        DueDate:     fromTime(args.Input.DueDate),
+    }
+    if args.Input.Name != nil {
+        changes.Name = *args.Input.Name
+    }
+    if args.Input.Status != nil {
+        changes.Status = states[*args.Input.Status]
+    }
+    if args.Input.Priority != nil {
+        changes.Priority = priorities[*args.Input.Priority]
+    }
+    task, err := r.tasks.Update(ctx, string(args.ID), changes)
+    if err != nil {
+        return nil, resolverError(err)
+    }
+    return r.task(*task), nil
+}
+
+func (r *resolver) DeleteTask(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+    if err := r.tasks.Delete(ctx, string(args.ID)); err != nil {
+        return "", resolverError(err)
+    }
+    return args.ID, nil
+}
+
+// TaskChanged streams events until ctx ends or the subscriber falls behind,
+// which completes the stream.
+func (r *resolver) TaskChanged(ctx context.Context, args struct{ IDs *[]graphql.ID }) (<-chan *eventResolver, error) {
+    only := map[string]bool{}
+    if args.IDs != nil {
+        for _, id := range *args.IDs {
+            only[string(id)] = true
+        }
+    }
+    if r.tasks.Events == nil {
+        return nil, resolverError(models.NewError(models.ErrUnavailable, "task changes are not published"))
+    }
+    ws := data.Workspace(ctx).ID
+    sub := r.tasks.Events.Subscribe(subscriptionBuffer)
+    out := make(chan *eventResolver)
+    go func() {
+        defer close(out)
+        defer sub.Cancel()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case e, ok := <-sub.C:
+                if !ok {
+                    return
+                }
+                if e.Task.WorkspaceID != ws || (len(only) > 0 && !only[e.Task.ID.String()]) {
+                    continue
+                }
+                select {
+                case out <- &eventResolver{e: e, task: r.task(e.Task)}:
+                case <-ctx.Done():
+                    return
+                }
+            }
+        }
+    }()
+    return out, nil
+}
+
+func (r *resolver) task(t models.Task) *taskResolver {
+    return &taskResolver{t: t, users: r.tasks.Users}
+}
+
+type pageResolver struct {
+    items []*taskResolver
+    total int32
+}
+
+func (p *pageResolver) Items() []*taskResolver {
+    if p.items == nil {
+        return []*taskResolver{}
+    }
+    return p.items
+}
+
+func (p *pageResolver) Total() int32 {
+    return p.total
+}
+
+type taskResolver struct {
+    t     models.Task
+    users *data.UserRepo
+}
+
+func (r *taskResolver) ID() graphql.ID {
+    return graphql.ID(r.t.ID.String())
+}
+
+func (r *taskResolver) Name() string {
+    return r.t.Name
+}
+
+func (r *taskResolver) Description() *string {
+    return r.t.Description
+}
+
+func (r *taskResolver) Status() string {
+    return enumOf(states, r.t.Status)
+}
+
+func (r *taskResolver) Priority() string {
+    return enumOf(priorities, r.t.Priority)
+}
+
+func (r *taskResolver) DueDate() *graphql.Time {
+    return toTime(r.t.DueDate)
+}
+
+// Owner is null for tasks created anonymously.
+func (r *taskResolver) Owner(ctx context.Context) (*userResolver, error) {
+    if r.t.OwnerID == nil {
+        return nil, nil
+    }
+    users, err := r.lookup(ctx, []uuid.UUID{*r.t.OwnerID})
+    if err != nil || len(users) == 0 {
+        return nil, err
+    }
+    return users[0], nil
+}
+
+func (r *taskResolver) Assignees(ctx context.Context) ([]*userResolver, error) {
+    return r.lookup(ctx, r.t.Assignees)
+}
+
+func (r *taskResolver) Watchers(ctx context.Context) ([]*userResolver, error) {
+    return r.lookup(ctx, r.t.Watchers)
+}
+
+func (r *taskResolver) CreatedAt() graphql.Time {
+    return graphql.Time{Time: r.t.CreatedAt}
+}
+
+func (r *taskResolver) UpdatedAt() graphql.Time {
+    return graphql.Time{Time: r.t.UpdatedAt}
+}
+
+func (r *taskResolver) CompletedAt() *graphql.Time {
+    return toTime(r.t.CompletedAt)
+}
+
+// lookup resolves ids to users, skipping any that no longer exist.
+func (r *taskResolver) lookup(ctx context.Context, ids []uuid.UUID) ([]*userResolver, error) {
+    out := []*userResolver{}
+    for _, id := range ids {
+        u, err := r.users.GetById(ctx, id.String())
+        if errors.Is(err, models.ErrNotFound) {
+            continue
+        }
+        if err != nil {
+            return nil, resolverError(err)
+        }
+        out = append(out, &userResolver{*u})
+    }
+    return out, nil
+}
+
+type userResolver struct {
+    u models.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+    return graphql.ID(r.u.ID.String())
+}
+
+func (r *userResolver) Username() string {
+    return r.u.Username
+}
+
+func (r *userResolver) CreatedAt() graphql.Time {
+    return graphql.Time{Time: r.u.CreatedAt}
+}
+
+type eventResolver struct {
+    e    events.Event
+    task *taskResolver
+}
+
+func (r *eventResolver) Type() string {
+    return eventTypes[r.e.Type]
+}
+
+func (r *eventResolver) Task() *taskResolver {
+    return r.task
+}
+
+func (r *eventResolver) At() graphql.Time {
+    return graphql.Time{Time: r.e.At}
+}
+
+var (
+    states = map[string]models.State{
+        "PENDING":     models.Pending,
+        "IN_PROGRESS": models.InProgress,
+        "COMPLETED":   models.Completed,
+    }
+    priorities = map[string]models.Importance{
+        "HIGH":   models.High,
+        "MEDIUM": models.Medium,
+        "LOW":    models.Low,
+    }
+    eventTypes = map[events.Type]string{
+        events.Created: "CREATED",
+        events.Updated: "UPDATED",
+        events.Deleted: "DELETED",
+    }
+)
+
+func enumOf[V comparable](values map[string]V, v V) string {
+    for name, value := range values {
+        if value == v {
+            return name
+        }
+    }
+    return ""
+}
+
+func parseIDs(field string, ids *[]graphql.ID) ([]uuid.UUID, error) {
+    if ids == nil || len(*ids) == 0 {
+        return nil, nil
+    }
+    out := make([]uuid.UUID, len(*ids))
+    for i, id := range *ids {
+        u, err := uuid.Parse(string(id))
+        if err != nil {
+            return nil, models.NewValidationError(field, "must be UUIDs")
+        }
+        out[i] = u
+    }
+    return out, nil
+}
+
+func toTime(t *time.Time) *graphql.Time {
+    if t == nil {
+        return nil
+    }
+    return &graphql.Time{Time: *t}
+}
+
+func fromTime(t *graphql.Time) *time.Time {
+    if t == nil {
+        return nil
+    }
+    return &t.Time
+}
@@ -0,0 +1,50 @@
+package grpcserver
+
+import (
+    "task_manager/middleware"
+
+    "google.golang.org/genproto/googleapis/rpc/errdetails"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+    "google.golang.org/protobuf/protoadapt"
+)
+
+// codesByProblem maps the stable problem codes of the REST API to gRPC
+// codes, so both APIs classify every failure the same way.
+var codesByProblem = map[string]codes.Code{
+    "validation_failed":  codes.InvalidArgument,
+    "unauthorized":       codes.Unauthenticated,
+    "forbidden":          codes.PermissionDenied,
+    "not_found":          codes.NotFound,
+    "conflict":           codes.AlreadyExists,
+    "rate_limited":       codes.ResourceExhausted,
+    "payload_too_large":  codes.ResourceExhausted,
+    "method_not_allowed": codes.Unimplemented,
+    "unavailable":        codes.Unavailable,
+    "timeout":            codes.DeadlineExceeded,
+    "canceled":           codes.Canceled,
+    "internal":           codes.Internal,
+}
+
+// toStatus converts a service error into a gRPC status carrying the problem
+// code as ErrorInfo and any invalid fields as BadRequest details.
+func toStatus(err error) error {
+    p := middleware.NewProblem(err)
+    code, ok := codesByProblem[p.Code]
+    if !ok {
+        code = codes.Internal
+    }
+    st := status.New(code, p.Detail)
+    details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: p.Code, Domain: "task_manager"}}
+    if len(p.Errors) > 0 {
+        br := &errdetails.BadRequest{}
+        for _, f := range p.Errors {
+            br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+        }
+        details = append(details, br)
+    }
+    if withDetails, err := st.WithDetails(details...); err == nil {
+        st = withDetails
+    }
+    return st.Err()
+}
@@ -0,0 +1,209 @@
+package grpcserver
+
+import (
+    "context"
+    "log/slog"
+    "net"
+    "strconv"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/logging"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/service"
+    "time"
+
+    pb "task_manager/gen/taskmanager/v1"
+
+    "github.com/google/uuid"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "google.golang.org/grpc/status"
+)
+
+// authenticate resolves the "authorization" and "x-workspace-id" metadata
+// the same way the REST API resolves the Authorization and X-Workspace-ID
+// headers. Without workspaces only the default workspace is available.
+func authenticate(ctx context.Context, users *data.UserRepo, workspaces *service.Workspaces) (context.Context, error) {
+    md, _ := metadata.FromIncomingContext(ctx)
+    user, err := auth.Authenticate(ctx, users, first(md, "authorization"))
+    if err != nil {
+        return ctx, toStatus(err)
+    }
+    if user != nil {
+        ctx = auth.WithUser(logging.With(ctx, "user_id", user.ID.String()), user)
+    }
+    id := first(md, "x-workspace-id")
+    if id == "" {
+        return ctx, nil
+    }
+    if workspaces == nil {
+        return ctx, toStatus(models.ErrWorkspaceNotFound)
+    }
+    ws, err := workspaces.Resolve(ctx, id)
+    if err != nil {
+        return ctx, toStatus(err)
+    }
+    return data.WithWorkspace(logging.With(ctx, "workspace_id", ws.ID.String()), ws), nil
+}
+
+func first(md metadata.MD, key string) string {
+    if v := md.Get(key); len(v) > 0 {
+        return v[0]
+    }
+    return ""
+}
+
+func unaryAuth(users *data.UserRepo, workspaces *service.Workspaces) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        ctx, err := authenticate(ctx, users, workspaces)
+        if err != nil {
+            return nil, err
+        }
+        return handler(ctx, req)
+    }
+}
+
+func streamAuth(users *data.UserRepo, workspaces *service.Workspaces) grpc.StreamServerInterceptor {
+    return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        ctx, err := authenticate(ss.Context(), users, workspaces)
+        if err != nil {
+            return err
+        }
+        return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+    }
+}
+
+// routes names methods after the REST routes they mirror, so that a client
+// draws on one bucket whichever transport it uses. Other methods are named
+// by their full method name in rate_limits.
+var routes = map[string]string{
+    pb.TaskService_CreateTask_FullMethodName:     "POST /tasks",
+    pb.TaskService_GetTask_FullMethodName:        "GET /tasks/:id",
+    pb.TaskService_ListTasks_FullMethodName:      "GET /tasks",
+    pb.TaskService_ListMyTasks_FullMethodName:    "GET /me/tasks",
+    pb.TaskService_UpdateTask_FullMethodName:     "PUT /tasks/:id",
+    pb.TaskService_DeleteTask_FullMethodName:     "DELETE /tasks/:id",
+    pb.TaskService_AddAssignees_FullMethodName:   "POST /tasks/:id/assignees",
+    pb.TaskService_RemoveAssignee_FullMethodName: "DELETE /tasks/:id/assignees/:userId",
+    pb.TaskService_AddWatchers_FullMethodName:    "POST /tasks/:id/watchers",
+    pb.TaskService_RemoveWatcher_FullMethodName:  "DELETE /tasks/:id/watchers/:userId",
+}
+
+// clientKey identifies the caller of ctx the way the REST API does, from
+// its user or address.
+func clientKey(ctx context.Context) string {
+    user, _ := auth.User(ctx)
+    var ip string
+    if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+        ip = p.Addr.String()
+        if host, _, err := net.SplitHostPort(ip); err == nil {
+            ip = host
+        }
+    }
+    return auth.ClientKey(user, ip)
+}
+
+// limit takes a token for method from the caller's bucket. Limited calls
+// get a "retry-after" header in seconds, as REST responses do.
+func limit(ctx context.Context, limits *middleware.Limits, method string) error {
+    route, ok := routes[method]
+    if !ok {
+        route = method
+    }
+    a := limits.Take(route, clientKey(ctx), time.Now())
+    if a.Allowed {
+        return nil
+    }
+    grpc.SetHeader(ctx, retryAfter(a.Retry))
+    return toStatus(models.NewError(models.ErrRateLimited, "rate limit exceeded"))
+}
+
+func retryAfter(d time.Duration) metadata.MD {
+    return metadata.Pairs("retry-after", strconv.Itoa(int((d+time.Second-1)/time.Second)))
+}
+
+// unaryLimit applies the rate limits in limits to every call, and the daily
+// quota to CreateTask.
+func unaryLimit(limits *middleware.Limits) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        if err := limit(ctx, limits, info.FullMethod); err != nil {
+            return nil, err
+        }
+        if info.FullMethod != pb.TaskService_CreateTask_FullMethodName {
+            return handler(ctx, req)
+        }
+        release, retry, ok := limits.Reserve(clientKey(ctx), time.Now())
+        if !ok {
+            grpc.SetHeader(ctx, retryAfter(retry))
+            return nil, toStatus(models.NewError(models.ErrRateLimited, "daily quota exceeded"))
+        }
+        resp, err := handler(ctx, req)
+        if err != nil {
+            release()
+        }
+        return resp, err
+    }
+}
+
+// streamLimit takes one token per stream, when it opens.
+func streamLimit(limits *middleware.Limits) grpc.StreamServerInterceptor {
+    return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        if err := limit(ss.Context(), limits, info.FullMethod); err != nil {
+            return err
+        }
+        return handler(srv, ss)
+    }
+}
+
+// unaryLogger logs one "rpc" line per call, like the HTTP request log.
+func unaryLogger(logger *slog.Logger) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        ctx = logging.With(ctx, "request_id", uuid.NewString())
+        start := time.Now()
+        resp, err := handler(ctx, req)
+        logRPC(ctx, logger, info.FullMethod, start, err)
+        return resp, err
+    }
+}
+
+func streamLogger(logger *slog.Logger) grpc.StreamServerInterceptor {
+    return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        ctx := logging.With(ss.Context(), "request_id", uuid.NewString())
+        start := time.Now()
+        err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+        logRPC(ctx, logger, info.FullMethod, start, err)
+        return err
+    }
+}
+
+func logRPC(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+    code := status.Code(err)
+    attrs := []any{
+        "method", method,
+        "code", code.String(),
+        "latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+    }
+    level := slog.LevelInfo
+    if err != nil {
+        attrs = append(attrs, "error", err.Error())
+        level = slog.LevelWarn
+        switch code {
+        case codes.Internal, codes.Unavailable, codes.DeadlineExceeded, codes.Unknown:
+            level = slog.LevelError
+        }
+    }
+    logger.Log(ctx, level, "rpc", attrs...)
+}
+
+// contextStream replaces a stream's context so handlers see the caller.
+type contextStream struct {
+    grpc.ServerStream
+    ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+    return s.ctx
+}
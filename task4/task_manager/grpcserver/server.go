@@ -0,0 +1,315 @@
+// Package grpcserver serves taskmanager.v1.TaskService on top of the same
+// service layer as the REST handlers.
+package grpcserver
+
+import (
+    "context"
+    "log/slog"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/events"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/service"
+    "time"
+
+    pb "task_manager/gen/taskmanager/v1"
+
+    "github.com/google/uuid"
+    "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/reflection"
+    "google.golang.org/grpc/status"
+    "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many events a WatchTasks client may lag behind.
+const watchBuffer = 64
+
+type Server struct {
+    pb.UnimplementedTaskServiceServer
+    tasks *service.Tasks
+}
+
+// New returns a gRPC server with TaskService, reflection, tracing, request
+// logging, bearer token authentication through users, workspace scoping
+// through workspaces, which may be nil, and the rate limits and daily quota
+// of limits, which the REST API shares.
+func New(tasks *service.Tasks, users *data.UserRepo, workspaces *service.Workspaces, limits *middleware.Limits, logger *slog.Logger) *grpc.Server {
+    srv := grpc.NewServer(
+        grpc.StatsHandler(otelgrpc.NewServerHandler()),
+        grpc.ChainUnaryInterceptor(unaryLogger(logger), unaryAuth(users, workspaces), unaryLimit(limits)),
+        grpc.ChainStreamInterceptor(streamLogger(logger), streamAuth(users, workspaces), streamLimit(limits)),
+    )
+    pb.RegisterTaskServiceServer(srv, &Server{tasks: tasks})
+    reflection.Register(srv)
+    return srv
+}
+
+func (s *Server) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
+    assignees, err := parseIDs("assignees", req.Assignees)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    watchers, err := parseIDs("watchers", req.Watchers)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    task, err := s.tasks.Create(ctx, models.Task{
+        Name:        req.Name,
+        Description: req.Description,
+        Priority:    fromPriority(req.Priority),
+        DueDate:     fromTimestamp(req.DueDate),
+        Assignees:   assignees,
+        Watchers:    watchers,
+    })
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    return toTask(task), nil
+}
+
+func (s *Server) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+    task, err := s.tasks.Get(ctx, req.Id)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    return toTask(task), nil
+}
+
+func (s *Server) ListTasks(_ *pb.ListTasksRequest, stream grpc.ServerStreamingServer[pb.Task]) error {
+    tasks, err := s.tasks.List(stream.Context())
+    if err != nil {
+        return toStatus(err)
+    }
+    return sendAll(stream, tasks)
+}
+
+func (s *Server) ListMyTasks(_ *pb.ListMyTasksRequest, stream grpc.ServerStreamingServer[pb.Task]) error {
+    user, ok := auth.User(stream.Context())
+    if !ok {
+        return toStatus(models.NewError(models.ErrUnauthorized, "authentication required"))
+    }
+    tasks, err := s.tasks.Assigned(stream.Context(), user.ID)
+    if err != nil {
+        return toStatus(err)
+    }
+    return sendAll(stream, tasks)
+}
+
+func (s *Server) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
+    changes := models.Task{
+        Description: req.Description,
+        Status:      fromStatus(req.Status),
+        Priority:    fromPriority(req.Priority),
+        DueDate:     fromTimestamp(req.DueDate),
+    }
+    if req.Name != nil {
+        changes.Name = *req.Name
+    }
+    task, err := s.tasks.Update(ctx, req.Id, changes)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    return toTask(task), nil
+}
+
+func (s *Server) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+    if err := s.tasks.Delete(ctx, req.Id); err != nil {
+        return nil, toStatus(err)
+    }
+    return &pb.DeleteTaskResponse{}, nil
+}
+
+func (s *Server) AddAssignees(ctx context.Context, req *pb.AddParticipantsRequest) (*pb.Task, error) {
+    return s.addParticipants(ctx, req, s.tasks.AddAssignees)
+}
+
+func (s *Server) RemoveAssignee(ctx context.Context, req *pb.RemoveParticipantRequest) (*pb.Task, error) {
+    return s.removeParticipant(ctx, req, s.tasks.RemoveAssignee)
+}
+
+func (s *Server) AddWatchers(ctx context.Context, req *pb.AddParticipantsRequest) (*pb.Task, error) {
+    return s.addParticipants(ctx, req, s.tasks.AddWatchers)
+}
+
+func (s *Server) RemoveWatcher(ctx context.Context, req *pb.RemoveParticipantRequest) (*pb.Task, error) {
+    return s.removeParticipant(ctx, req, s.tasks.RemoveWatcher)
+}
+
+func (s *Server) WatchTasks(req *pb.WatchTasksRequest, stream grpc.ServerStreamingServer[pb.TaskEvent]) error {
+    only := map[string]bool{}
+    for _, id := range req.TaskIds {
+        only[id] = true
+    }
+    ws := data.Workspace(stream.Context()).ID
+    sub := s.tasks.Events.Subscribe(watchBuffer)
+    defer sub.Cancel()
+    // Headers tell the client every later change will be delivered.
+    if err := stream.SendHeader(metadata.MD{}); err != nil {
+        return err
+    }
+    for {
+        select {
+        case <-stream.Context().Done():
+            return nil
+        case e, ok := <-sub.C:
+            if !ok {
+                if sub.Err() == events.ErrSlow {
+                    return status.Error(codes.ResourceExhausted, "watcher fell behind; list tasks and watch again")
+                }
+                return nil
+            }
+            if e.Task.WorkspaceID != ws || (len(only) > 0 && !only[e.Task.ID.String()]) {
+                continue
+            }
+            if err := stream.Send(toEvent(e)); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+func (s *Server) addParticipants(ctx context.Context, req *pb.AddParticipantsRequest, add func(context.Context, string, []uuid.UUID) (*models.Task, error)) (*pb.Task, error) {
+    ids, err := parseIDs("user_ids", req.UserIds)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    task, err := add(ctx, req.TaskId, ids)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    return toTask(task), nil
+}
+
+func (s *Server) removeParticipant(ctx context.Context, req *pb.RemoveParticipantRequest, remove func(context.Context, string, uuid.UUID) (*models.Task, error)) (*pb.Task, error) {
+    userID, err := uuid.Parse(req.UserId)
+    if err != nil {
+        return nil, toStatus(models.NewValidationError("user_id", "must be a UUID"))
+    }
+    task, err := remove(ctx, req.TaskId, userID)
+    if err != nil {
+        return nil, toStatus(err)
+    }
+    return toTask(task), nil
+}
+
+func sendAll(stream grpc.ServerStreamingServer[pb.Task], tasks []models.Task) error {
+    for i := range tasks {
+        if err := stream.Send(toTask(&tasks[i])); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func parseIDs(field string, ids []string) ([]uuid.UUID, error) {
+    if len(ids) == 0 {
+        return nil, nil
+    }
+    out := make([]uuid.UUID, len(ids))
+    for i, id := range ids {
+        u, err := uuid.Parse(id)
+        if err != nil {
+            return nil, models.NewValidationError(field, "must be UUIDs")
+        }
+        out[i] = u
+    }
+    return out, nil
+}
+
+func toTask(t *models.Task) *pb.Task {
+    out := &pb.Task{
+        Id:          t.ID.String(),
+        Name:        t.Name,
+        Description: t.Description,
+        Status:      toStatusEnum(t.Status),
+        Priority:    toPriority(t.Priority),
+        DueDate:     toTimestamp(t.DueDate),
+        Assignees:   toStrings(t.Assignees),
+        Watchers:    toStrings(t.Watchers),
+        CreatedAt:   timestamppb.New(t.CreatedAt),
+        UpdatedAt:   timestamppb.New(t.UpdatedAt),
+        CompletedAt: toTimestamp(t.CompletedAt),
+    }
+    if t.OwnerID != nil {
+        out.OwnerId = t.OwnerID.String()
+    }
+    return out
+}
+
+var eventTypes = map[events.Type]pb.TaskEvent_Type{
+    events.Created: pb.TaskEvent_TYPE_CREATED,
+    events.Updated: pb.TaskEvent_TYPE_UPDATED,
+    events.Deleted: pb.TaskEvent_TYPE_DELETED,
+}
+
+func toEvent(e events.Event) *pb.TaskEvent {
+    return &pb.TaskEvent{Type: eventTypes[e.Type], Task: toTask(&e.Task), At: timestamppb.New(e.At)}
+}
+
+var (
+    statuses = map[models.State]pb.Status{
+        models.Pending:    pb.Status_STATUS_PENDING,
+        models.InProgress: pb.Status_STATUS_IN_PROGRESS,
+        models.Completed:  pb.Status_STATUS_COMPLETED,
+    }
+    priorities = map[models.Importance]pb.Priority{
+        models.High:   pb.Priority_PRIORITY_HIGH,
+        models.Medium: pb.Priority_PRIORITY_MEDIUM,
+        models.Low:    pb.Priority_PRIORITY_LOW,
+    }
+)
+
+func toStatusEnum(s models.State) pb.Status {
+    return statuses[s]
+}
+
+// fromStatus maps STATUS_UNSPECIFIED to "", which leaves a task's status
+// unchanged on update.
+func fromStatus(s pb.Status) models.State {
+    for state, v := range statuses {
+        if v == s {
+            return state
+        }
+    }
+    return ""
+}
+
+func toPriority(p models.Importance) pb.Priority {
+    return priorities[p]
+}
+
+func fromPriority(p pb.Priority) models.Importance {
+    for importance, v := range priorities {
+        if v == p {
+            return importance
+        }
+    }
+    return ""
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+    if t == nil {
+        return nil
+    }
+    return timestamppb.New(*t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+    if ts == nil {
+        return nil
+    }
+    t := ts.AsTime()
+    return &t
+}
+
+func toStrings(ids []uuid.UUID) []string {
+    out := make([]string, len(ids))
+    for i, id := range ids {
+        out[i] = id.String()
+    }
+    return out
+}
@@ -0,0 +1,187 @@
+package grpcserver
+
+import (
+    "context"
+    "io"
+    "log/slog"
+    "net"
+    "task_manager/auth"
+    "task_manager/config"
+    "task_manager/data"
+    "task_manager/events"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    pb "task_manager/gen/taskmanager/v1"
+
+    "google.golang.org/genproto/googleapis/rpc/errdetails"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/status"
+    "google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves srv in memory until the test ends.
+func dial(t *testing.T, srv *grpc.Server) pb.TaskServiceClient {
+    t.Helper()
+    lis := bufconn.Listen(1 << 20)
+    go srv.Serve(lis)
+    t.Cleanup(srv.Stop)
+    conn, err := grpc.NewClient("passthrough:///bufnet",
+        grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return pb.NewTaskServiceClient(conn)
+}
+
+func TestTaskService(t *testing.T) {
+    users := data.NewUserRepo(nil, "", true)
+    tasks := service.NewTasks(data.NewRepo(nil, "", true), users, events.NewBroker())
+    user, token, _ := models.NewUser("ann", time.Now())
+    if err := users.Create(context.Background(), user); err != nil {
+        t.Fatal(err)
+    }
+
+    client := dial(t, New(tasks, users, nil, middleware.NewLimits(config.Limit{}, nil, 0), slog.New(slog.DiscardHandler)))
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+    watch, err := client.WatchTasks(ctx, &pb.WatchTasksRequest{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := watch.Header(); err != nil {
+        t.Fatal(err)
+    }
+
+    created, err := client.CreateTask(authed, &pb.CreateTaskRequest{Name: "ship grpc", Priority: pb.Priority_PRIORITY_HIGH})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if created.Status != pb.Status_STATUS_PENDING || created.OwnerId != user.ID.String() {
+        t.Errorf("created = %v; want a pending task owned by the caller", created)
+    }
+
+    // Changes made through the service, as the REST API does, reach watchers.
+    if _, err := tasks.Update(context.Background(), created.Id, models.Task{Status: models.InProgress}); err != nil {
+        t.Fatal(err)
+    }
+    for _, want := range []pb.TaskEvent_Type{pb.TaskEvent_TYPE_CREATED, pb.TaskEvent_TYPE_UPDATED} {
+        e, err := watch.Recv()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if e.Type != want || e.Task.Id != created.Id {
+            t.Errorf("event = %v %s; want %v %s", e.Type, e.Task.Id, want, created.Id)
+        }
+    }
+
+    stream, err := client.ListTasks(ctx, &pb.ListTasksRequest{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var listed []*pb.Task
+    for {
+        task, err := stream.Recv()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            t.Fatal(err)
+        }
+        listed = append(listed, task)
+    }
+    if len(listed) != 1 || listed[0].Status != pb.Status_STATUS_IN_PROGRESS {
+        t.Errorf("listed = %v; want the one in-progress task", listed)
+    }
+
+    testcases := []struct {
+        cases  string
+        call   func() error
+        code   codes.Code
+        reason string
+    }{
+        {"unknown task", func() error {
+            _, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: "0b7e7a4e-58c4-4e0e-9a37-3c44e6e1d2f1"})
+            return err
+        }, codes.NotFound, "not_found"},
+        {"bad id", func() error {
+            _, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: "42"})
+            return err
+        }, codes.InvalidArgument, "validation_failed"},
+        {"missing name", func() error {
+            _, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Priority: pb.Priority_PRIORITY_LOW})
+            return err
+        }, codes.InvalidArgument, "validation_failed"},
+        {"bad token", func() error {
+            bad := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer nope")
+            _, err := client.GetTask(bad, &pb.GetTaskRequest{Id: created.Id})
+            return err
+        }, codes.Unauthenticated, "unauthorized"},
+    }
+    for _, test := range testcases {
+        st := status.Convert(test.call())
+        if st.Code() != test.code {
+            t.Errorf("%s: code = %v; want %v", test.cases, st.Code(), test.code)
+            continue
+        }
+        var reason string
+        for _, d := range st.Details() {
+            if info, ok := d.(*errdetails.ErrorInfo); ok {
+                reason = info.Reason
+            }
+        }
+        if reason != test.reason {
+            t.Errorf("%s: reason = %q; want %q", test.cases, reason, test.reason)
+        }
+    }
+}
+
+// TestLimits checks that gRPC calls draw on the same buckets and quota as
+// the REST routes they mirror.
+func TestLimits(t *testing.T) {
+    users := data.NewUserRepo(nil, "", true)
+    tasks := service.NewTasks(data.NewRepo(nil, "", true), users, nil)
+    user, token, _ := models.NewUser("ann", time.Now())
+    if err := users.Create(context.Background(), user); err != nil {
+        t.Fatal(err)
+    }
+    limits := middleware.NewLimits(config.Limit{}, map[string]config.Limit{"GET /tasks/:id": {Requests: 1, Per: time.Hour}}, 2)
+    client := dial(t, New(tasks, users, nil, limits, slog.New(slog.DiscardHandler)))
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+    // One creation went through REST, and a failed one does not count.
+    if _, _, ok := limits.Reserve(auth.ClientKey(user, ""), time.Now()); !ok {
+        t.Fatal("Reserve failed with the quota unused")
+    }
+    if _, err := client.CreateTask(ctx, &pb.CreateTaskRequest{}); status.Code(err) != codes.InvalidArgument {
+        t.Fatalf("CreateTask without a name = %v; want InvalidArgument", err)
+    }
+    created, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Name: "a", Priority: pb.Priority_PRIORITY_LOW})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var header metadata.MD
+    _, err = client.CreateTask(ctx, &pb.CreateTaskRequest{Name: "b", Priority: pb.Priority_PRIORITY_LOW}, grpc.Header(&header))
+    if status.Code(err) != codes.ResourceExhausted || len(header.Get("retry-after")) == 0 {
+        t.Errorf("CreateTask over quota = %v with %v; want ResourceExhausted and retry-after", err, header)
+    }
+
+    if _, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id}); status.Code(err) != codes.ResourceExhausted {
+        t.Errorf("second GetTask = %v; want ResourceExhausted", err)
+    }
+}
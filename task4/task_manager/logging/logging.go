@@ -0,0 +1,62 @@
+package logging
+
+import (
+    "context"
+    "io"
+    "log/slog"
+)
+
+type attrsKey struct{}
+
+// New returns a JSON logger that also writes any attributes attached to the
+// record's context with With, such as the request ID.
+func New(w io.Writer, level string) *slog.Logger {
+    handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: ParseLevel(level)})
+    return slog.New(contextHandler{handler})
+}
+
+func ParseLevel(level string) slog.Level {
+    var l slog.Level
+    if err := l.UnmarshalText([]byte(level)); err != nil {
+        return slog.LevelInfo
+    }
+    return l
+}
+
+// With returns a copy of ctx carrying extra attributes for every log line
+// written with it.
+func With(ctx context.Context, args ...any) context.Context {
+    attrs := append([]slog.Attr{}, attrs(ctx)...)
+    r := slog.Record{}
+    r.Add(args...)
+    r.Attrs(func(a slog.Attr) bool {
+        attrs = append(attrs, a)
+        return true
+    })
+    return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+func attrs(ctx context.Context) []slog.Attr {
+    if ctx == nil {
+        return nil
+    }
+    a, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+    return a
+}
+
+type contextHandler struct {
+    slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+    r.AddAttrs(attrs(ctx)...)
+    return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(as []slog.Attr) slog.Handler {
+    return contextHandler{h.Handler.WithAttrs(as)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+    return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -2,65 +2,251 @@ package main
 
 import (
     "context"
-    "log"
+    "errors"
+    "flag"
+    "log/slog"
+    "net"
+    "net/http"
     "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "task_manager/config"
     "task_manager/controllers"
     "task_manager/data"
+    "task_manager/events"
+    "task_manager/grpcserver"
+    "task_manager/logging"
+    "task_manager/metrics"
+    "task_manager/middleware"
+    "task_manager/migrations"
     "task_manager/router"
+    "task_manager/service"
+    "task_manager/tracing"
     "time"
 
+    "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
+    "go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+    "google.golang.org/grpc"
 )
 
 func main() {
     Init()
 
-    uri := os.Getenv("URI")
-    if uri == "" {
-        log.Fatal("Environment variable URI is not set")
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        os.Exit(migrate(os.Args[2:]))
     }
 
-    conn := connect(uri)
-    defer func() {
-        if err := conn.Disconnect(context.Background()); err != nil {
-            log.Printf("Error disconnecting from MongoDB: %v", err)
+    cfg, err := config.Load(os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        return
+    }
+    if err != nil {
+        slog.Error("Invalid configuration", "error", err)
+        os.Exit(1)
+    }
+    slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
+    if cfg.LogLevel != "debug" {
+        gin.SetMode(gin.ReleaseMode)
+    }
+
+    shutdownTracing, err := tracing.Setup(tracing.Options{
+        Exporter:    cfg.TraceExporter,
+        File:        cfg.TraceFile,
+        SampleRatio: cfg.TraceSample,
+    })
+    if err != nil {
+        slog.Error("Setting up tracing", "error", err)
+        os.Exit(1)
+    }
+
+    var conn *mongo.Client
+    isMemory := cfg.Storage == "memory"
+    if !isMemory {
+        conn = connect(cfg.MongoURI, cfg.ConnectTimeout)
+        if cfg.AutoMigrate {
+            applied, err := migrations.New(conn.Database(cfg.Database)).Up(context.Background())
+            for _, m := range applied {
+                slog.Info("Applied migration", "version", m.Version, "description", m.Description)
+            }
+            if err != nil {
+                slog.Error("Migrating database", "error", err)
+                os.Exit(1)
+            }
         }
+    }
+
+    repo := data.NewRepo(conn, cfg.Database, isMemory)
+    users := data.NewUserRepo(conn, cfg.Database, isMemory)
+    timeouts := data.Timeouts{Default: cfg.QueryTimeout, Ops: cfg.QueryTimeouts}
+    if err := timeouts.Validate(); err != nil {
+        slog.Error("Invalid configuration", "error", err)
+        os.Exit(1)
+    }
+    repo.SetTimeouts(timeouts)
+    users.SetTimeouts(timeouts)
+    escalationStore := data.NewEscalationRepo(conn, cfg.Database, isMemory)
+    escalationStore.SetTimeouts(timeouts)
+    timeStore := data.NewTimeRepo(conn, cfg.Database, isMemory)
+    timeStore.SetTimeouts(timeouts)
+    workspaceStore := data.NewWorkspaceRepo(conn, cfg.Database, isMemory)
+    workspaceStore.SetTimeouts(timeouts)
+    broker := events.NewBroker()
+    var taskRepo data.TaskRepository = repo
+    if !isMemory && cfg.CacheSize > 0 {
+        taskRepo = data.NewCachedRepo(repo, cfg.CacheSize, cfg.CacheTTL)
+    }
+    tasks := service.NewTasks(taskRepo, users, broker)
+    tasks.Workspaces = workspaceStore
+    workspaces := service.NewWorkspaces(workspaceStore, users)
+    escalations := service.NewEscalations(escalationStore, tasks)
+    handler := controllers.SetHandler(tasks)
+    handler.Escalations = escalations
+    handler.Time = service.NewTimeTracking(timeStore, tasks)
+    handler.Workspaces = workspaces
+    health := controllers.NewHealth(repo, cfg.ReadyTimeout)
+    if err := metrics.RegisterTasks(tasks.ListAll); err != nil {
+        slog.Error("Registering task metrics", "error", err)
+        os.Exit(1)
+    }
+
+    // REST and gRPC draw on the same rate limits and quota.
+    limits := middleware.NewLimits(cfg.RateLimit, cfg.RouteLimits, cfg.TaskQuota)
+    srv := &http.Server{
+        Addr:           cfg.Addr(),
+        Handler:        router.NewRouter(handler, health, cfg, limits),
+        ReadTimeout:    cfg.ReadTimeout,
+        WriteTimeout:   cfg.WriteTimeout,
+        IdleTimeout:    cfg.IdleTimeout,
+        MaxHeaderBytes: cfg.MaxHeaderBytes,
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    var background []func(context.Context)
+    if cfg.EscalationInterval > 0 {
+        background = append(background, func(ctx context.Context) {
+            escalations.Run(ctx, cfg.EscalationInterval)
+        })
+    }
+    if cfg.RebalanceInterval > 0 {
+        background = append(background, func(ctx context.Context) {
+            tasks.RunRebalancer(ctx, cfg.RebalanceInterval)
+        })
+    }
+    workers := startWorkers(ctx, background...)
+
+    serveErr := make(chan error, 2)
+    go func() {
+        slog.Info("Listening", "addr", srv.Addr)
+        serveErr <- srv.ListenAndServe()
     }()
+    var grpcSrv *grpc.Server
+    if cfg.GRPCPort != 0 {
+        lis, err := net.Listen("tcp", cfg.GRPCAddr())
+        if err != nil {
+            slog.Error("Listening for gRPC", "error", err)
+            os.Exit(1)
+        }
+        grpcSrv = grpcserver.New(tasks, users, workspaces, limits, slog.Default())
+        go func() {
+            slog.Info("Listening for gRPC", "addr", lis.Addr().String())
+            serveErr <- grpcSrv.Serve(lis)
+        }()
+    }
+    health.SetReady(true)
 
-    repo := data.NewRepo(conn, false)
-    handler := controllers.SetHandler(repo)
-    r := router.NewRouter(handler)
+    select {
+    case err := <-serveErr:
+        if !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, grpc.ErrServerStopped) {
+            slog.Error("Server error", "error", err)
+        }
+    case <-ctx.Done():
+        slog.Info("Shutting down")
+        // Fail readiness first so load balancers stop sending new requests
+        // before the listener closes.
+        health.SetReady(false)
+        time.Sleep(cfg.ShutdownDelay)
+    }
+    stop()
 
-    if err := r.Run(":3000"); err != nil {
-        log.Fatal(err)
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+    defer cancel()
+    // Ending the watch streams lets the gRPC server drain.
+    broker.Close()
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        slog.Error("Error draining HTTP server", "error", err)
+    }
+    if grpcSrv != nil {
+        stopGRPC(shutdownCtx, grpcSrv)
+    }
+    workers.Wait()
+    if conn != nil {
+        if err := conn.Disconnect(shutdownCtx); err != nil {
+            slog.Error("Error disconnecting from MongoDB", "error", err)
+        }
+    }
+    if err := shutdownTracing(shutdownCtx); err != nil {
+        slog.Error("Error flushing traces", "error", err)
+    }
+}
+
+// startWorkers runs each background worker until ctx is cancelled. The
+// returned WaitGroup completes once every worker has returned.
+func startWorkers(ctx context.Context, workers ...func(context.Context)) *sync.WaitGroup {
+    var wg sync.WaitGroup
+    for _, w := range workers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            w(ctx)
+        }()
+    }
+    return &wg
+}
+
+// stopGRPC waits for in-flight RPCs until ctx ends, then cuts them off.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+    done := make(chan struct{})
+    go func() {
+        srv.GracefulStop()
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-ctx.Done():
+        slog.Error("Error draining gRPC server", "error", ctx.Err())
+        srv.Stop()
     }
 }
 
 func Init() {
     if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found")
+        slog.Info("No .env file found")
     }
 }
 
-func connect(uri string) *mongo.Client {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func connect(uri string, timeout time.Duration) *mongo.Client {
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
     defer cancel()
 
-    clientOpts := options.Client().ApplyURI(uri)
+    clientOpts := options.Client().ApplyURI(uri).SetMonitor(otelmongo.NewMonitor())
     for attempt := 1; attempt <= 3; attempt++ {
         client, err := mongo.Connect(ctx, clientOpts)
         if err == nil {
             if err := client.Ping(ctx, nil); err == nil {
                 return client
             }
-            log.Printf("Ping failed on attempt %d: %v", attempt, err)
+            slog.Warn("Ping failed", "attempt", attempt, "error", err)
         } else {
-            log.Printf("Connection failed on attempt %d: %v", attempt, err)
+            slog.Warn("Connection failed", "attempt", attempt, "error", err)
         }
         time.Sleep(time.Second * time.Duration(attempt))
     }
-    log.Fatal("Failed to connect to MongoDB after retries")
+    slog.Error("Failed to connect to MongoDB after retries")
+    os.Exit(1)
     return nil
 }
\ No newline at end of file
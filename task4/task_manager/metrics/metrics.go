@@ -0,0 +1,131 @@
+package metrics
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+    Registry = prometheus.NewRegistry()
+
+    httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Name: "http_requests_total",
+        Help: "HTTP requests by method, route and status.",
+    }, []string{"method", "route", "status"})
+    httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Name:    "http_request_duration_seconds",
+        Help:    "HTTP request latency by method, route and status.",
+        Buckets: prometheus.DefBuckets,
+    }, []string{"method", "route", "status"})
+    repoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Name:    "repository_operation_duration_seconds",
+        Help:    "Repository operation latency.",
+        Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+    }, []string{"operation"})
+    repoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Name: "repository_operation_errors_total",
+        Help: "Repository operations that returned an error.",
+    }, []string{"operation"})
+    cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Name: "task_cache_lookups_total",
+        Help: "Task cache lookups by result, hit or miss.",
+    }, []string{"result"})
+)
+
+func init() {
+    Registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+        httpRequests,
+        httpDuration,
+        repoDuration,
+        repoErrors,
+        cacheLookups,
+    )
+}
+
+func Handler() http.Handler {
+    return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request under its route template rather than the
+// raw path, so /tasks/:id stays a single series.
+func Middleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+        route := c.FullPath()
+        if route == "" {
+            route = "unmatched"
+        }
+        status := strconv.Itoa(c.Writer.Status())
+        httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+        httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+    }
+}
+
+func ObserveRepo(operation string, start time.Time, err error) {
+    repoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+    if err != nil {
+        repoErrors.WithLabelValues(operation).Inc()
+    }
+}
+
+func ObserveCache(hit bool) {
+    result := "miss"
+    if hit {
+        result = "hit"
+    }
+    cacheLookups.WithLabelValues(result).Inc()
+}
+
+// RegisterTasks exposes task gauges computed from list at scrape time.
+func RegisterTasks(list func(context.Context) ([]models.Task, error)) error {
+    return Registry.Register(&taskCollector{list: list})
+}
+
+type taskCollector struct {
+    list func(context.Context) ([]models.Task, error)
+}
+
+var (
+    tasksByStatus = prometheus.NewDesc("tasks", "Tasks by status.", []string{"status"}, nil)
+    tasksOverdue  = prometheus.NewDesc("tasks_overdue", "Unfinished tasks whose due date has passed.", nil, nil)
+)
+
+func (tc *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+    ch <- tasksByStatus
+    ch <- tasksOverdue
+}
+
+func (tc *taskCollector) Collect(ch chan<- prometheus.Metric) {
+    tasks, err := tc.list(context.Background())
+    if err != nil {
+        ch <- prometheus.NewInvalidMetric(tasksByStatus, err)
+        return
+    }
+    counts := map[models.State]int{}
+    for state := range models.ValidStates {
+        counts[state] = 0
+    }
+    overdue := 0
+    now := time.Now()
+    for _, t := range tasks {
+        counts[t.Status]++
+        if t.Status != models.Completed && t.DueDate != nil && t.DueDate.Before(now) {
+            overdue++
+        }
+    }
+    for state, n := range counts {
+        ch <- prometheus.MustNewConstMetric(tasksByStatus, prometheus.GaugeValue, float64(n), string(state))
+    }
+    ch <- prometheus.MustNewConstMetric(tasksOverdue, prometheus.GaugeValue, float64(overdue))
+}
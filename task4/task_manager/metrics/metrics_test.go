@@ -0,0 +1,84 @@
+package metrics_test
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "task_manager/config"
+    "task_manager/controllers"
+    "task_manager/data"
+    "task_manager/metrics"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/router"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func scrape(t *testing.T, r http.Handler) string {
+    t.Helper()
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /metrics = %d; want 200", w.Code)
+    }
+    return w.Body.String()
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    repo := data.NewRepo(nil, "", true)
+    if err := metrics.RegisterTasks(repo.GetAll); err != nil {
+        t.Fatal(err)
+    }
+    cfg := config.Default()
+    limits := middleware.NewLimits(cfg.RateLimit, cfg.RouteLimits, cfg.TaskQuota)
+    r := router.NewRouter(controllers.SetHandler(service.NewTasks(repo, data.NewUserRepo(nil, "", true), nil)), controllers.NewHealth(repo, time.Second), cfg, limits)
+
+    requests := []struct {
+        method, path, body string
+    }{
+        {http.MethodPost, "/tasks", `{"name":"a","priority":"low"}`},
+        {http.MethodPost, "/tasks", `{"name":"b","priority":"high","due_date":"2999-01-01T00:00:00Z"}`},
+        {http.MethodPost, "/tasks", `{"name":""}`},
+        {http.MethodGet, "/tasks", ""},
+        {http.MethodGet, "/tasks/0b7e7a4e-58c4-4e0e-9a37-3c44e6e1d2f1", ""},
+        {http.MethodGet, "/nowhere", ""},
+    }
+    for _, req := range requests {
+        w := httptest.NewRecorder()
+        hr := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+        hr.Header.Set("Content-Type", "application/json")
+        r.ServeHTTP(w, hr)
+    }
+
+    past := time.Now().Add(-time.Hour)
+    repo.Create(context.Background(), models.NewTask("late", "", models.InProgress, models.Low, &past, past.Add(-time.Hour)))
+
+    body := scrape(t, r)
+    testcases := []struct {
+        cases string
+        want  string
+    }{
+        {"created count", `http_requests_total{method="POST",route="/tasks",status="201"} 2`},
+        {"rejected count", `http_requests_total{method="POST",route="/tasks",status="400"} 1`},
+        {"route template", `http_requests_total{method="GET",route="/tasks/:id",status="404"} 1`},
+        {"unmatched route", `http_requests_total{method="GET",route="unmatched",status="404"} 1`},
+        {"latency histogram", `http_request_duration_seconds_count{method="GET",route="/tasks",status="200"} 1`},
+        {"repository latency", `repository_operation_duration_seconds_count{operation="create"} 3`},
+        {"repository errors", `repository_operation_errors_total{operation="get_by_id"} 1`},
+        {"pending gauge", `tasks{status="pending"} 2`},
+        {"in progress gauge", `tasks{status="inprogress"} 1`},
+        {"completed gauge", `tasks{status="completed"} 0`},
+        {"overdue gauge", `tasks_overdue 1`},
+    }
+    for _, test := range testcases {
+        if !strings.Contains(body, test.want) {
+            t.Errorf("%s: /metrics missing %q", test.cases, test.want)
+        }
+    }
+}
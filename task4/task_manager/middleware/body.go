@@ -0,0 +1,32 @@
+package middleware
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "task_manager/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+// BodyLimit caps every request body at n bytes, so that no middleware or
+// handler reads more. Reading past the cap fails with an error TooLarge
+// recognises.
+func BodyLimit(n int64) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.Request.Body != nil {
+            c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+        }
+        c.Next()
+    }
+}
+
+// TooLarge returns an ErrTooLarge if err comes from reading past BodyLimit,
+// and nil otherwise.
+func TooLarge(err error) error {
+    var maxErr *http.MaxBytesError
+    if !errors.As(err, &maxErr) {
+        return nil
+    }
+    return models.NewError(models.ErrTooLarge, fmt.Sprintf("request body must be at most %d bytes", maxErr.Limit))
+}
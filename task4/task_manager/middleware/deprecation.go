@@ -0,0 +1,32 @@
+package middleware
+
+import (
+    "net/http"
+    "regexp"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+var versionPrefix = regexp.MustCompile(`^/api/v[0-9]+`)
+
+// Deprecated announces that a route has been deprecated since since and
+// will be removed at sunset (RFC 9745, RFC 8594), and links to the same path
+// under successor.
+func Deprecated(since, sunset time.Time, successor string) gin.HandlerFunc {
+    deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+    sunsetAt := sunset.UTC().Format(http.TimeFormat)
+    return func(c *gin.Context) {
+        c.Header("Deprecation", deprecation)
+        c.Header("Sunset", sunsetAt)
+        c.Header("Link", "<"+successor+c.Request.URL.Path+`>; rel="successor-version"`)
+        c.Next()
+    }
+}
+
+// RouteName names the matched route "METHOD /template" without its API
+// version prefix, so every version of a route shares one name.
+func RouteName(c *gin.Context) string {
+    return c.Request.Method + " " + versionPrefix.ReplaceAllString(c.FullPath(), "")
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestDeprecated(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    since := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+    sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+    var names []string
+    name := func(c *gin.Context) { names = append(names, RouteName(c)) }
+    r := gin.New()
+    r.GET("/api/v1/tasks/:id", name)
+    r.GET("/tasks/:id", Deprecated(since, sunset, "/api/v1"), name)
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/42", nil))
+    want := map[string]string{
+        "Deprecation": "@1792368000",
+        "Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+        "Link":        `</api/v1/tasks/42>; rel="successor-version"`,
+    }
+    for header, value := range want {
+        if got := w.Header().Get(header); got != value {
+            t.Errorf("%s = %q, want %q", header, got, value)
+        }
+    }
+
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/42", nil))
+    if got := w.Header().Get("Deprecation"); got != "" {
+        t.Errorf("versioned route has Deprecation %q", got)
+    }
+    if len(names) != 2 || names[0] != names[1] || names[0] != "GET /tasks/:id" {
+        t.Errorf("route names = %q, want both %q", names, "GET /tasks/:id")
+    }
+}
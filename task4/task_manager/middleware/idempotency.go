@@ -0,0 +1,234 @@
+package middleware
+
+import (
+    "bytes"
+    "container/list"
+    "crypto/sha256"
+    "io"
+    "net/http"
+    "sync"
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader names the header clients send to make a retry safe.
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKey bounds the keys clients may send.
+const maxIdempotencyKey = 255
+
+// maxIdempotencyKeys bounds the responses kept per client. Beyond it the
+// client's oldest finished response is forgotten.
+const maxIdempotencyKeys = 1000
+
+type idempotentResponse struct {
+    client      string
+    element     *list.Element
+    fingerprint [sha256.Size]byte
+    done        bool
+    status      int
+    contentType string
+    location    string
+    body        []byte
+    expires     time.Time
+}
+
+type idempotencyStore struct {
+    mu        sync.Mutex
+    responses map[string]*idempotentResponse
+    // clients lists the store keys of each client, oldest first.
+    clients   map[string]*list.List
+    lastSweep time.Time
+}
+
+// Idempotency replays the first successful response to a POST, PUT, PATCH or
+// DELETE carrying an Idempotency-Key for window, so a client can retry a
+// request without repeating its effect. Keys are per client, as named by key,
+// and bound to the method, path and body of their first request; reusing one
+// for another request is a validation error, and retrying while the first
+// request is still running a conflict. Failed requests release their key.
+// Requests are told apart by route, so the deprecated aliases share keys
+// with /api/v1.
+func Idempotency(window time.Duration, key func(*gin.Context) string) gin.HandlerFunc {
+    if window <= 0 {
+        return func(c *gin.Context) { c.Next() }
+    }
+    s := &idempotencyStore{responses: map[string]*idempotentResponse{}, clients: map[string]*list.List{}, lastSweep: time.Now()}
+    return func(c *gin.Context) {
+        idemKey := c.GetHeader(IdempotencyHeader)
+        if idemKey == "" || !unsafeMethod(c.Request.Method) {
+            c.Next()
+            return
+        }
+        if len(idemKey) > maxIdempotencyKey {
+            c.Error(models.NewValidationError(IdempotencyHeader, "must be at most 255 characters"))
+            c.Abort()
+            return
+        }
+        // The body is kept for the fingerprint, so it must be bounded; see
+        // BodyLimit.
+        body, err := io.ReadAll(c.Request.Body)
+        if tooLarge := TooLarge(err); tooLarge != nil {
+            c.Error(tooLarge)
+            c.Abort()
+            return
+        }
+        if err != nil {
+            c.Error(models.NewValidationError("body", "could not be read"))
+            c.Abort()
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        h := sha256.New()
+        io.WriteString(h, RouteName(c))
+        for _, p := range c.Params {
+            io.WriteString(h, " "+p.Key+"="+p.Value)
+        }
+        io.WriteString(h, "\n")
+        h.Write(body)
+        var fingerprint [sha256.Size]byte
+        copy(fingerprint[:], h.Sum(nil))
+
+        client := key(c)
+        storeKey := client + "|" + idemKey
+        now := time.Now()
+        s.mu.Lock()
+        s.sweep(now)
+        if r, ok := s.responses[storeKey]; ok && !r.expired(now) {
+            stored := *r
+            s.mu.Unlock()
+            switch {
+            case stored.fingerprint != fingerprint:
+                c.Error(models.NewValidationError(IdempotencyHeader, "was already used for a different request"))
+            case !stored.done:
+                c.Error(models.NewError(models.ErrConflict, "a request with this Idempotency-Key is still in progress"))
+            default:
+                stored.replay(c)
+            }
+            c.Abort()
+            return
+        }
+        if !s.makeRoom(client) {
+            s.mu.Unlock()
+            c.Error(models.NewError(models.ErrRateLimited, "too many requests with an Idempotency-Key in progress"))
+            c.Abort()
+            return
+        }
+        pending := &idempotentResponse{client: client, fingerprint: fingerprint, expires: now.Add(window)}
+        s.add(storeKey, pending)
+        s.mu.Unlock()
+
+        w := &teeWriter{ResponseWriter: c.Writer}
+        c.Writer = w
+        succeeded := false
+        // Deferred so that a panicking handler releases its key too.
+        defer func() {
+            c.Writer = w.ResponseWriter
+            s.mu.Lock()
+            defer s.mu.Unlock()
+            if !succeeded {
+                s.remove(storeKey)
+                return
+            }
+            pending.done = true
+            pending.status = w.Status()
+            pending.contentType = w.Header().Get("Content-Type")
+            pending.location = w.Header().Get("Location")
+            pending.body = w.body.Bytes()
+        }()
+        c.Next()
+        succeeded = len(c.Errors) == 0 && w.Status() < http.StatusBadRequest
+    }
+}
+
+func (r *idempotentResponse) expired(now time.Time) bool {
+    return r.done && now.After(r.expires)
+}
+
+func (r *idempotentResponse) replay(c *gin.Context) {
+    c.Header("Idempotent-Replayed", "true")
+    if r.location != "" {
+        c.Header("Location", r.location)
+    }
+    c.Data(r.status, r.contentType, r.body)
+}
+
+// sweep drops expired responses. It runs at most once a minute.
+func (s *idempotencyStore) sweep(now time.Time) {
+    if now.Sub(s.lastSweep) < time.Minute {
+        return
+    }
+    s.lastSweep = now
+    for key, r := range s.responses {
+        if r.expired(now) {
+            s.remove(key)
+        }
+    }
+}
+
+func (s *idempotencyStore) add(key string, r *idempotentResponse) {
+    s.remove(key)
+    keys, ok := s.clients[r.client]
+    if !ok {
+        keys = list.New()
+        s.clients[r.client] = keys
+    }
+    r.element = keys.PushBack(key)
+    s.responses[key] = r
+}
+
+func (s *idempotencyStore) remove(key string) {
+    r, ok := s.responses[key]
+    if !ok {
+        return
+    }
+    delete(s.responses, key)
+    keys := s.clients[r.client]
+    keys.Remove(r.element)
+    if keys.Len() == 0 {
+        delete(s.clients, r.client)
+    }
+}
+
+// makeRoom forgets client's oldest finished responses until it has fewer
+// than maxIdempotencyKeys. It fails when the rest are all in progress.
+func (s *idempotencyStore) makeRoom(client string) bool {
+    keys, ok := s.clients[client]
+    if !ok {
+        return true
+    }
+    for e := keys.Front(); e != nil && keys.Len() >= maxIdempotencyKeys; {
+        next := e.Next()
+        if key := e.Value.(string); s.responses[key].done {
+            s.remove(key)
+        }
+        e = next
+    }
+    return keys.Len() < maxIdempotencyKeys
+}
+
+func unsafeMethod(method string) bool {
+    switch method {
+    case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+        return true
+    }
+    return false
+}
+
+// teeWriter keeps a copy of the body it writes.
+type teeWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestIdempotency(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    created, failures := 0, 1
+    r := gin.New()
+    r.Use(Problems(), BodyLimit(64), Idempotency(time.Hour, func(c *gin.Context) string { return c.GetHeader("X-Client") }))
+    r.POST("/tasks", func(c *gin.Context) {
+        if failures > 0 {
+            failures--
+            c.AbortWithStatus(http.StatusServiceUnavailable)
+            return
+        }
+        created++
+        c.JSON(http.StatusCreated, gin.H{"n": created})
+    })
+
+    send := func(client, key, body string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+        req.Header.Set("X-Client", client)
+        if key != "" {
+            req.Header.Set(IdempotencyHeader, key)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    testcases := []struct {
+        cases    string
+        client   string
+        key      string
+        body     string
+        want     int
+        wantBody string
+        replayed bool
+    }{
+        {"failure releases the key", "ann", "k1", `{"name":"a"}`, http.StatusServiceUnavailable, "", false},
+        {"first success", "ann", "k1", `{"name":"a"}`, http.StatusCreated, `{"n":1}`, false},
+        {"retry is replayed", "ann", "k1", `{"name":"a"}`, http.StatusCreated, `{"n":1}`, true},
+        {"other body", "ann", "k1", `{"name":"b"}`, http.StatusBadRequest, "", false},
+        {"other client", "bob", "k1", `{"name":"a"}`, http.StatusCreated, `{"n":2}`, false},
+        {"without a key", "ann", "", `{"name":"a"}`, http.StatusCreated, `{"n":3}`, false},
+        {"key too long", "ann", strings.Repeat("k", 256), `{}`, http.StatusBadRequest, "", false},
+        {"body too large", "ann", "k2", `{"name":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge, "", false},
+    }
+    for _, tc := range testcases {
+        w := send(tc.client, tc.key, tc.body)
+        if w.Code != tc.want || (tc.wantBody != "" && w.Body.String() != tc.wantBody) {
+            t.Errorf("%s: got %d %s, want %d %s", tc.cases, w.Code, w.Body, tc.want, tc.wantBody)
+        }
+        if got := w.Header().Get("Idempotent-Replayed") == "true"; got != tc.replayed {
+            t.Errorf("%s: replayed = %v, want %v", tc.cases, got, tc.replayed)
+        }
+    }
+    if created != 3 {
+        t.Errorf("handler created %d tasks, want 3", created)
+    }
+
+    // Responses are forgotten once the window has passed.
+    short := gin.New()
+    short.Use(Idempotency(time.Nanosecond, func(*gin.Context) string { return "" }))
+    n := 0
+    short.POST("/tasks", func(c *gin.Context) { n++; c.String(http.StatusCreated, strconv.Itoa(n)) })
+    for i := 0; i < 2; i++ {
+        req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+        req.Header.Set(IdempotencyHeader, "k")
+        short.ServeHTTP(httptest.NewRecorder(), req)
+        time.Sleep(time.Millisecond)
+    }
+    if n != 2 {
+        t.Errorf("handler ran %d times across an expired window, want 2", n)
+    }
+
+    // Each client keeps at most maxIdempotencyKeys responses, forgetting the
+    // oldest first.
+    n = 0
+    long := gin.New()
+    long.Use(Idempotency(time.Hour, func(*gin.Context) string { return "" }))
+    long.POST("/tasks", func(c *gin.Context) { n++; c.String(http.StatusCreated, strconv.Itoa(n)) })
+    post := func(key string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+        req.Header.Set(IdempotencyHeader, key)
+        w := httptest.NewRecorder()
+        long.ServeHTTP(w, req)
+        return w
+    }
+    for i := 0; i <= maxIdempotencyKeys; i++ {
+        post(strconv.Itoa(i))
+    }
+    if w := post(strconv.Itoa(maxIdempotencyKeys)); w.Header().Get("Idempotent-Replayed") != "true" {
+        t.Error("the newest response was forgotten")
+    }
+    if w := post("0"); w.Header().Get("Idempotent-Replayed") == "true" {
+        t.Errorf("the oldest response was kept beyond %d keys", maxIdempotencyKeys)
+    }
+}
+
+// TestIdempotencyAliases checks that a retry on a deprecated alias replays
+// the response to the /api/v1 original.
+func TestIdempotencyAliases(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(Problems(), Idempotency(time.Hour, func(*gin.Context) string { return "" }))
+    n := 0
+    handler := func(c *gin.Context) { n++; c.String(http.StatusOK, strconv.Itoa(n)) }
+    r.PUT("/api/v1/tasks/:id", handler)
+    r.PUT("/tasks/:id", handler)
+
+    testcases := []struct {
+        cases    string
+        path     string
+        want     int
+        replayed bool
+    }{
+        {"original", "/api/v1/tasks/1", http.StatusOK, false},
+        {"alias", "/tasks/1", http.StatusOK, true},
+        {"other task", "/tasks/2", http.StatusBadRequest, false},
+    }
+    for _, tc := range testcases {
+        req := httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(`{"name":"a"}`))
+        req.Header.Set(IdempotencyHeader, "k")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        if w.Code != tc.want || (w.Header().Get("Idempotent-Replayed") == "true") != tc.replayed {
+            t.Errorf("%s: got %d replayed %q, want %d replayed %v", tc.cases, w.Code, w.Header().Get("Idempotent-Replayed"), tc.want, tc.replayed)
+        }
+    }
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+    "fmt"
+    "log/slog"
+    "net/http"
+    "runtime/debug"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Logger writes one structured line per request. Request and user IDs come
+// from the request context, so they are attached by the handler's logger.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        status := c.Writer.Status()
+        attrs := []slog.Attr{
+            slog.String("method", c.Request.Method),
+            slog.String("route", c.FullPath()),
+            slog.String("path", c.Request.URL.Path),
+            slog.Int("status", status),
+            slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+            slog.String("client_ip", c.ClientIP()),
+        }
+        if len(c.Errors) > 0 {
+            attrs = append(attrs, slog.String("error", strings.Join(c.Errors.Errors(), "; ")))
+        }
+        level := slog.LevelInfo
+        switch {
+        case status >= http.StatusInternalServerError:
+            level = slog.LevelError
+        case status >= http.StatusBadRequest:
+            level = slog.LevelWarn
+        }
+        logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+    }
+}
+
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+    return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+        logger.ErrorContext(c.Request.Context(), "panic", "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+        c.AbortWithStatus(http.StatusInternalServerError)
+    })
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+    "errors"
+    "net/http"
+    "task_manager/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the non-standard status, borrowed from nginx,
+// recorded when the client went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+// Problem is an RFC 7807 problem details body. Code is a stable, machine
+// readable identifier for the kind of failure.
+type Problem struct {
+    Type      string              `json:"type"`
+    Title     string              `json:"title"`
+    Status    int                 `json:"status"`
+    Detail    string              `json:"detail,omitempty"`
+    Instance  string              `json:"instance,omitempty"`
+    Code      string              `json:"code"`
+    RequestID string              `json:"request_id,omitempty"`
+    Errors    []models.FieldError `json:"errors,omitempty"`
+}
+
+var problemKinds = []struct {
+    kind   error
+    status int
+    code   string
+}{
+    {models.ErrValidation, http.StatusBadRequest, "validation_failed"},
+    {models.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+    {models.ErrForbidden, http.StatusForbidden, "forbidden"},
+    {models.ErrNotFound, http.StatusNotFound, "not_found"},
+    {models.ErrConflict, http.StatusConflict, "conflict"},
+    {models.ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+    {models.ErrTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+    {models.ErrMethod, http.StatusMethodNotAllowed, "method_not_allowed"},
+    {models.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+    {models.ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+    {models.ErrCanceled, StatusClientClosedRequest, "canceled"},
+}
+
+// Problems renders the last error a handler attached with c.Error as a
+// problem+json response, unless the handler already wrote a body.
+func Problems() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        c.Next()
+        if len(c.Errors) == 0 || c.Writer.Written() {
+            return
+        }
+        p := NewProblem(c.Errors.Last().Err)
+        p.Instance = c.Request.URL.Path
+        p.RequestID = c.GetString("request_id")
+        c.Header("Content-Type", ProblemContentType)
+        c.IndentedJSON(p.Status, p)
+    }
+}
+
+func NewProblem(err error) Problem {
+    p := Problem{
+        Type:   "about:blank",
+        Status: http.StatusInternalServerError,
+        Code:   "internal",
+        Detail: "internal server error",
+    }
+    for _, k := range problemKinds {
+        if errors.Is(err, k.kind) {
+            p.Status, p.Code = k.status, k.code
+            p.Detail = detail(err)
+            break
+        }
+    }
+    var v *models.ValidationError
+    if errors.As(err, &v) {
+        p.Errors = v.Fields
+    }
+    p.Title = http.StatusText(p.Status)
+    if p.Status == StatusClientClosedRequest {
+        p.Title = "Client Closed Request"
+    }
+    return p
+}
+
+// detail prefers the client-facing message of a models.Error over the full
+// chain, which may include driver internals.
+func detail(err error) string {
+    var e *models.Error
+    if errors.As(err, &e) {
+        return e.Message
+    }
+    return err.Error()
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "task_manager/models"
+    "testing"
+)
+
+func TestNewProblem(t *testing.T) {
+    testcases := []struct {
+        cases  string
+        err    error
+        status int
+        code   string
+        detail string
+    }{
+        {"not found", models.ErrTaskNotFound, http.StatusNotFound, "not_found", "task not found"},
+        {"wrapped not found", fmt.Errorf("loading: %w", models.ErrUserNotFound), http.StatusNotFound, "not_found", "user not found"},
+        {"validation", models.NewValidationError("name", "is required"), http.StatusBadRequest, "validation_failed", "name: is required"},
+        {"conflict", models.ErrUsernameTaken, http.StatusConflict, "conflict", "username already taken"},
+        {"unavailable hides cause", models.Wrap(models.ErrUnavailable, "storage unavailable", context.DeadlineExceeded), http.StatusServiceUnavailable, "unavailable", "storage unavailable"},
+        {"timeout", models.Wrap(models.ErrTimeout, "storage timed out", context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout", "storage timed out"},
+        {"canceled", models.Wrap(models.ErrCanceled, "request canceled", context.Canceled), StatusClientClosedRequest, "canceled", "request canceled"},
+        {"unauthorized", models.NewError(models.ErrUnauthorized, "invalid token"), http.StatusUnauthorized, "unauthorized", "invalid token"},
+        {"forbidden", models.NewError(models.ErrForbidden, "admin only"), http.StatusForbidden, "forbidden", "admin only"},
+        {"rate limited", models.NewError(models.ErrRateLimited, "rate limit exceeded"), http.StatusTooManyRequests, "rate_limited", "rate limit exceeded"},
+        {"method not allowed", models.NewError(models.ErrMethod, "mutations must be sent with POST"), http.StatusMethodNotAllowed, "method_not_allowed", "mutations must be sent with POST"},
+        {"unknown hides message", errors.New("connection reset by peer"), http.StatusInternalServerError, "internal", "internal server error"},
+    }
+    for _, test := range testcases {
+        p := NewProblem(test.err)
+        if p.Status != test.status || p.Code != test.code || p.Detail != test.detail {
+            t.Errorf("%s: NewProblem(%v) = %d %q %q; want %d %q %q", test.cases, test.err, p.Status, p.Code, p.Detail, test.status, test.code, test.detail)
+        }
+        title := http.StatusText(test.status)
+        if test.status == StatusClientClosedRequest {
+            title = "Client Closed Request"
+        }
+        if p.Title != title {
+            t.Errorf("%s: title = %q; want %q", test.cases, p.Title, title)
+        }
+    }
+
+    p := NewProblem(&models.ValidationError{Fields: []models.FieldError{{Field: "name", Message: "is required"}, {Field: "priority", Message: "is invalid"}}})
+    if len(p.Errors) != 2 || p.Errors[1].Field != "priority" {
+        t.Errorf("validation problem errors = %v; want both fields", p.Errors)
+    }
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+    "fmt"
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "task_manager/config"
+    "task_manager/models"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+type bucket struct {
+    tokens float64
+    last   time.Time
+    limit  config.Limit
+}
+
+// Limits holds every client's rate limit buckets and daily quota. One
+// instance serves the REST routes and the gRPC server alike, so a client
+// cannot get around a limit by switching transports.
+type Limits struct {
+    def    config.Limit
+    routes map[string]config.Limit
+    quota  int
+
+    mu        sync.Mutex
+    buckets   map[string]*bucket
+    lastSweep time.Time
+    day       string
+    counts    map[string]int
+}
+
+// NewLimits limits each client to def on every route without an entry in
+// routes, and to quota creations per UTC day; a quota of 0 disables it.
+func NewLimits(def config.Limit, routes map[string]config.Limit, quota int) *Limits {
+    return &Limits{
+        def:       def,
+        routes:    routes,
+        quota:     quota,
+        buckets:   map[string]*bucket{},
+        lastSweep: time.Now(),
+        counts:    map[string]int{},
+    }
+}
+
+// Allowance is what is left of a client's bucket after Take.
+type Allowance struct {
+    Limit     config.Limit
+    Allowed   bool
+    Remaining int
+    // Reset is when the bucket is full again, Retry when it next has a
+    // token.
+    Reset time.Duration
+    Retry time.Duration
+}
+
+// Take takes a token from client's bucket for route. Routes that are not
+// limited always allow, with a disabled Limit.
+func (l *Limits) Take(route, client string, now time.Time) Allowance {
+    limit, ok := l.routes[route]
+    if !ok {
+        limit = l.def
+    }
+    if !limit.Enabled() {
+        return Allowance{Allowed: true}
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.sweep(now)
+
+    capacity := float64(limit.Requests)
+    rate := capacity / limit.Per.Seconds()
+    key := route + "|" + client
+    b, ok := l.buckets[key]
+    if !ok || b.limit != limit {
+        b = &bucket{tokens: capacity, last: now, limit: limit}
+        l.buckets[key] = b
+    }
+    b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+    b.last = now
+
+    allowed := b.tokens >= 1
+    if allowed {
+        b.tokens--
+    }
+    return Allowance{
+        Limit:     limit,
+        Allowed:   allowed,
+        Remaining: int(b.tokens),
+        Reset:     time.Duration((capacity - b.tokens) / rate * float64(time.Second)),
+        Retry:     time.Duration((1 - b.tokens) / rate * float64(time.Second)),
+    }
+}
+
+// sweep drops buckets that have refilled completely, since they are
+// indistinguishable from new ones. It runs at most once a minute.
+func (l *Limits) sweep(now time.Time) {
+    if now.Sub(l.lastSweep) < time.Minute {
+        return
+    }
+    l.lastSweep = now
+    for key, b := range l.buckets {
+        if now.Sub(b.last) >= b.limit.Per {
+            delete(l.buckets, key)
+        }
+    }
+}
+
+// Reserve takes one of client's creations for the UTC day of now. Over
+// quota it returns false and the time until midnight; otherwise the caller
+// must call release if the creation fails, so that it does not count.
+func (l *Limits) Reserve(client string, now time.Time) (release func(), retry time.Duration, ok bool) {
+    if l.quota <= 0 {
+        return func() {}, 0, true
+    }
+    now = now.UTC()
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if today := now.Format(time.DateOnly); today != l.day {
+        l.day, l.counts = today, map[string]int{}
+    }
+    if l.counts[client] >= l.quota {
+        return nil, now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now), false
+    }
+    l.counts[client]++
+    reserved := l.day
+    return func() {
+        l.mu.Lock()
+        defer l.mu.Unlock()
+        if l.day == reserved && l.counts[client] > 0 {
+            l.counts[client]--
+        }
+    }, 0, true
+}
+
+// RateLimit applies limits per client and route. Routes are named by
+// RouteName, e.g. "POST /tasks", so every version of a route shares one
+// bucket. key identifies the client.
+func RateLimit(limits *Limits, key func(*gin.Context) string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        a := limits.Take(RouteName(c), key(c), time.Now())
+        if !a.Limit.Enabled() {
+            c.Next()
+            return
+        }
+        c.Header("RateLimit-Limit", strconv.Itoa(a.Limit.Requests))
+        c.Header("RateLimit-Remaining", strconv.Itoa(a.Remaining))
+        c.Header("RateLimit-Reset", strconv.Itoa(seconds(a.Reset)))
+        c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", a.Limit.Requests, seconds(a.Limit.Per)))
+        if !a.Allowed {
+            c.Header("Retry-After", strconv.Itoa(seconds(a.Retry)))
+            c.Error(models.NewError(models.ErrRateLimited, "rate limit exceeded"))
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+// DailyQuota caps how many requests per client may succeed each UTC day,
+// counted in limits. A slot is reserved up front and released again if the
+// request fails.
+func DailyQuota(limits *Limits, key func(*gin.Context) string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        release, retry, ok := limits.Reserve(key(c), time.Now())
+        if !ok {
+            c.Header("Retry-After", strconv.Itoa(seconds(retry)))
+            c.Error(models.NewError(models.ErrRateLimited, "daily quota exceeded"))
+            c.Abort()
+            return
+        }
+
+        c.Next()
+
+        if len(c.Errors) > 0 || c.Writer.Status() >= http.StatusBadRequest {
+            release()
+        }
+    }
+}
+
+func seconds(d time.Duration) int {
+    return int(math.Ceil(d.Seconds()))
+}
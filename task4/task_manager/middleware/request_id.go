@@ -0,0 +1,37 @@
+package middleware
+
+import (
+    "task_manager/logging"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID propagates a well-formed incoming X-Request-ID or assigns a new
+// one, echoes it on the response and attaches it to the request's log context.
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(RequestIDHeader)
+        if !validRequestID(id) {
+            id = uuid.NewString()
+        }
+        c.Set("request_id", id)
+        c.Header(RequestIDHeader, id)
+        c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "request_id", id))
+        c.Next()
+    }
+}
+
+func validRequestID(id string) bool {
+    if id == "" || len(id) > 128 {
+        return false
+    }
+    for _, r := range id {
+        if r < 0x21 || r > 0x7e {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "log/slog"
+    "os"
+    "strconv"
+    "strings"
+    "task_manager/config"
+    "task_manager/logging"
+    "task_manager/migrations"
+    "text/tabwriter"
+    "time"
+)
+
+const migrateUsage = `usage: task_manager migrate up|down [steps]|status [flags]
+
+  up      apply every pending migration
+  down    revert the latest applied migration, or the latest steps of them
+  status  list migrations and when they were applied
+
+Flags are the same as for the server, e.g. -mongo_uri and -database.`
+
+// migrate runs the migrate subcommand and returns the process exit code.
+func migrate(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, migrateUsage)
+        return 2
+    }
+    cmd, args := args[0], args[1:]
+    steps := 1
+    if cmd == "down" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+        n, err := strconv.Atoi(args[0])
+        if err != nil || n < 1 {
+            fmt.Fprintf(os.Stderr, "steps must be a positive number, got %q\n", args[0])
+            return 2
+        }
+        steps, args = n, args[1:]
+    }
+    switch cmd {
+    case "up", "down", "status":
+    default:
+        fmt.Fprintln(os.Stderr, migrateUsage)
+        return 2
+    }
+
+    cfg, err := config.Load(args)
+    if errors.Is(err, flag.ErrHelp) {
+        return 0
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    if cfg.Storage != "mongo" {
+        fmt.Fprintln(os.Stderr, "migrations only apply to mongo storage")
+        return 1
+    }
+    slog.SetDefault(logging.New(os.Stderr, cfg.LogLevel))
+
+    conn := connect(cfg.MongoURI, cfg.ConnectTimeout)
+    defer conn.Disconnect(context.Background())
+    m := migrations.New(conn.Database(cfg.Database))
+    ctx := context.Background()
+
+    switch cmd {
+    case "up":
+        applied, err := m.Up(ctx)
+        report("applied", applied)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        if len(applied) == 0 {
+            fmt.Println("database is up to date")
+        }
+    case "down":
+        reverted, err := m.Down(ctx, steps)
+        report("reverted", reverted)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        if len(reverted) == 0 {
+            fmt.Println("no applied migrations")
+        }
+    case "status":
+        statuses, err := m.Status(ctx)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(w, "VERSION\tAPPLIED\tDESCRIPTION")
+        for _, s := range statuses {
+            applied := "pending"
+            if s.Applied != nil {
+                applied = s.Applied.AppliedAt.Local().Format(time.DateTime)
+            }
+            fmt.Fprintf(w, "%d\t%s\t%s\n", s.Migration.Version, applied, s.Migration.Description)
+        }
+        w.Flush()
+    }
+    return 0
+}
+
+func report(verb string, ms []migrations.Migration) {
+    for _, m := range ms {
+        fmt.Printf("%s %d: %s\n", verb, m.Version, m.Description)
+    }
+}
@@ -0,0 +1,312 @@
+package migrations
+
+import (
+    "context"
+    "errors"
+    "task_manager/models"
+    "task_manager/rank"
+
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All lists the migrations in version order. Append new ones; never edit or
+// renumber one that has shipped.
+var All = []Migration{
+    {
+        Version:     1,
+        Description: "index tasks by id, status, due_date and owner_id",
+        Up: createIndexes("tasks",
+            index("id_unique", "id", true),
+            index("status", "status", false),
+            index("due_date", "due_date", false),
+            index("owner_id", "owner_id", false),
+        ),
+        Down: dropIndexes("tasks", "id_unique", "status", "due_date", "owner_id"),
+    },
+    {
+        Version:     2,
+        Description: "index users by id, username and token_hash",
+        Up: createIndexes("users",
+            index("id_unique", "id", true),
+            index("username_unique", "username", true),
+            index("token_hash", "token_hash", false),
+        ),
+        Down: dropIndexes("users", "id_unique", "username_unique", "token_hash"),
+    },
+    {
+        Version:     3,
+        Description: "lowercase task statuses and priorities written by early clients",
+        Up:          lowercaseEnums,
+    },
+    {
+        Version:     4,
+        Description: "backfill completed_at of completed tasks from updated_at",
+        Up:          backfillCompletedAt,
+    },
+    {
+        Version:     5,
+        Description: "index escalation rules by id and escalations by id, rule and task",
+        Up: func(ctx context.Context, db *mongo.Database) error {
+            if err := createIndexes("escalation_rules", index("id_unique", "id", true))(ctx, db); err != nil {
+                return err
+            }
+            return createIndexes("escalations",
+                index("id_unique", "id", true),
+                // A rule escalates each task at most once, even across instances.
+                mongo.IndexModel{
+                    Keys:    bson.D{{Key: "rule_id", Value: 1}, {Key: "task_id", Value: 1}},
+                    Options: options.Index().SetName("rule_task_unique").SetUnique(true),
+                },
+                index("task_id", "task_id", false),
+            )(ctx, db)
+        },
+        Down: func(ctx context.Context, db *mongo.Database) error {
+            if err := dropIndexes("escalations", "id_unique", "rule_task_unique", "task_id")(ctx, db); err != nil {
+                return err
+            }
+            return dropIndexes("escalation_rules", "id_unique")(ctx, db)
+        },
+    },
+    {
+        Version:     6,
+        Description: "index time entries by id, task, start and running timer",
+        Up: createIndexes("time_entries",
+            index("id_unique", "id", true),
+            index("task_id", "task_id", false),
+            index("start", "start", false),
+            // Running is only stored while true, so this allows one running
+            // timer per user and any number of finished entries.
+            mongo.IndexModel{
+                Keys:    bson.D{{Key: "user_id", Value: 1}},
+                Options: options.Index().SetName("running_unique").SetUnique(true).SetPartialFilterExpression(bson.M{"running": true}),
+            },
+        ),
+        Down: dropIndexes("time_entries", "id_unique", "task_id", "start", "running_unique"),
+    },
+    {
+        Version:     7,
+        Description: "index tasks by status and rank for the board",
+        Up: createIndexes("tasks", mongo.IndexModel{
+            Keys:    bson.D{{Key: "status", Value: 1}, {Key: "rank", Value: 1}},
+            Options: options.Index().SetName("status_rank"),
+        }),
+        Down: dropIndexes("tasks", "status_rank"),
+    },
+    {
+        Version:     8,
+        Description: "rank the tasks of each status column that has unranked tasks",
+        Up:          backfillRanks,
+    },
+    {
+        Version:     9,
+        Description: "index workspaces by id and member, and tasks by workspace",
+        Up:          indexWorkspaces,
+        Down:        unindexWorkspaces,
+    },
+    {
+        Version:     10,
+        Description: "put tasks, time entries and escalations without a workspace in the default workspace",
+        Up:          backfillWorkspaces,
+        Down:        unbackfillWorkspaces,
+    },
+}
+
+func index(name, field string, unique bool) mongo.IndexModel {
+    return mongo.IndexModel{
+        Keys:    bson.D{{Key: field, Value: 1}},
+        Options: options.Index().SetName(name).SetUnique(unique),
+    }
+}
+
+func createIndexes(coll string, indexes ...mongo.IndexModel) func(context.Context, *mongo.Database) error {
+    return func(ctx context.Context, db *mongo.Database) error {
+        _, err := db.Collection(coll).Indexes().CreateMany(ctx, indexes)
+        return err
+    }
+}
+
+func dropIndexes(coll string, names ...string) func(context.Context, *mongo.Database) error {
+    return func(ctx context.Context, db *mongo.Database) error {
+        for _, name := range names {
+            _, err := db.Collection(coll).Indexes().DropOne(ctx, name)
+            var cmdErr mongo.CommandError
+            if errors.As(err, &cmdErr) && (cmdErr.Code == indexNotFound || cmdErr.Code == namespaceNotFound) {
+                continue
+            }
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+}
+
+const (
+    namespaceNotFound = 26
+    indexNotFound     = 27
+)
+
+// legacyValues maps the spellings accepted before statuses and priorities
+// were validated to their current values.
+var legacyValues = map[string]map[string]string{
+    "status": {
+        "Pending":     string(models.Pending),
+        "In Progress": string(models.InProgress),
+        "InProgress":  string(models.InProgress),
+        "in_progress": string(models.InProgress),
+        "Completed":   string(models.Completed),
+    },
+    "priority": {
+        "High":   string(models.High),
+        "Medium": string(models.Medium),
+        "Low":    string(models.Low),
+    },
+}
+
+func lowercaseEnums(ctx context.Context, db *mongo.Database) error {
+    tasks := db.Collection("tasks")
+    for field, values := range legacyValues {
+        for from, to := range values {
+            _, err := tasks.UpdateMany(ctx, bson.M{field: from}, bson.M{"$set": bson.M{field: to}})
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// backfillCompletedAt estimates when tasks completed before completed_at was
+// recorded were finished. updated_at is the latest it can have been.
+func backfillCompletedAt(ctx context.Context, db *mongo.Database) error {
+    _, err := db.Collection("tasks").UpdateMany(ctx,
+        bson.M{"status": models.Completed, "completed_at": bson.M{"$exists": false}},
+        bson.A{bson.M{"$set": bson.M{"completed_at": "$updated_at"}}},
+    )
+    return err
+}
+
+// backfillRanks gives every task of a column that has unranked tasks a fresh
+// rank. Ranked tasks keep their order at the top of the column, and the
+// unranked ones follow by creation.
+func backfillRanks(ctx context.Context, db *mongo.Database) error {
+    tasks := db.Collection("tasks")
+    find := func(filter bson.M, sort bson.D) ([]any, error) {
+        return taskIDs(ctx, tasks, filter, sort)
+    }
+    for _, status := range []models.State{models.Pending, models.InProgress, models.Completed} {
+        writes, err := rankColumn(find, status)
+        if err != nil {
+            return err
+        }
+        if len(writes) == 0 {
+            continue
+        }
+        if _, err := tasks.BulkWrite(ctx, writes); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// rankColumn returns the writes that rank the column of status, or none if
+// it has no unranked tasks. find returns the ids of the tasks matching a
+// filter, sorted. Ranked and unranked tasks are read separately, since a
+// missing rank would sort first.
+func rankColumn(find func(filter bson.M, sort bson.D) ([]any, error), status models.State) ([]mongo.WriteModel, error) {
+    unranked, err := find(
+        bson.M{"status": status, "rank": bson.M{"$exists": false}},
+        bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+    if err != nil || len(unranked) == 0 {
+        return nil, err
+    }
+    ranked, err := find(
+        bson.M{"status": status, "rank": bson.M{"$exists": true}},
+        bson.D{{Key: "rank", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+    if err != nil {
+        return nil, err
+    }
+    ids := append(ranked, unranked...)
+    writes := make([]mongo.WriteModel, len(ids))
+    for i, r := range rank.Spread(len(ids)) {
+        writes[i] = mongo.NewUpdateOneModel().
+            SetFilter(bson.M{"id": ids[i]}).
+            SetUpdate(bson.M{"$set": bson.M{"rank": r}})
+    }
+    return writes, nil
+}
+
+// taskIDs returns the ids of the tasks matching filter, in the order of sort.
+func taskIDs(ctx context.Context, tasks *mongo.Collection, filter bson.M, sort bson.D) ([]any, error) {
+    cursor, err := tasks.Find(ctx, filter, options.Find().SetSort(sort).SetProjection(bson.M{"id": 1}))
+    if err != nil {
+        return nil, err
+    }
+    var docs []struct {
+        ID any `bson:"id"`
+    }
+    if err := cursor.All(ctx, &docs); err != nil {
+        return nil, err
+    }
+    ids := make([]any, len(docs))
+    for i, d := range docs {
+        ids[i] = d.ID
+    }
+    return ids, nil
+}
+
+func indexWorkspaces(ctx context.Context, db *mongo.Database) error {
+    err := createIndexes("workspaces",
+        index("id_unique", "id", true),
+        index("members_user_id", "members.user_id", false),
+    )(ctx, db)
+    if err != nil {
+        return err
+    }
+    return createIndexes("tasks", mongo.IndexModel{
+        Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "status", Value: 1}, {Key: "rank", Value: 1}},
+        Options: options.Index().SetName("workspace_status_rank"),
+    })(ctx, db)
+}
+
+func unindexWorkspaces(ctx context.Context, db *mongo.Database) error {
+    if err := dropIndexes("tasks", "workspace_status_rank")(ctx, db); err != nil {
+        return err
+    }
+    return dropIndexes("workspaces", "id_unique", "members_user_id")(ctx, db)
+}
+
+// backfillWorkspaces scopes the records written before workspaces existed to
+// the default workspace, which every query now filters by. They are marked
+// workspace_backfilled so that reverting leaves records created in the
+// default workspace since alone.
+func backfillWorkspaces(ctx context.Context, db *mongo.Database) error {
+    for _, coll := range []string{"tasks", "time_entries", "escalations"} {
+        _, err := db.Collection(coll).UpdateMany(ctx,
+            bson.M{"workspace_id": bson.M{"$exists": false}},
+            bson.M{"$set": bson.M{"workspace_id": uuid.Nil, "workspace_backfilled": true}},
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// unbackfillWorkspaces takes the records backfillWorkspaces scoped out of the
+// default workspace again.
+func unbackfillWorkspaces(ctx context.Context, db *mongo.Database) error {
+    for _, coll := range []string{"tasks", "time_entries", "escalations"} {
+        _, err := db.Collection(coll).UpdateMany(ctx,
+            bson.M{"workspace_backfilled": true},
+            bson.M{"$unset": bson.M{"workspace_id": "", "workspace_backfilled": ""}},
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
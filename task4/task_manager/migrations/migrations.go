@@ -0,0 +1,178 @@
+// Package migrations evolves the MongoDB schema: indexes and the shape of
+// stored documents. Each migration has a version and runs at most once per
+// database; applied versions are recorded in the migrations collection.
+package migrations
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collection = "migrations"
+
+var ErrIrreversible = errors.New("migration cannot be reverted")
+
+// Migration changes the database from Version-1 to Version. Up must be safe
+// to run again after a partial failure, since several instances may start at
+// once. A nil Down marks the migration as irreversible.
+type Migration struct {
+    Version     int
+    Description string
+    Up          func(ctx context.Context, db *mongo.Database) error
+    Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the document stored for each applied migration.
+type Record struct {
+    Version     int       `bson:"version"`
+    Description string    `bson:"description"`
+    AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Status pairs a known migration with its record, if it has been applied.
+type Status struct {
+    Migration Migration
+    Applied   *Record
+}
+
+type Migrator struct {
+    db         *mongo.Database
+    migrations []Migration
+    records    records
+}
+
+// records keeps track of the applied migrations.
+type records interface {
+    // applied returns the records by version.
+    applied(ctx context.Context) (map[int]Record, error)
+    // add fails with errRecorded if rec's version is recorded already.
+    add(ctx context.Context, rec Record) error
+    remove(ctx context.Context, version int) error
+}
+
+var errRecorded = errors.New("migration already recorded")
+
+// New returns a Migrator for All migrations.
+func New(db *mongo.Database) *Migrator {
+    return &Migrator{db: db, migrations: All, records: collectionRecords{db.Collection(collection)}}
+}
+
+// Up applies every pending migration in order and returns those it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+    applied, err := m.records.applied(ctx)
+    if err != nil {
+        return nil, err
+    }
+    var ran []Migration
+    for _, mig := range m.migrations {
+        if _, ok := applied[mig.Version]; ok {
+            continue
+        }
+        if err := mig.Up(ctx, m.db); err != nil {
+            return ran, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+        }
+        rec := Record{Version: mig.Version, Description: mig.Description, AppliedAt: time.Now().UTC()}
+        err := m.records.add(ctx, rec)
+        if errors.Is(err, errRecorded) {
+            // Another instance finished the same migration first.
+            continue
+        }
+        if err != nil {
+            return ran, fmt.Errorf("recording migration %d: %w", mig.Version, err)
+        }
+        ran = append(ran, mig)
+    }
+    return ran, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns
+// those it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+    applied, err := m.records.applied(ctx)
+    if err != nil {
+        return nil, err
+    }
+    var reverted []Migration
+    for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+        mig := m.migrations[i]
+        if _, ok := applied[mig.Version]; !ok {
+            continue
+        }
+        if mig.Down == nil {
+            return reverted, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, ErrIrreversible)
+        }
+        if err := mig.Down(ctx, m.db); err != nil {
+            return reverted, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+        }
+        if err := m.records.remove(ctx, mig.Version); err != nil {
+            return reverted, fmt.Errorf("unrecording migration %d: %w", mig.Version, err)
+        }
+        reverted = append(reverted, mig)
+    }
+    return reverted, nil
+}
+
+// Status lists every known migration in order and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+    applied, err := m.records.applied(ctx)
+    if err != nil {
+        return nil, err
+    }
+    statuses := make([]Status, len(m.migrations))
+    for i, mig := range m.migrations {
+        statuses[i] = Status{Migration: mig}
+        if rec, ok := applied[mig.Version]; ok {
+            statuses[i].Applied = &rec
+        }
+    }
+    return statuses, nil
+}
+
+// collectionRecords stores records in the migrations collection.
+type collectionRecords struct {
+    coll *mongo.Collection
+}
+
+// applied creates the unique index that stops two instances from recording
+// the same version before it reads the records.
+func (r collectionRecords) applied(ctx context.Context) (map[int]Record, error) {
+    _, err := r.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "version", Value: 1}},
+        Options: options.Index().SetName("version_unique").SetUnique(true),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("indexing %s: %w", collection, err)
+    }
+    cursor, err := r.coll.Find(ctx, bson.M{})
+    if err != nil {
+        return nil, err
+    }
+    var records []Record
+    if err := cursor.All(ctx, &records); err != nil {
+        return nil, err
+    }
+    applied := make(map[int]Record, len(records))
+    for _, rec := range records {
+        applied[rec.Version] = rec
+    }
+    return applied, nil
+}
+
+func (r collectionRecords) add(ctx context.Context, rec Record) error {
+    _, err := r.coll.InsertOne(ctx, rec)
+    if mongo.IsDuplicateKeyError(err) {
+        return errRecorded
+    }
+    return err
+}
+
+func (r collectionRecords) remove(ctx context.Context, version int) error {
+    _, err := r.coll.DeleteOne(ctx, bson.M{"version": version})
+    return err
+}
@@ -0,0 +1,180 @@
+package migrations
+
+import (
+    "cmp"
+    "context"
+    "errors"
+    "maps"
+    "slices"
+    "task_manager/models"
+    "testing"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAllIsOrdered(t *testing.T) {
+    for i, m := range All {
+        if m.Version != i+1 {
+            t.Errorf("migration %d has version %d; versions must count up from 1", i, m.Version)
+        }
+        if m.Description == "" || m.Up == nil {
+            t.Errorf("migration %d needs a description and an Up function", m.Version)
+        }
+    }
+}
+
+// memoryRecords keeps records in memory, so tests need no MongoDB.
+type memoryRecords map[int]Record
+
+func (r memoryRecords) applied(context.Context) (map[int]Record, error) {
+    return maps.Clone(r), nil
+}
+
+func (r memoryRecords) add(_ context.Context, rec Record) error {
+    if _, ok := r[rec.Version]; ok {
+        return errRecorded
+    }
+    r[rec.Version] = rec
+    return nil
+}
+
+func (r memoryRecords) remove(_ context.Context, version int) error {
+    delete(r, version)
+    return nil
+}
+
+// TestUpDown steps through All with records kept in memory and each
+// migration replaced by one that logs it, and checks where reverting stops.
+func TestUpDown(t *testing.T) {
+    ctx := context.Background()
+    var log []int
+    stubs := make([]Migration, len(All))
+    for i, m := range All {
+        v := m.Version
+        stubs[i] = Migration{Version: v, Description: m.Description}
+        stubs[i].Up = func(context.Context, *mongo.Database) error {
+            log = append(log, v)
+            return nil
+        }
+        if m.Down != nil {
+            stubs[i].Down = func(context.Context, *mongo.Database) error {
+                log = append(log, -v)
+                return nil
+            }
+        }
+    }
+    m := &Migrator{migrations: stubs, records: memoryRecords{}}
+
+    ran, err := m.Up(ctx)
+    if err != nil || len(ran) != len(All) {
+        t.Fatalf("Up applied %d of %d migrations: %v", len(ran), len(All), err)
+    }
+    if ran, err := m.Up(ctx); err != nil || len(ran) != 0 {
+        t.Fatalf("Up again applied %d migrations: %v", len(ran), err)
+    }
+
+    var irreversible []int
+    for _, mig := range All {
+        if mig.Down == nil {
+            irreversible = append(irreversible, mig.Version)
+        }
+    }
+    if want := []int{3, 4, 8}; !slices.Equal(irreversible, want) {
+        t.Errorf("irreversible versions are %v, want %v", irreversible, want)
+    }
+
+    reverted, err := m.Down(ctx, len(All))
+    if !errors.Is(err, ErrIrreversible) {
+        t.Fatalf("Down returned %v, want ErrIrreversible", err)
+    }
+    if len(reverted) != 2 || reverted[0].Version != 10 || reverted[1].Version != 9 {
+        t.Errorf("Down reverted %v, want 10 and 9", reverted)
+    }
+    if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, -10, -9}; !slices.Equal(log, want) {
+        t.Errorf("ran %v, want %v", log, want)
+    }
+
+    statuses, err := m.Status(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, s := range statuses {
+        if applied := s.Applied != nil; applied != (s.Migration.Version <= 8) {
+            t.Errorf("migration %d applied is %v after Down", s.Migration.Version, applied)
+        }
+    }
+}
+
+// TestRankColumn checks that backfilled ranks keep the tasks users already
+// ordered at the top of the column, ahead of the unranked ones.
+func TestRankColumn(t *testing.T) {
+    type task struct {
+        id, rank string
+        created  time.Time
+    }
+    now := time.Now()
+    column := []task{
+        {"new", "", now},
+        {"second", "t", now.Add(-3 * time.Hour)},
+        {"old", "", now.Add(-time.Hour)},
+        {"first", "m", now.Add(-2 * time.Hour)},
+    }
+    // find sorts as MongoDB does, which would put unranked tasks first.
+    find := func(filter bson.M, sort bson.D) ([]any, error) {
+        exists := filter["rank"].(bson.M)["$exists"].(bool)
+        var match []task
+        for _, tk := range column {
+            if (tk.rank != "") == exists {
+                match = append(match, tk)
+            }
+        }
+        slices.SortFunc(match, func(a, b task) int {
+            for _, k := range sort {
+                var c int
+                switch k.Key {
+                case "rank":
+                    c = cmp.Compare(a.rank, b.rank)
+                case "created_at":
+                    c = a.created.Compare(b.created)
+                case "id":
+                    c = cmp.Compare(a.id, b.id)
+                }
+                if c != 0 {
+                    return c
+                }
+            }
+            return 0
+        })
+        ids := make([]any, len(match))
+        for i, tk := range match {
+            ids[i] = tk.id
+        }
+        return ids, nil
+    }
+
+    writes, err := rankColumn(find, models.Pending)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var order []any
+    var last string
+    for _, w := range writes {
+        u := w.(*mongo.UpdateOneModel)
+        r := u.Update.(bson.M)["$set"].(bson.M)["rank"].(string)
+        if r <= last {
+            t.Errorf("ranks are not ascending: %q after %q", r, last)
+        }
+        last = r
+        order = append(order, u.Filter.(bson.M)["id"])
+    }
+    if want := []any{"first", "second", "old", "new"}; !slices.Equal(order, want) {
+        t.Errorf("ranked %v; want %v", order, want)
+    }
+
+    column = column[1:2]
+    if writes, err := rankColumn(find, models.Pending); err != nil || len(writes) != 0 {
+        t.Errorf("rankColumn of a ranked column = %d writes, %v; want none", len(writes), err)
+    }
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+    "errors"
+    "strings"
+)
+
+// Kinds of failure. Check them with errors.Is; the HTTP layer maps each one
+// to a status code.
+var (
+    ErrNotFound     = errors.New("not found")
+    ErrValidation   = errors.New("validation failed")
+    ErrConflict     = errors.New("conflict")
+    ErrUnavailable  = errors.New("service unavailable")
+    ErrTimeout      = errors.New("timeout")
+    ErrCanceled     = errors.New("canceled")
+    ErrUnauthorized = errors.New("unauthorized")
+    ErrForbidden    = errors.New("forbidden")
+    ErrRateLimited  = errors.New("rate limited")
+    ErrTooLarge     = errors.New("payload too large")
+    ErrMethod       = errors.New("method not allowed")
+)
+
+var (
+    ErrTaskNotFound       = NewError(ErrNotFound, "task not found")
+    ErrUserNotFound       = NewError(ErrNotFound, "user not found")
+    ErrUsernameTaken      = NewError(ErrConflict, "username already taken")
+    ErrRuleNotFound       = NewError(ErrNotFound, "escalation rule not found")
+    ErrEscalationNotFound = NewError(ErrNotFound, "escalation not found")
+    ErrAlreadyEscalated   = NewError(ErrConflict, "task already escalated by this rule")
+    ErrAlreadyReverted    = NewError(ErrConflict, "escalation already reverted")
+    ErrPriorityChanged    = NewError(ErrConflict, "task priority changed")
+    ErrEntryNotFound      = NewError(ErrNotFound, "time entry not found")
+    ErrNoTimer            = NewError(ErrNotFound, "no running timer")
+    ErrTimerRunning       = NewError(ErrConflict, "a timer is already running")
+    ErrWorkspaceNotFound  = NewError(ErrNotFound, "workspace not found")
+    ErrLastOwner          = NewError(ErrConflict, "a workspace needs at least one owner")
+)
+
+// Error is a failure of a given kind with a message fit for clients and an
+// optional underlying cause.
+type Error struct {
+    Kind    error
+    Message string
+    Err     error
+}
+
+func NewError(kind error, message string) error {
+    return &Error{Kind: kind, Message: message}
+}
+
+func Wrap(kind error, message string, err error) error {
+    return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+    if e.Err != nil {
+        return e.Message + ": " + e.Err.Error()
+    }
+    return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+    if e.Err != nil {
+        return []error{e.Kind, e.Err}
+    }
+    return []error{e.Kind}
+}
+
+type FieldError struct {
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request.
+type ValidationError struct {
+    Fields []FieldError
+}
+
+func NewValidationError(field, message string) *ValidationError {
+    return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Add(field, message string) {
+    e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e, or nil when no field failed.
+func (e *ValidationError) Err() error {
+    if len(e.Fields) == 0 {
+        return nil
+    }
+    return e
+}
+
+func (e *ValidationError) Error() string {
+    msgs := make([]string, len(e.Fields))
+    for i, f := range e.Fields {
+        msgs[i] = f.Field + ": " + f.Message
+    }
+    return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+    return ErrValidation
+}
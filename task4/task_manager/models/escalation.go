@@ -0,0 +1,98 @@
+package models
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// EscalationRule raises open tasks of Priority to RaiseTo once they are more
+// than OverdueBy past their due date.
+type EscalationRule struct {
+    ID        uuid.UUID  `bson:"id" json:"id"`
+    Name      string     `bson:"name" json:"name"`
+    Priority  Importance `bson:"priority" json:"priority"`
+    OverdueBy Duration   `bson:"overdue_by" json:"overdue_by"`
+    RaiseTo   Importance `bson:"raise_to" json:"raise_to"`
+    CreatedBy uuid.UUID  `bson:"created_by" json:"created_by"`
+    CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+}
+
+func (r *EscalationRule) Validate() error {
+    v := &ValidationError{}
+    if r.Name == "" {
+        v.Add("name", "is required")
+    }
+    if !ValidPriorities[r.Priority] {
+        v.Add("priority", "must be one of high, medium, low")
+    }
+    if !ValidPriorities[r.RaiseTo] {
+        v.Add("raise_to", "must be one of high, medium, low")
+    } else if ValidPriorities[r.Priority] && !r.RaiseTo.Above(r.Priority) {
+        v.Add("raise_to", "must be above priority")
+    }
+    if r.OverdueBy < 0 {
+        v.Add("overdue_by", "must not be negative")
+    }
+    return v.Err()
+}
+
+// Escalation records one priority change made by a rule. The rule's name is
+// copied so the record still reads well after the rule is deleted.
+type Escalation struct {
+    ID          uuid.UUID  `bson:"id" json:"id"`
+    RuleID      uuid.UUID  `bson:"rule_id" json:"rule_id"`
+    RuleName    string     `bson:"rule_name" json:"rule_name"`
+    TaskID      uuid.UUID  `bson:"task_id" json:"task_id"`
+    WorkspaceID uuid.UUID  `bson:"workspace_id" json:"-"`
+    From        Importance `bson:"from" json:"from"`
+    To          Importance `bson:"to" json:"to"`
+    At          time.Time  `bson:"at" json:"at"`
+    RevertedAt  *time.Time `bson:"reverted_at,omitempty" json:"reverted_at,omitempty"`
+    RevertedBy  *uuid.UUID `bson:"reverted_by,omitempty" json:"reverted_by,omitempty"`
+}
+
+// Duration is a time.Duration that JSON writes as a string such as "36h" or
+// "2d", where a day is 24 hours.
+type Duration time.Duration
+
+func ParseDuration(s string) (Duration, error) {
+    if n, ok := strings.CutSuffix(s, "d"); ok {
+        days, err := strconv.Atoi(n)
+        if err != nil {
+            return 0, fmt.Errorf("invalid duration %q", s)
+        }
+        return Duration(time.Duration(days) * 24 * time.Hour), nil
+    }
+    d, err := time.ParseDuration(s)
+    return Duration(d), err
+}
+
+func (d Duration) String() string {
+    const day = 24 * time.Hour
+    if d != 0 && time.Duration(d)%day == 0 {
+        return fmt.Sprintf("%dd", time.Duration(d)/day)
+    }
+    return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+    return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+    var s string
+    if err := json.Unmarshal(b, &s); err != nil {
+        return err
+    }
+    v, err := ParseDuration(s)
+    if err != nil {
+        return err
+    }
+    *d = v
+    return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+// TaskStats summarises every task, with lead times, throughput and the daily
+// series limited to a window of UTC days.
+type TaskStats struct {
+    From       string             `json:"from"`
+    To         string             `json:"to"`
+    Total      int                `json:"total"`
+    ByStatus   map[State]int      `json:"by_status"`
+    ByPriority map[Importance]int `json:"by_priority"`
+    Overdue    int                `json:"overdue"`
+    LeadTime   LeadTime           `json:"lead_time"`
+    Throughput []WeekCount        `json:"throughput"`
+    Series     []DayCount         `json:"series"`
+}
+
+// LeadTime describes how long the tasks completed in the window took from
+// creation to completion. The hours are zero when Completed is.
+type LeadTime struct {
+    Completed    int     `json:"completed"`
+    AverageHours float64 `json:"average_hours"`
+    P50Hours     float64 `json:"p50_hours"`
+    P90Hours     float64 `json:"p90_hours"`
+    P95Hours     float64 `json:"p95_hours"`
+}
+
+// WeekCount is how many tasks were completed in the week starting on the
+// Monday WeekStart, counting only days inside the window.
+type WeekCount struct {
+    WeekStart string `json:"week_start"`
+    Completed int    `json:"completed"`
+}
+
+type DayCount struct {
+    Date      string `json:"date"`
+    Created   int    `json:"created"`
+    Completed int    `json:"completed"`
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-    "errors"
     "time"
 
     "github.com/google/uuid"
@@ -32,8 +31,19 @@ var (
         Medium: true,
         Low:    true,
     }
+    // priorityRank orders the priorities from least to most urgent.
+    priorityRank = map[Importance]int{
+        Low:    1,
+        Medium: 2,
+        High:   3,
+    }
 )
 
+// Above reports whether i is more urgent than j.
+func (i Importance) Above(j Importance) bool {
+    return priorityRank[i] > priorityRank[j]
+}
+
 type BaseModel struct {
     ID          uuid.UUID  `bson:"id" json:"id"`
     CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
@@ -48,6 +58,19 @@ type Task struct {
     Status      State        `bson:"status" json:"status"`
     Priority    Importance   `bson:"priority" json:"priority"`
     DueDate     *time.Time   `bson:"due_date,omitempty" json:"due_date,omitempty"`
+    OwnerID     *uuid.UUID   `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+    Assignees   []uuid.UUID  `bson:"assignees,omitempty" json:"assignees,omitempty"`
+    Watchers    []uuid.UUID  `bson:"watchers,omitempty" json:"watchers,omitempty"`
+    Project     *string      `bson:"project,omitempty" json:"project,omitempty"`
+    // WorkspaceID is set by the repository from the workspace the task is
+    // created in; see data.WithWorkspace.
+    WorkspaceID uuid.UUID `bson:"workspace_id" json:"-"`
+    // Rank orders the tasks of a status column; see package rank.
+    Rank string `bson:"rank,omitempty" json:"rank,omitempty"`
+    // EstimateSeconds is set by clients; TrackedSeconds sums the finished
+    // time entries and is only changed through them.
+    EstimateSeconds *int64 `bson:"estimate_seconds,omitempty" json:"estimate_seconds,omitempty"`
+    TrackedSeconds  int64  `bson:"tracked_seconds,omitempty" json:"tracked_seconds,omitempty"`
 }
 
 func NewTask(name, desc string, status State, priority Importance, dueDate *time.Time, createdAt time.Time) *Task {
@@ -69,17 +92,21 @@ func NewTask(name, desc string, status State, priority Importance, dueDate *time
 }
 
 func (t *Task) Validate() error {
+    v := &ValidationError{}
     if t.Name == "" {
-        return errors.New("name is required")
+        v.Add("name", "is required")
     }
     if !ValidStates[t.Status] {
-        return errors.New("invalid status")
+        v.Add("status", "must be one of pending, inprogress, completed")
     }
     if !ValidPriorities[t.Priority] {
-        return errors.New("invalid priority")
+        v.Add("priority", "must be one of high, medium, low")
     }
     if t.DueDate != nil && !t.DueDate.IsZero() && t.DueDate.Before(t.CreatedAt) {
-        return errors.New("due date is in the past")
+        v.Add("due_date", "is in the past")
+    }
+    if t.EstimateSeconds != nil && *t.EstimateSeconds < 0 {
+        v.Add("estimate_seconds", "must not be negative")
     }
-    return nil
+    return v.Err()
 }
\ No newline at end of file
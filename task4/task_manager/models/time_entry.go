@@ -0,0 +1,57 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// TimeEntry is time a user spent on a task, either measured by a timer or
+// entered by hand. A running timer has no End and counts no Seconds yet.
+type TimeEntry struct {
+    ID          uuid.UUID  `bson:"id" json:"id"`
+    TaskID      uuid.UUID  `bson:"task_id" json:"task_id"`
+    WorkspaceID uuid.UUID  `bson:"workspace_id" json:"-"`
+    UserID      uuid.UUID  `bson:"user_id" json:"user_id"`
+    Start       time.Time  `bson:"start" json:"start"`
+    End         *time.Time `bson:"end,omitempty" json:"end,omitempty"`
+    Seconds     int64      `bson:"seconds" json:"seconds"`
+    // Running is only stored while true, so that a partial unique index
+    // allows each user one running timer.
+    Running bool    `bson:"running,omitempty" json:"running"`
+    Manual  bool    `bson:"manual" json:"manual"`
+    Note    *string `bson:"note,omitempty" json:"note,omitempty"`
+}
+
+// TimeReport sums the finished time entries that started within a window of
+// UTC days.
+type TimeReport struct {
+    From         string        `json:"from"`
+    To           string        `json:"to"`
+    TotalSeconds int64         `json:"total_seconds"`
+    ByTask       []TaskTime    `json:"by_task"`
+    ByProject    []ProjectTime `json:"by_project"`
+    ByDay        []DayTime     `json:"by_day"`
+}
+
+// TaskTime compares the time tracked on a task in the window and in total
+// with its estimate. Name and Project are empty once the task is deleted.
+type TaskTime struct {
+    TaskID          uuid.UUID `json:"task_id"`
+    Name            string    `json:"name,omitempty"`
+    Project         string    `json:"project,omitempty"`
+    Seconds         int64     `json:"seconds"`
+    TrackedSeconds  int64     `json:"tracked_seconds"`
+    EstimateSeconds *int64    `json:"estimate_seconds,omitempty"`
+}
+
+// ProjectTime sums a project's tasks; Project is empty for tasks without one.
+type ProjectTime struct {
+    Project string `json:"project"`
+    Seconds int64  `json:"seconds"`
+}
+
+type DayTime struct {
+    Date    string `json:"date"`
+    Seconds int64  `json:"seconds"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "time"
+    // Time zones must resolve even where the system has no zoneinfo.
+    _ "time/tzdata"
+
+    "github.com/google/uuid"
+)
+
+type User struct {
+    ID        uuid.UUID `bson:"id" json:"id"`
+    Username  string    `bson:"username" json:"username"`
+    TokenHash string    `bson:"token_hash" json:"-"`
+    CreatedAt time.Time `bson:"created_at" json:"created_at"`
+    // TimeZone is an IANA name such as "Europe/Berlin"; empty means UTC.
+    TimeZone string `bson:"time_zone,omitempty" json:"time_zone,omitempty"`
+}
+
+// NewUser returns the user together with its plaintext API token. Only the
+// hash of the token is stored, so the caller must hand it out immediately.
+func NewUser(username string, createdAt time.Time) (*User, string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return nil, "", err
+    }
+    token := hex.EncodeToString(buf)
+    u := &User{
+        ID:        uuid.New(),
+        Username:  username,
+        TokenHash: HashToken(token),
+        CreatedAt: createdAt,
+    }
+    return u, token, nil
+}
+
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+func (u *User) Validate() error {
+    v := &ValidationError{}
+    if u.Username == "" {
+        v.Add("username", "is required")
+    }
+    if _, err := LoadZone(u.TimeZone); err != nil {
+        v.Add("time_zone", "must be an IANA time zone such as Europe/Berlin")
+    }
+    return v.Err()
+}
+
+// Location returns the user's time zone.
+func (u *User) Location() *time.Location {
+    loc, err := LoadZone(u.TimeZone)
+    if err != nil {
+        return time.UTC
+    }
+    return loc
+}
+
+// LoadZone returns the IANA time zone name, or UTC for an empty name. Unlike
+// time.LoadLocation it refuses "Local", which differs between servers.
+func LoadZone(name string) (*time.Location, error) {
+    if name == "Local" {
+        return nil, errors.New("unknown time zone Local")
+    }
+    return time.LoadLocation(name)
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// Role is what a member may do in a workspace. Owners change its settings
+// and members; members work on its tasks.
+type Role string
+
+const (
+    RoleOwner  Role = "owner"
+    RoleMember Role = "member"
+)
+
+var ValidRoles = map[Role]bool{
+    RoleOwner:  true,
+    RoleMember: true,
+}
+
+// Workspace holds one team's tasks, apart from every other team's. The
+// default workspace, with the zero ID, is not stored: it holds the tasks
+// created without a workspace, and every caller may use it.
+type Workspace struct {
+    ID        uuid.UUID         `bson:"id" json:"id"`
+    Name      string            `bson:"name" json:"name"`
+    Members   []Member          `bson:"members" json:"members"`
+    Settings  WorkspaceSettings `bson:"settings" json:"settings"`
+    CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+    UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+type Member struct {
+    UserID uuid.UUID `bson:"user_id" json:"user_id"`
+    Role   Role      `bson:"role" json:"role"`
+}
+
+// WorkspaceSettings adjust how tasks behave in one workspace. Zero values
+// keep the defaults.
+type WorkspaceSettings struct {
+    // DefaultPriority is given to tasks created without a priority.
+    DefaultPriority Importance `bson:"default_priority,omitempty" json:"default_priority,omitempty"`
+    // AllowedStatuses are the statuses tasks may move to; empty allows all.
+    // It always includes pending, where new tasks start.
+    AllowedStatuses []State `bson:"allowed_statuses,omitempty" json:"allowed_statuses,omitempty"`
+}
+
+func DefaultWorkspace() *Workspace {
+    return &Workspace{Name: "default", Members: []Member{}}
+}
+
+func (w *Workspace) IsDefault() bool {
+    return w.ID == uuid.Nil
+}
+
+// Role returns the role of userID, and false if it is not a member.
+func (w *Workspace) Role(userID uuid.UUID) (Role, bool) {
+    for _, m := range w.Members {
+        if m.UserID == userID {
+            return m.Role, true
+        }
+    }
+    return "", false
+}
+
+// Allows reports whether tasks may move to status.
+func (s WorkspaceSettings) Allows(status State) bool {
+    if len(s.AllowedStatuses) == 0 {
+        return ValidStates[status]
+    }
+    for _, allowed := range s.AllowedStatuses {
+        if allowed == status {
+            return true
+        }
+    }
+    return false
+}
+
+func (w *Workspace) Validate() error {
+    v := &ValidationError{}
+    if w.Name == "" {
+        v.Add("name", "is required")
+    }
+    w.Settings.validate(v)
+    return v.Err()
+}
+
+func (s WorkspaceSettings) validate(v *ValidationError) {
+    if s.DefaultPriority != "" && !ValidPriorities[s.DefaultPriority] {
+        v.Add("settings.default_priority", "must be one of high, medium, low")
+    }
+    if len(s.AllowedStatuses) == 0 {
+        return
+    }
+    seen := map[State]bool{}
+    for _, status := range s.AllowedStatuses {
+        if !ValidStates[status] || seen[status] {
+            v.Add("settings.allowed_statuses", "must be distinct values of pending, inprogress, completed")
+            return
+        }
+        seen[status] = true
+    }
+    if !seen[Pending] {
+        v.Add("settings.allowed_statuses", "must include pending")
+    }
+}
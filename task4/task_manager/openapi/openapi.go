@@ -0,0 +1,174 @@
+// Package openapi serves the OpenAPI 3 description of the HTTP API and
+// checks traffic against it.
+package openapi
+
+import (
+    "bytes"
+    "context"
+    _ "embed"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "task_manager/middleware"
+    "task_manager/models"
+
+    "github.com/getkin/kin-openapi/openapi3"
+    "github.com/getkin/kin-openapi/openapi3filter"
+    "github.com/getkin/kin-openapi/routers/gorillamux"
+    "github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the raw document served at /openapi.json.
+func Spec() []byte {
+    return spec
+}
+
+// Load parses and validates the embedded document.
+func Load() (*openapi3.T, error) {
+    doc, err := openapi3.NewLoader().LoadFromData(spec)
+    if err != nil {
+        return nil, err
+    }
+    if err := doc.Validate(context.Background()); err != nil {
+        return nil, err
+    }
+    return doc, nil
+}
+
+// Handler serves the document.
+func Handler(c *gin.Context) {
+    c.Data(http.StatusOK, "application/json", spec)
+}
+
+// Validator rejects requests that do not match the document with a
+// validation error. Routes the document does not describe are passed through.
+// With validateResponses set, responses other than event streams are
+// buffered and checked too; a mismatch is replaced by a 500, which is only
+// meant for tests. It panics if the embedded document is invalid.
+func Validator(validateResponses bool) gin.HandlerFunc {
+    doc, err := Load()
+    if err != nil {
+        panic("openapi: " + err.Error())
+    }
+    router, err := gorillamux.NewRouter(doc)
+    if err != nil {
+        panic("openapi: " + err.Error())
+    }
+    opts := &openapi3filter.Options{
+        MultiError: true,
+        // Identify checks tokens; the spec only documents them.
+        AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+    }
+    return func(c *gin.Context) {
+        if c.FullPath() == "" {
+            c.Next()
+            return
+        }
+        route, params, err := router.FindRoute(c.Request)
+        if err != nil {
+            c.Next()
+            return
+        }
+        input := &openapi3filter.RequestValidationInput{
+            Request:    c.Request,
+            PathParams: params,
+            Route:      route,
+            Options:    opts,
+        }
+        if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+            c.Error(requestError(err))
+            c.Abort()
+            return
+        }
+        if !validateResponses || strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+            c.Next()
+            return
+        }
+
+        w := &bufferedWriter{ResponseWriter: c.Writer}
+        c.Writer = w
+        c.Next()
+        c.Writer = w.ResponseWriter
+        if w.body.Len() == 0 && len(c.Errors) > 0 {
+            // Problems renders the error after this returns.
+            return
+        }
+
+        out := &openapi3filter.ResponseValidationInput{
+            RequestValidationInput: input,
+            Status:                 w.Status(),
+            Header:                 w.Header(),
+            Options:                opts,
+        }
+        out.SetBodyBytes(w.body.Bytes())
+        if err := openapi3filter.ValidateResponse(c.Request.Context(), out); err != nil {
+            c.Error(fmt.Errorf("response does not match the OpenAPI document: %w", err))
+            return
+        }
+        c.Writer.WriteHeaderNow()
+        c.Writer.Write(w.body.Bytes())
+    }
+}
+
+// requestError turns the filter's errors into field errors so clients get
+// the same problem body as for any other invalid request.
+func requestError(err error) error {
+    if tooLarge := middleware.TooLarge(err); tooLarge != nil {
+        return tooLarge
+    }
+    v := &models.ValidationError{}
+    var errs openapi3.MultiError
+    if !errors.As(err, &errs) {
+        errs = openapi3.MultiError{err}
+    }
+    for _, e := range errs {
+        var reqErr *openapi3filter.RequestError
+        if !errors.As(e, &reqErr) {
+            v.Add("request", e.Error())
+            continue
+        }
+        field := "body"
+        if reqErr.Parameter != nil {
+            field = reqErr.Parameter.Name
+        }
+        var nested openapi3.MultiError
+        if !errors.As(reqErr.Err, &nested) {
+            nested = openapi3.MultiError{reqErr.Err}
+        }
+        for _, n := range nested {
+            var schemaErr *openapi3.SchemaError
+            switch {
+            case errors.As(n, &schemaErr):
+                name := field
+                if ptr := schemaErr.JSONPointer(); len(ptr) > 0 && reqErr.Parameter == nil {
+                    name = strings.Join(ptr, ".")
+                }
+                v.Add(name, schemaErr.Reason)
+            case n != nil:
+                v.Add(field, n.Error())
+            default:
+                v.Add(field, reqErr.Reason)
+            }
+        }
+    }
+    return v.Err()
+}
+
+type bufferedWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+    return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+    return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
@@ -0,0 +1,381 @@
+package openapi_test
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "task_manager/config"
+    "task_manager/controllers"
+    "task_manager/data"
+    "task_manager/middleware"
+    "task_manager/openapi"
+    "task_manager/router"
+    "task_manager/service"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+    doc, err := openapi.Load()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, route := range newRouter().Routes() {
+        path := route.Path
+        for _, part := range strings.Split(path, "/") {
+            if strings.HasPrefix(part, ":") {
+                path = strings.Replace(path, part, "{"+part[1:]+"}", 1)
+            }
+        }
+        item := doc.Paths.Find(path)
+        if item == nil || item.GetOperation(route.Method) == nil {
+            t.Errorf("%s %s is not in openapi.json", route.Method, path)
+        }
+    }
+}
+
+// TestTraffic runs in test mode, so every response below is also checked
+// against the document and would come back as a 500 if it drifted.
+func TestTraffic(t *testing.T) {
+    r := newRouter()
+    send := func(method, path, body, token string) (int, map[string]any) {
+        t.Helper()
+        req := httptest.NewRequest(method, path, strings.NewReader(body))
+        if body != "" {
+            req.Header.Set("Content-Type", "application/json")
+        }
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        var m map[string]any
+        json.Unmarshal(w.Body.Bytes(), &m)
+        return w.Code, m
+    }
+
+    code, created := send(http.MethodPost, "/api/v1/users", `{"username":"ann"}`, "")
+    if code != http.StatusCreated {
+        t.Fatalf("create user: got %d", code)
+    }
+    token := created["token"].(string)
+    userID := created["user"].(map[string]any)["id"].(string)
+
+    code, task := send(http.MethodPost, "/api/v1/tasks", `{"name":"write spec","priority":"high","due_date":"2999-01-01T00:00:00Z","project":"docs","estimate_seconds":7200}`, token)
+    if code != http.StatusCreated {
+        t.Fatalf("create task: got %d", code)
+    }
+    taskPath := "/api/v1/tasks/" + task["id"].(string)
+
+    _, bob := send(http.MethodPost, "/api/v1/users", `{"username":"bob"}`, "")
+    code, rule := send(http.MethodPost, "/api/v1/escalation-rules", `{"name":"late low","priority":"low","overdue_by":"2d","raise_to":"medium"}`, token)
+    if code != http.StatusCreated {
+        t.Fatalf("create escalation rule: got %d", code)
+    }
+    rulePath := "/api/v1/escalation-rules/" + rule["id"].(string)
+
+    code, entry := send(http.MethodPost, taskPath+"/time-entries", `{"start":"2026-01-05T09:00:00Z","seconds":3600,"note":"outline"}`, token)
+    if code != http.StatusCreated {
+        t.Fatalf("add time entry: got %d", code)
+    }
+    entryPath := taskPath + "/time-entries/" + entry["id"].(string)
+
+    code, ws := send(http.MethodPost, "/api/v1/workspaces", `{"name":"ops","settings":{"default_priority":"low"}}`, token)
+    if code != http.StatusCreated {
+        t.Fatalf("create workspace: got %d", code)
+    }
+    wsPath := "/api/v1/workspaces/" + ws["id"].(string)
+    bobID := bob["user"].(map[string]any)["id"].(string)
+
+    testcases := []struct {
+        name   string
+        method string
+        path   string
+        body   string
+        token  string
+        want   int
+    }{
+        {"list tasks", http.MethodGet, "/api/v1/tasks", "", "", http.StatusOK},
+        {"deprecated alias", http.MethodGet, "/tasks", "", "", http.StatusOK},
+        {"stats", http.MethodGet, "/api/v1/tasks/stats?from=2026-01-01&to=2026-03-31", "", "", http.StatusOK},
+        {"stats window too long", http.MethodGet, "/api/v1/tasks/stats?from=2020-01-01&to=2026-03-31", "", "", http.StatusBadRequest},
+        {"stats bad date", http.MethodGet, "/api/v1/tasks/stats?from=yesterday", "", "", http.StatusBadRequest},
+        {"get task", http.MethodGet, taskPath, "", "", http.StatusOK},
+        {"update task", http.MethodPut, taskPath, `{"status":"inprogress"}`, "", http.StatusOK},
+        {"due date expression", http.MethodPut, taskPath, `{"due_date":"next friday 5pm"}`, token, http.StatusOK},
+        {"unknown due date expression", http.MethodPut, taskPath, `{"due_date":"someday"}`, token, http.StatusBadRequest},
+        {"due date in 0 days", http.MethodPut, taskPath, `{"due_date":"in 0 days"}`, token, http.StatusBadRequest},
+        {"body too large", http.MethodPost, "/api/v1/tasks", `{"name":"` + strings.Repeat("x", 1<<20) + `"}`, token, http.StatusRequestEntityTooLarge},
+        {"graphql body too large", http.MethodPost, "/graphql", `{"query":"` + strings.Repeat("x", 1<<20) + `"}`, token, http.StatusRequestEntityTooLarge},
+        {"due dates in a zone", http.MethodGet, taskPath + "?tz=America/New_York", "", "", http.StatusOK},
+        {"due dates in my zone need a token", http.MethodGet, taskPath + "?tz=me", "", "", http.StatusUnauthorized},
+        {"unknown zone", http.MethodGet, "/api/v1/tasks?tz=Mars/Olympus", "", "", http.StatusBadRequest},
+        {"get me", http.MethodGet, "/api/v1/me", "", token, http.StatusOK},
+        {"set time zone", http.MethodPut, "/api/v1/me", `{"time_zone":"Europe/Berlin"}`, token, http.StatusOK},
+        {"set unknown time zone", http.MethodPut, "/api/v1/me", `{"time_zone":"Local"}`, token, http.StatusBadRequest},
+        {"move task", http.MethodPost, taskPath + "/move", `{"status":"pending"}`, "", http.StatusOK},
+        {"move next to a stranger", http.MethodPost, taskPath + "/move", `{"after":"` + userID + `"}`, "", http.StatusBadRequest},
+        {"move bad status", http.MethodPost, taskPath + "/move", `{"status":"done"}`, "", http.StatusBadRequest},
+        {"board", http.MethodGet, "/api/v1/board", "", "", http.StatusOK},
+        {"assign", http.MethodPost, taskPath + "/assignees", `{"user_ids":["` + userID + `"]}`, "", http.StatusOK},
+        {"my tasks", http.MethodGet, "/api/v1/me/tasks", "", token, http.StatusOK},
+        {"unassign", http.MethodDelete, taskPath + "/assignees/" + userID, "", "", http.StatusOK},
+        {"watch", http.MethodPost, taskPath + "/watchers", `{"user_ids":["` + userID + `"]}`, "", http.StatusOK},
+        {"unwatch", http.MethodDelete, taskPath + "/watchers/" + userID, "", "", http.StatusOK},
+        {"list users", http.MethodGet, "/api/v1/users", "", "", http.StatusOK},
+        {"get user", http.MethodGet, "/api/v1/users/" + userID, "", "", http.StatusOK},
+        {"list escalation rules", http.MethodGet, "/api/v1/escalation-rules", "", token, http.StatusOK},
+        {"escalation rules need a token", http.MethodGet, "/api/v1/escalation-rules", "", "", http.StatusUnauthorized},
+        {"escalation rules need an admin", http.MethodGet, "/api/v1/escalation-rules", "", bob["token"].(string), http.StatusForbidden},
+        {"rule must raise priority", http.MethodPost, "/api/v1/escalation-rules", `{"name":"down","priority":"high","overdue_by":"1h","raise_to":"low"}`, token, http.StatusBadRequest},
+        {"rule bad duration", http.MethodPost, "/api/v1/escalation-rules", `{"name":"late","priority":"low","overdue_by":"soon","raise_to":"high"}`, token, http.StatusBadRequest},
+        {"list escalations", http.MethodGet, "/api/v1/escalations?task_id=" + task["id"].(string), "", "", http.StatusOK},
+        {"revert unknown escalation", http.MethodPost, "/api/v1/escalations/" + userID + "/revert", "", token, http.StatusNotFound},
+        {"delete escalation rule", http.MethodDelete, rulePath, "", token, http.StatusNoContent},
+        {"start timer", http.MethodPost, taskPath + "/timer/start", "", token, http.StatusCreated},
+        {"second timer", http.MethodPost, taskPath + "/timer/start", "", token, http.StatusConflict},
+        {"timer needs a token", http.MethodPost, taskPath + "/timer/start", "", "", http.StatusUnauthorized},
+        {"my timer", http.MethodGet, "/api/v1/me/timer", "", token, http.StatusOK},
+        {"stop timer", http.MethodPost, taskPath + "/timer/stop", "", token, http.StatusOK},
+        {"stop stopped timer", http.MethodPost, taskPath + "/timer/stop", "", token, http.StatusNotFound},
+        {"entry without duration", http.MethodPost, taskPath + "/time-entries", `{"start":"2026-01-05T09:00:00Z"}`, token, http.StatusBadRequest},
+        {"list time entries", http.MethodGet, taskPath + "/time-entries", "", "", http.StatusOK},
+        {"time report", http.MethodGet, "/api/v1/time-report?from=2026-01-01&to=2026-01-31", "", "", http.StatusOK},
+        {"delete others' time entry", http.MethodDelete, entryPath, "", bob["token"].(string), http.StatusForbidden},
+        {"delete time entry", http.MethodDelete, entryPath, "", token, http.StatusNoContent},
+        {"list workspaces", http.MethodGet, "/api/v1/workspaces", "", token, http.StatusOK},
+        {"workspaces need a token", http.MethodGet, "/api/v1/workspaces", "", "", http.StatusUnauthorized},
+        {"get workspace", http.MethodGet, wsPath, "", token, http.StatusOK},
+        {"workspace of others", http.MethodGet, wsPath, "", bob["token"].(string), http.StatusNotFound},
+        {"update workspace", http.MethodPut, wsPath, `{"name":"ops","settings":{"allowed_statuses":["pending","completed"]}}`, token, http.StatusOK},
+        {"statuses without pending", http.MethodPut, wsPath, `{"name":"ops","settings":{"allowed_statuses":["completed"]}}`, token, http.StatusBadRequest},
+        {"add member", http.MethodPost, wsPath + "/members", `{"user_id":"` + bobID + `","role":"member"}`, token, http.StatusOK},
+        {"members cannot update", http.MethodPut, wsPath, `{"name":"mine"}`, bob["token"].(string), http.StatusForbidden},
+        {"remove last owner", http.MethodDelete, wsPath + "/members/" + userID, "", token, http.StatusConflict},
+        {"leave workspace", http.MethodDelete, wsPath + "/members/" + bobID, "", bob["token"].(string), http.StatusOK},
+        {"liveness", http.MethodGet, "/healthz", "", "", http.StatusOK},
+        {"readiness", http.MethodGet, "/readyz", "", "", http.StatusServiceUnavailable},
+        {"spec", http.MethodGet, "/openapi.json", "", "", http.StatusOK},
+        {"graphql", http.MethodPost, "/graphql", `{"query":"{ tasks(limit: 5) { total items { id name owner { username } } } }"}`, "", http.StatusOK},
+        {"graphql get", http.MethodGet, "/graphql?query=%7B%20me%20%7B%20id%20%7D%20%7D", "", token, http.StatusOK},
+        {"graphql get mutation", http.MethodGet, "/graphql?query=mutation%20%7B%20deleteTask(id%3A%20%22x%22)%20%7D", "", token, http.StatusMethodNotAllowed},
+        {"graphql without query", http.MethodPost, "/graphql", `{"variables":{}}`, "", http.StatusBadRequest},
+        {"unknown status", http.MethodPut, taskPath, `{"status":"Done"}`, "", http.StatusBadRequest},
+        {"bad id", http.MethodGet, "/api/v1/tasks/42", "", "", http.StatusBadRequest},
+        {"missing name", http.MethodPost, "/api/v1/tasks", `{"priority":"low"}`, "", http.StatusBadRequest},
+        {"wrong type", http.MethodPost, "/api/v1/tasks", `{"name":1,"priority":"low"}`, "", http.StatusBadRequest},
+        {"delete task", http.MethodDelete, taskPath, "", "", http.StatusNoContent},
+    }
+    for _, tc := range testcases {
+        t.Run(tc.name, func(t *testing.T) {
+            code, body := send(tc.method, tc.path, tc.body, tc.token)
+            if code != tc.want {
+                t.Errorf("got %d, want %d: %v", code, tc.want, body)
+            }
+        })
+    }
+}
+
+func TestConditionalGet(t *testing.T) {
+    r := newRouter()
+    get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, path, nil)
+        for k, v := range header {
+            req.Header.Set(k, v)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"name":"cache me","priority":"low"}`))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    var task map[string]any
+    json.Unmarshal(w.Body.Bytes(), &task)
+    path := "/api/v1/tasks/" + task["id"].(string)
+
+    first := get(path, nil)
+    etag, modified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+    if first.Code != http.StatusOK || etag == "" || modified == "" {
+        t.Fatalf("GET = %d with ETag %q, Last-Modified %q", first.Code, etag, modified)
+    }
+    testcases := []struct {
+        cases  string
+        path   string
+        header map[string]string
+        want   int
+    }{
+        {"matching etag", path, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+        {"one of several etags", path, map[string]string{"If-None-Match": `"old", W/` + etag}, http.StatusNotModified},
+        {"other etag", path, map[string]string{"If-None-Match": `"old"`}, http.StatusOK},
+        {"not modified since", path, map[string]string{"If-Modified-Since": modified}, http.StatusNotModified},
+        {"etag wins over date", path, map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": modified}, http.StatusOK},
+        {"legacy alias", strings.TrimPrefix(path, "/api/v1"), map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+    }
+    for _, tc := range testcases {
+        if w := get(tc.path, tc.header); w.Code != tc.want {
+            t.Errorf("%s: got %d, want %d", tc.cases, w.Code, tc.want)
+        }
+    }
+
+    // An update changes the ETag, even though the task was cached.
+    time.Sleep(time.Millisecond)
+    req = httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"status":"inprogress"}`))
+    req.Header.Set("Content-Type", "application/json")
+    r.ServeHTTP(httptest.NewRecorder(), req)
+    if w := get(path, map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "inprogress") {
+        t.Errorf("GET after update = %d %s; want the updated task", w.Code, w.Body)
+    }
+}
+
+func TestDueDateZones(t *testing.T) {
+    r := newRouter()
+    send := func(method, path, body, token string) map[string]any {
+        t.Helper()
+        req := httptest.NewRequest(method, path, strings.NewReader(body))
+        if body != "" {
+            req.Header.Set("Content-Type", "application/json")
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        if w.Code >= 300 {
+            t.Fatalf("%s %s = %d %s", method, path, w.Code, w.Body)
+        }
+        var m map[string]any
+        json.Unmarshal(w.Body.Bytes(), &m)
+        return m
+    }
+
+    req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"username":"kei","time_zone":"Asia/Tokyo"}`))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    var created map[string]any
+    json.Unmarshal(w.Body.Bytes(), &created)
+    token, _ := created["token"].(string)
+    if w.Code != http.StatusCreated || token == "" {
+        t.Fatalf("create user: got %d %s", w.Code, w.Body)
+    }
+
+    task := send(http.MethodPost, "/api/v1/tasks", `{"name":"call home","priority":"low","due_date":"tomorrow 9am"}`, token)
+    path := "/api/v1/tasks/" + task["id"].(string)
+    due, _ := time.Parse(time.RFC3339, task["due_date"].(string))
+    if due.Location() != time.UTC || due.Hour() != 0 || due.Minute() != 0 {
+        t.Errorf("due_date = %v; want 9am in Tokyo, stored and rendered as 00:00 UTC", task["due_date"])
+    }
+    if got := send(http.MethodGet, path+"?tz=me", "", token)["due_date"]; !strings.HasSuffix(got.(string), "T09:00:00+09:00") {
+        t.Errorf("due_date with tz=me = %v; want 09:00 in Tokyo", got)
+    }
+
+    send(http.MethodPut, "/api/v1/me", `{"time_zone":"America/New_York"}`, token)
+    updated := send(http.MethodPut, path, `{"due_date":"tomorrow 11pm"}`, token)
+    if got := send(http.MethodGet, path+"?tz=me", "", token)["due_date"]; !strings.Contains(got.(string), "T23:00:00-0") {
+        t.Errorf("due_date after changing zones = %v (stored %v); want 23:00 in New York", got, updated["due_date"])
+    }
+}
+
+func TestWorkspaceHeader(t *testing.T) {
+    r := newRouter()
+    send := func(method, path, body, token, workspace string) (int, map[string]any) {
+        t.Helper()
+        req := httptest.NewRequest(method, path, strings.NewReader(body))
+        if body != "" {
+            req.Header.Set("Content-Type", "application/json")
+        }
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        if workspace != "" {
+            req.Header.Set(controllers.WorkspaceHeader, workspace)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        var m map[string]any
+        json.Unmarshal(w.Body.Bytes(), &m)
+        return w.Code, m
+    }
+
+    _, ann := send(http.MethodPost, "/api/v1/users", `{"username":"ann"}`, "", "")
+    _, bob := send(http.MethodPost, "/api/v1/users", `{"username":"bob"}`, "", "")
+    token := ann["token"].(string)
+    _, ws := send(http.MethodPost, "/api/v1/workspaces", `{"name":"ops","settings":{"default_priority":"low"}}`, token, "")
+    wsID := ws["id"].(string)
+    code, task := send(http.MethodPost, "/api/v1/tasks", `{"name":"rotate keys"}`, token, wsID)
+    if code != http.StatusCreated {
+        t.Fatalf("create task in workspace: got %d %v", code, task)
+    }
+    if _, ok := task["workspace_id"]; task["priority"] != "low" || ok {
+        t.Errorf("created task = %v; want the workspace's default priority and no workspace_id", task)
+    }
+    taskPath := "/api/v1/tasks/" + task["id"].(string)
+
+    testcases := []struct {
+        name      string
+        token     string
+        workspace string
+        want      int
+    }{
+        {"in its workspace", token, wsID, http.StatusOK},
+        {"in the default workspace", token, "", http.StatusNotFound},
+        {"without a token", "", wsID, http.StatusUnauthorized},
+        {"by a non-member", bob["token"].(string), wsID, http.StatusNotFound},
+        {"in an unknown workspace", token, bob["user"].(map[string]any)["id"].(string), http.StatusNotFound},
+    }
+    for _, tc := range testcases {
+        t.Run(tc.name, func(t *testing.T) {
+            if code, body := send(http.MethodGet, taskPath, "", tc.token, tc.workspace); code != tc.want {
+                t.Errorf("got %d, want %d: %v", code, tc.want, body)
+            }
+        })
+    }
+}
+
+// TestMadeUpAPIKeys checks that an anonymous client cannot get a fresh
+// bucket by sending a different X-API-Key with every request.
+func TestMadeUpAPIKeys(t *testing.T) {
+    cfg := testConfig()
+    cfg.RouteLimits = map[string]config.Limit{"GET /tasks": {Requests: 1, Per: time.Hour}}
+    r := routerFor(cfg)
+    for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+        req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+        req.RemoteAddr = "192.0.2.1:1234"
+        req.Header.Set("X-API-Key", fmt.Sprintf("made-up-%d", i))
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        if w.Code != want {
+            t.Errorf("request %d: got %d, want %d: %s", i, w.Code, want, w.Body)
+        }
+    }
+}
+
+func newRouter() *gin.Engine {
+    return routerFor(testConfig())
+}
+
+func testConfig() config.Config {
+    cfg := config.Default()
+    cfg.Admins = []string{"ann"}
+    return cfg
+}
+
+func routerFor(cfg config.Config) *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    repo := data.NewRepo(nil, "", true)
+    // The cache normally only fronts MongoDB; here it runs every request
+    // through it.
+    tasks := service.NewTasks(data.NewCachedRepo(repo, 100, time.Minute), data.NewUserRepo(nil, "", true), nil)
+    handler := controllers.SetHandler(tasks)
+    handler.Escalations = service.NewEscalations(data.NewEscalationRepo(nil, "", true), tasks)
+    handler.Time = service.NewTimeTracking(data.NewTimeRepo(nil, "", true), tasks)
+    tasks.Workspaces = data.NewWorkspaceRepo(nil, "", true)
+    handler.Workspaces = service.NewWorkspaces(tasks.Workspaces, tasks.Users)
+    limits := middleware.NewLimits(cfg.RateLimit, cfg.RouteLimits, cfg.TaskQuota)
+    return router.NewRouter(handler, controllers.NewHealth(repo, time.Second), cfg, limits)
+}
@@ -0,0 +1,128 @@
+// Package rank orders items by strings, so that an item can be moved between
+// two others by giving it a new rank without touching any other item.
+//
+// Ranks use the digits 0-9 and a-z, compare as plain strings and never end
+// in "0", which guarantees that there is always another rank between two
+// different ones. Repeated insertions at one spot make ranks longer; Spread
+// replaces a whole list with short, evenly spaced ones.
+package rank
+
+import (
+    "errors"
+    "strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxLength is the length past which a list should be spread again.
+const MaxLength = 12
+
+var ErrNoRoom = errors.New("rank: no rank between equal or unordered ranks")
+
+// Valid reports whether r is a rank.
+func Valid(r string) bool {
+    if r == "" || r[len(r)-1] == '0' {
+        return false
+    }
+    for i := 0; i < len(r); i++ {
+        if strings.IndexByte(digits, r[i]) < 0 {
+            return false
+        }
+    }
+    return true
+}
+
+// Between returns a rank after a and before b. An empty a means the start of
+// the list and an empty b its end.
+func Between(a, b string) (string, error) {
+    if (a != "" && !Valid(a)) || (b != "" && !Valid(b)) || (a != "" && b != "" && a >= b) {
+        return "", ErrNoRoom
+    }
+    if a != "" && b == "" {
+        return After(a), nil
+    }
+    return midpoint(a, b), nil
+}
+
+// After returns a short rank after a, for appending to a list: a with its
+// first digit below "z" raised by one and the rest dropped.
+func After(a string) string {
+    for i := 0; i < len(a); i++ {
+        if d := strings.IndexByte(digits, a[i]); d < len(digits)-1 {
+            return a[:i] + digits[d+1:d+2]
+        }
+    }
+    return a + "i"
+}
+
+// midpoint returns a rank roughly halfway between a and b, where b == ""
+// stands for the end of the list.
+func midpoint(a, b string) string {
+    if b != "" {
+        // Keep the common prefix, reading a missing digit of a as "0".
+        n := 0
+        for n < len(b) && digitAt(a, n) == b[n] {
+            n++
+        }
+        if n > 0 {
+            return b[:n] + midpoint(suffix(a, n), b[n:])
+        }
+    }
+    da := 0
+    if a != "" {
+        da = strings.IndexByte(digits, a[0])
+    }
+    db := len(digits)
+    if b != "" {
+        db = strings.IndexByte(digits, b[0])
+    }
+    if db-da > 1 {
+        mid := (da + db + 1) / 2
+        return digits[mid : mid+1]
+    }
+    // The first digits are adjacent.
+    if len(b) > 1 {
+        return b[:1]
+    }
+    return digits[da:da+1] + midpoint(suffix(a, 1), "")
+}
+
+func digitAt(s string, i int) byte {
+    if i < len(s) {
+        return s[i]
+    }
+    return '0'
+}
+
+func suffix(s string, n int) string {
+    if n >= len(s) {
+        return ""
+    }
+    return s[n:]
+}
+
+// Spread returns n ascending ranks, evenly spaced and all of the shortest
+// length that leaves room between neighbours.
+func Spread(n int) []string {
+    length, space := 1, int64(len(digits))
+    for space/int64(n+1) < int64(len(digits)) {
+        length++
+        space *= int64(len(digits))
+    }
+    step := space / int64(n+1)
+    ranks := make([]string, n)
+    for i := range ranks {
+        ranks[i] = format(int64(i+1)*step, length)
+    }
+    return ranks
+}
+
+// format writes v in base 36 with length digits, without trailing zeros.
+func format(v int64, length int) string {
+    b := make([]byte, length)
+    for i := length - 1; i >= 0; i-- {
+        b[i] = digits[v%int64(len(digits))]
+        v /= int64(len(digits))
+    }
+    return strings.TrimRight(string(b), "0")
+}
@@ -0,0 +1,70 @@
+package rank
+
+import (
+    "math/rand"
+    "sort"
+    "testing"
+)
+
+func TestBetween(t *testing.T) {
+    testcases := []struct {
+        a, b string
+        want string
+    }{
+        {"", "", "i"},
+        {"i", "", "j"},
+        {"z", "", "zi"},
+        {"", "1", "0i"},
+        {"a", "c", "b"},
+        {"a", "b", "ai"},
+        {"a", "a1", "a0i"},
+        {"az", "b", "azi"},
+    }
+    for _, tc := range testcases {
+        got, err := Between(tc.a, tc.b)
+        if err != nil || got != tc.want {
+            t.Errorf("Between(%q, %q) = %q, %v; want %q", tc.a, tc.b, got, err, tc.want)
+        }
+    }
+    for _, bad := range [][2]string{{"b", "a"}, {"a", "a"}, {"a0", ""}, {"A", ""}} {
+        if _, err := Between(bad[0], bad[1]); err != ErrNoRoom {
+            t.Errorf("Between(%q, %q) error = %v; want ErrNoRoom", bad[0], bad[1], err)
+        }
+    }
+}
+
+// TestRandomInserts keeps a list sorted while inserting at random spots, as
+// a board does when cards are dragged around.
+func TestRandomInserts(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    list := []string{}
+    for i := 0; i < 2000; i++ {
+        at := rng.Intn(len(list) + 1)
+        var a, b string
+        if at > 0 {
+            a = list[at-1]
+        }
+        if at < len(list) {
+            b = list[at]
+        }
+        r, err := Between(a, b)
+        if err != nil || !Valid(r) || (a != "" && r <= a) || (b != "" && r >= b) {
+            t.Fatalf("Between(%q, %q) = %q, %v", a, b, r, err)
+        }
+        list = append(list[:at], append([]string{r}, list[at:]...)...)
+    }
+}
+
+func TestSpread(t *testing.T) {
+    for _, n := range []int{1, 35, 36, 1000} {
+        ranks := Spread(n)
+        if len(ranks) != n || !sort.StringsAreSorted(ranks) {
+            t.Fatalf("Spread(%d) is not %d sorted ranks", n, n)
+        }
+        for i, r := range ranks {
+            if !Valid(r) || len(r) > 3 || (i > 0 && r == ranks[i-1]) {
+                t.Fatalf("Spread(%d)[%d] = %q", n, i, r)
+            }
+        }
+    }
+}
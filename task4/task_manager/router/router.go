@@ -1,21 +1,171 @@
 package router
 
 import (
+    "log/slog"
+    "task_manager/config"
     "task_manager/controllers"
+    "task_manager/graphqlapi"
+    "task_manager/metrics"
+    "task_manager/middleware"
+    "task_manager/models"
+    "task_manager/openapi"
+    "task_manager/tracing"
+    "time"
+
     "github.com/gin-gonic/gin"
+    "go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func NewRouter(handler *controllers.Handler) *gin.Engine {
-    router := gin.Default()
+// NewRouter serves the API with handler. limits holds the clients' rate
+// limits and quotas, which the gRPC server shares.
+func NewRouter(handler *controllers.Handler, health *controllers.Health, cfg config.Config, limits *middleware.Limits) *gin.Engine {
+    router := gin.New()
     router.RedirectTrailingSlash = false
+    router.Use(
+        middleware.RequestID(),
+        middleware.Logger(slog.Default()),
+        middleware.Recovery(slog.Default()),
+        metrics.Middleware(),
+        otelgin.Middleware(tracing.ServiceName),
+        // Problems writes error responses after the handler chain returns,
+        // so middleware that reads the final status must come before it.
+        middleware.Problems(),
+        // Before anything reads the body.
+        middleware.BodyLimit(int64(cfg.MaxBodyBytes)),
+        openapi.Validator(gin.Mode() == gin.TestMode),
+    )
+
+    router.NoRoute(func(c *gin.Context) {
+        c.Error(models.NewError(models.ErrNotFound, "route not found"))
+    })
+
+    router.GET("/metrics", gin.WrapH(metrics.Handler()))
+    router.GET("/healthz", health.Live)
+    router.GET("/readyz", health.Ready)
+    router.GET("/openapi.json", openapi.Handler)
+
+    router.Use(
+        handler.Identify,
+        handler.Workspace,
+        handler.TimeZone,
+        middleware.RateLimit(limits, controllers.ClientKey),
+        // After the rate limit, so retries count, but before the daily quota,
+        // so replays do not.
+        middleware.Idempotency(cfg.IdempotencyWindow, controllers.WorkspaceKey),
+    )
+
+    quota := middleware.DailyQuota(limits, controllers.ClientKey)
+
+    v1 := handler.Version(controllers.V1{})
+    api := router.Group("/api/v1")
+    routes(api, v1, quota)
+    // Escalations, time tracking, the board, workspaces and the profile came
+    // after the unversioned paths and have no aliases.
+    escalationRoutes(api, v1, controllers.RequireAdmin(cfg.Admins))
+    timeRoutes(api, v1)
+    boardRoutes(api, v1)
+    workspaceRoutes(api, v1)
+    profileRoutes(api, v1)
+
+    // The unversioned paths predate /api/v1 and are kept as aliases until
+    // legacySunset.
+    routes(router.Group("", middleware.Deprecated(legacyDeprecated, legacySunset, "/api/v1")), v1, quota)
+
+    graphql := graphqlapi.Handler(handler.Tasks, limits, controllers.ClientKey)
+    router.GET("/graphql", graphql)
+    router.POST("/graphql", graphql)
+    return router
+}
+
+var (
+    legacyDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+    legacySunset     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
 
-    tasks := router.Group("/tasks")
+// routes registers the API resources on g, rendered by handler's presenter.
+func routes(g *gin.RouterGroup, handler *controllers.Handler, quota gin.HandlerFunc) {
+    tasks := g.Group("/tasks")
     {
         tasks.GET("", handler.GetAll)
+        tasks.GET("/stats", handler.Stats)
         tasks.GET("/:id", handler.GetById)
-        tasks.POST("", handler.Create)
+        tasks.POST("", quota, handler.Create)
         tasks.PUT("/:id", handler.Update)
         tasks.DELETE("/:id", handler.Delete)
+        tasks.POST("/:id/assignees", handler.Assign)
+        tasks.DELETE("/:id/assignees/:userId", handler.Unassign)
+        tasks.POST("/:id/watchers", handler.Watch)
+        tasks.DELETE("/:id/watchers/:userId", handler.Unwatch)
     }
-    return router
-}
\ No newline at end of file
+
+    users := g.Group("/users")
+    {
+        users.GET("", handler.GetUsers)
+        users.GET("/:id", handler.GetUserById)
+        users.POST("", handler.CreateUser)
+    }
+
+    me := g.Group("/me", handler.RequireUser)
+    {
+        me.GET("/tasks", handler.MyTasks)
+    }
+}
+
+// escalationRoutes registers the escalation rules, which only admins may
+// see or change, and the record of the changes they made.
+func escalationRoutes(g *gin.RouterGroup, handler *controllers.Handler, admin gin.HandlerFunc) {
+    rules := g.Group("/escalation-rules", admin)
+    {
+        rules.GET("", handler.GetRules)
+        rules.POST("", handler.CreateRule)
+        rules.DELETE("/:id", handler.DeleteRule)
+    }
+
+    escalations := g.Group("/escalations")
+    {
+        escalations.GET("", handler.GetEscalations)
+        escalations.POST("/:id/revert", admin, handler.RevertEscalation)
+    }
+}
+
+// timeRoutes registers time tracking. Writes are made as the caller, so they
+// need a token.
+func timeRoutes(g *gin.RouterGroup, handler *controllers.Handler) {
+    task := g.Group("/tasks/:id")
+    {
+        task.POST("/timer/start", handler.RequireUser, handler.StartTimer)
+        task.POST("/timer/stop", handler.RequireUser, handler.StopTimer)
+        task.GET("/time-entries", handler.GetTime)
+        task.POST("/time-entries", handler.RequireUser, handler.AddTime)
+        task.DELETE("/time-entries/:entryId", handler.RequireUser, handler.DeleteTime)
+    }
+    g.GET("/me/timer", handler.RequireUser, handler.MyTimer)
+    g.GET("/time-report", handler.TimeReport)
+}
+
+// boardRoutes registers the Kanban board and moves between and within its
+// columns.
+func boardRoutes(g *gin.RouterGroup, handler *controllers.Handler) {
+    g.GET("/board", handler.GetBoard)
+    g.POST("/tasks/:id/move", handler.MoveTask)
+}
+
+// workspaceRoutes registers workspaces and their members. Every route acts
+// as the caller, so all of them need a token.
+func workspaceRoutes(g *gin.RouterGroup, handler *controllers.Handler) {
+    workspaces := g.Group("/workspaces", handler.RequireUser)
+    {
+        workspaces.GET("", handler.GetWorkspaces)
+        workspaces.POST("", handler.CreateWorkspace)
+        workspaces.GET("/:id", handler.GetWorkspace)
+        workspaces.PUT("/:id", handler.UpdateWorkspace)
+        workspaces.POST("/:id/members", handler.SetMember)
+        workspaces.DELETE("/:id/members/:userId", handler.RemoveMember)
+    }
+}
+
+// profileRoutes registers the caller's own user and preferences.
+func profileRoutes(g *gin.RouterGroup, handler *controllers.Handler) {
+    g.GET("/me", handler.RequireUser, handler.GetMe)
+    g.PUT("/me", handler.RequireUser, handler.UpdateMe)
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "task_manager/data"
+    "task_manager/models"
+    "task_manager/rank"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// boardColumns lists the statuses in board order.
+var boardColumns = []models.State{models.Pending, models.InProgress, models.Completed}
+
+// Placement says where Move puts a task: in the Status column, right after
+// the task After and/or right before the task Before. Without either it goes
+// to the end of the column.
+type Placement struct {
+    Status models.State
+    After  *uuid.UUID
+    Before *uuid.UUID
+}
+
+// Board returns the status columns the workspace allows, in order. Tasks
+// left in a status the workspace no longer allows are not shown.
+func (s *Tasks) Board(ctx context.Context) (map[models.State][]models.Task, error) {
+    board := map[models.State][]models.Task{}
+    settings := data.Workspace(ctx).Settings
+    for _, status := range boardColumns {
+        if !settings.Allows(status) {
+            continue
+        }
+        col, err := s.Repo.Column(ctx, status)
+        if err != nil {
+            return nil, err
+        }
+        board[status] = col
+    }
+    return board, nil
+}
+
+// Move places the task with id as p says. Only the moved task is written,
+// unless a neighbour is unranked or shares its rank with the other, in which
+// case the column is rebalanced first.
+func (s *Tasks) Move(ctx context.Context, id string, p Placement) (*models.Task, error) {
+    task, err := s.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if p.Status == "" {
+        p.Status = task.Status
+    }
+    v := &models.ValidationError{}
+    if !models.ValidStates[p.Status] {
+        v.Add("status", "must be one of pending, inprogress, completed")
+    } else if !data.Workspace(ctx).Settings.Allows(p.Status) {
+        v.Add("status", "is not allowed in this workspace")
+    }
+    if p.After != nil && *p.After == task.ID {
+        v.Add("after", "must be another task")
+    }
+    if p.Before != nil && *p.Before == task.ID {
+        v.Add("before", "must be another task")
+    }
+    if err := v.Err(); err != nil {
+        return nil, err
+    }
+
+    var r string
+    for attempt := 0; ; attempt++ {
+        col, err := s.Repo.Column(ctx, p.Status)
+        if err != nil {
+            return nil, err
+        }
+        r, err = place(col, task.ID, p)
+        if errors.Is(err, rank.ErrNoRoom) && attempt == 0 {
+            if _, err := s.Rebalance(ctx, p.Status); err != nil {
+                return nil, err
+            }
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        break
+    }
+    changes := models.Task{Status: p.Status, Rank: r}
+    changes.UpdatedAt = time.Now()
+    if err := s.Repo.Update(ctx, id, changes); err != nil {
+        return nil, err
+    }
+    return s.changed(ctx, id)
+}
+
+// place returns the rank for the task self put into col as p says.
+func place(col []models.Task, self uuid.UUID, p Placement) (string, error) {
+    others := make([]models.Task, 0, len(col))
+    for _, t := range col {
+        if t.ID != self {
+            others = append(others, t)
+        }
+    }
+    index := func(field string, id uuid.UUID) (int, error) {
+        for i, t := range others {
+            if t.ID == id {
+                return i, nil
+            }
+        }
+        return 0, models.NewValidationError(field, "must be a task in the "+string(p.Status)+" column")
+    }
+    // The task goes between others[lo] and others[hi]; either may be off
+    // the end of the column.
+    lo, hi := len(others)-1, len(others)
+    if p.After != nil {
+        i, err := index("after", *p.After)
+        if err != nil {
+            return "", err
+        }
+        lo, hi = i, i+1
+    }
+    if p.Before != nil {
+        i, err := index("before", *p.Before)
+        if err != nil {
+            return "", err
+        }
+        if p.After != nil && i != hi {
+            return "", models.NewError(models.ErrConflict, "after and before are no longer neighbours; reload the board")
+        }
+        lo, hi = i-1, i
+    }
+    var a, b string
+    if lo >= 0 {
+        if a = others[lo].Rank; a == "" {
+            return "", rank.ErrNoRoom
+        }
+    }
+    if hi < len(others) {
+        if b = others[hi].Rank; b == "" {
+            return "", rank.ErrNoRoom
+        }
+    }
+    return rank.Between(a, b)
+}
+
+// Rebalance gives the status column short, evenly spaced ranks in its
+// current order if any rank is missing, shared or longer than
+// rank.MaxLength. It returns how many tasks it changed.
+func (s *Tasks) Rebalance(ctx context.Context, status models.State) (int, error) {
+    col, err := s.Repo.Column(ctx, status)
+    if err != nil {
+        return 0, err
+    }
+    needed := false
+    for i, t := range col {
+        if t.Rank == "" || len(t.Rank) > rank.MaxLength || (i > 0 && t.Rank == col[i-1].Rank) {
+            needed = true
+            break
+        }
+    }
+    if !needed {
+        return 0, nil
+    }
+    var changes []data.RankChange
+    for i, r := range rank.Spread(len(col)) {
+        if col[i].Rank != r {
+            changes = append(changes, data.RankChange{ID: col[i].ID, Old: col[i].Rank, New: r})
+        }
+    }
+    return s.Repo.SetRanks(ctx, changes, time.Now())
+}
+
+// RunRebalancer rebalances every column of every workspace each interval
+// until ctx is cancelled.
+func (s *Tasks) RunRebalancer(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            err := s.eachWorkspace(ctx, func(ctx context.Context) error {
+                ws := data.Workspace(ctx).ID.String()
+                for _, status := range boardColumns {
+                    n, err := s.Rebalance(ctx, status)
+                    if err != nil {
+                        if ctx.Err() == nil {
+                            slog.ErrorContext(ctx, "Rebalancing task ranks", "workspace_id", ws, "status", status, "error", err)
+                        }
+                        continue
+                    }
+                    if n > 0 {
+                        slog.InfoContext(ctx, "Rebalanced task ranks", "workspace_id", ws, "status", status, "tasks", n)
+                    }
+                }
+                return nil
+            })
+            if err != nil && ctx.Err() == nil {
+                slog.ErrorContext(ctx, "Listing workspaces", "error", err)
+            }
+        }
+    }
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "task_manager/data"
+    "task_manager/models"
+    "task_manager/rank"
+    "testing"
+
+    "github.com/google/uuid"
+)
+
+func TestMove(t *testing.T) {
+    ctx := context.Background()
+    s := NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), nil)
+    var ids []uuid.UUID
+    for _, name := range []string{"a", "b", "c"} {
+        task, err := s.Create(ctx, models.Task{Name: name, Priority: models.Low})
+        if err != nil {
+            t.Fatal(err)
+        }
+        ids = append(ids, task.ID)
+    }
+    order := func(status models.State) string {
+        t.Helper()
+        col, err := s.Repo.Column(ctx, status)
+        if err != nil {
+            t.Fatal(err)
+        }
+        names := ""
+        for _, task := range col {
+            names += task.Name
+        }
+        return names
+    }
+    if got := order(models.Pending); got != "abc" {
+        t.Fatalf("created order = %q; want abc", got)
+    }
+
+    if _, err := s.Move(ctx, ids[2].String(), Placement{After: &ids[0], Before: &ids[1]}); err != nil {
+        t.Fatal(err)
+    }
+    if got := order(models.Pending); got != "acb" {
+        t.Errorf("order after move = %q; want acb", got)
+    }
+    if _, err := s.Move(ctx, ids[0].String(), Placement{After: &ids[2], Before: &ids[1]}); err != nil {
+        t.Fatal(err)
+    }
+    if got := order(models.Pending); got != "cab" {
+        t.Errorf("order after second move = %q; want cab", got)
+    }
+    if _, err := s.Move(ctx, ids[1].String(), Placement{After: &ids[0], Before: &ids[2]}); !errors.Is(err, models.ErrConflict) {
+        t.Errorf("move between non-neighbours error = %v; want a conflict", err)
+    }
+
+    if _, err := s.Move(ctx, ids[1].String(), Placement{Status: models.InProgress}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Move(ctx, ids[2].String(), Placement{Status: models.InProgress, Before: &ids[1]}); err != nil {
+        t.Fatal(err)
+    }
+    if got := order(models.InProgress); got != "cb" {
+        t.Errorf("in progress order = %q; want cb", got)
+    }
+    if _, err := s.Move(ctx, ids[0].String(), Placement{Status: models.InProgress, After: &ids[0]}); err == nil {
+        t.Error("moving a task next to itself succeeded")
+    }
+
+    // Moving into the same gap over and over grows the ranks until
+    // Rebalance spreads them again in the same order.
+    for i := 0; i < 50; i++ {
+        if _, err := s.Move(ctx, ids[0].String(), Placement{Status: models.InProgress, After: &ids[2], Before: &ids[1]}); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := s.Move(ctx, ids[2].String(), Placement{Status: models.InProgress, After: &ids[0], Before: &ids[1]}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if got := order(models.InProgress); got != "acb" {
+        t.Errorf("order after many moves = %q; want acb", got)
+    }
+    if n, err := s.Rebalance(ctx, models.InProgress); err != nil || n == 0 {
+        t.Fatalf("Rebalance = %d, %v; want the long ranks replaced", n, err)
+    }
+    if got := order(models.InProgress); got != "acb" {
+        t.Errorf("order after Rebalance = %q; want acb", got)
+    }
+    col, _ := s.Repo.Column(ctx, models.InProgress)
+    for _, task := range col {
+        if !rank.Valid(task.Rank) || len(task.Rank) > rank.MaxLength {
+            t.Errorf("rank %q of %s after Rebalance is not a valid, short rank", task.Rank, task.Name)
+        }
+    }
+    if n, err := s.Rebalance(ctx, models.InProgress); err != nil || n != 0 {
+        t.Errorf("second Rebalance = %d, %v; want nothing to do", n, err)
+    }
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// Escalations raises the priority of overdue tasks by the rules admins
+// define, and records each change so it can be reverted.
+type Escalations struct {
+    Store *data.EscalationRepo
+    Tasks *Tasks
+}
+
+func NewEscalations(store *data.EscalationRepo, tasks *Tasks) *Escalations {
+    return &Escalations{Store: store, Tasks: tasks}
+}
+
+// CreateRule stores a rule made by the caller in ctx. Only the
+// client-editable fields of rule are used.
+func (s *Escalations) CreateRule(ctx context.Context, rule models.EscalationRule) (*models.EscalationRule, error) {
+    rule.ID = uuid.New()
+    rule.CreatedAt = time.Now()
+    rule.CreatedBy = uuid.Nil
+    if user, ok := auth.User(ctx); ok {
+        rule.CreatedBy = user.ID
+    }
+    if err := rule.Validate(); err != nil {
+        return nil, err
+    }
+    if err := s.Store.CreateRule(ctx, &rule); err != nil {
+        return nil, err
+    }
+    return &rule, nil
+}
+
+func (s *Escalations) Rules(ctx context.Context) ([]models.EscalationRule, error) {
+    return s.Store.Rules(ctx)
+}
+
+func (s *Escalations) DeleteRule(ctx context.Context, id string) error {
+    u, err := parseID("id", id)
+    if err != nil {
+        return err
+    }
+    return s.Store.DeleteRule(ctx, u)
+}
+
+// List returns the escalations of the task taskID, or of every task when it
+// is empty, newest first.
+func (s *Escalations) List(ctx context.Context, taskID string) ([]models.Escalation, error) {
+    if taskID == "" {
+        return s.Store.Escalations(ctx, nil)
+    }
+    u, err := parseID("task_id", taskID)
+    if err != nil {
+        return nil, err
+    }
+    return s.Store.Escalations(ctx, &u)
+}
+
+// Revert restores the priority an escalation replaced. It fails with a
+// conflict if the task's priority has changed since, so a later decision is
+// never undone. The rule does not escalate the task again.
+func (s *Escalations) Revert(ctx context.Context, id string) (*models.Escalation, error) {
+    u, err := parseID("id", id)
+    if err != nil {
+        return nil, err
+    }
+    e, err := s.Store.GetEscalation(ctx, u)
+    if err != nil {
+        return nil, err
+    }
+    if e.RevertedAt != nil {
+        return nil, models.ErrAlreadyReverted
+    }
+    _, err = s.Tasks.SetPriority(ctx, e.TaskID, e.To, e.From)
+    if errors.Is(err, models.ErrPriorityChanged) {
+        return nil, models.NewError(models.ErrConflict, "task priority changed since the escalation")
+    }
+    if err != nil {
+        return nil, err
+    }
+    var by uuid.UUID
+    if user, ok := auth.User(ctx); ok {
+        by = user.ID
+    }
+    now := time.Now()
+    if err := s.Store.MarkReverted(ctx, e.ID, now, by); err != nil {
+        return nil, err
+    }
+    e.RevertedAt, e.RevertedBy = &now, &by
+    return e, nil
+}
+
+// Evaluate applies every rule to the tasks of the workspace of ctx that are
+// overdue at now and returns the escalations it made. A rule escalates each
+// task at most once, even after the change is reverted. It carries on past
+// failures and returns them joined.
+func (s *Escalations) Evaluate(ctx context.Context, now time.Time) ([]models.Escalation, error) {
+    rules, err := s.Store.Rules(ctx)
+    if err != nil {
+        return nil, err
+    }
+    var (
+        applied []models.Escalation
+        errs    []error
+    )
+    for _, rule := range rules {
+        done, err := s.Store.EscalatedTasks(ctx, rule.ID)
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        cutoff := now.Add(-time.Duration(rule.OverdueBy))
+        tasks, _, err := s.Tasks.Repo.Find(ctx, data.TaskFilter{Open: true, Priority: rule.Priority, DueBefore: &cutoff})
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        for _, task := range tasks {
+            if done[task.ID] {
+                continue
+            }
+            e, err := s.escalate(ctx, rule, task, now)
+            if errors.Is(err, models.ErrAlreadyEscalated) || errors.Is(err, models.ErrPriorityChanged) {
+                // Another instance got there first, or someone changed the
+                // priority since the task was found.
+                continue
+            }
+            if err != nil {
+                errs = append(errs, err)
+                continue
+            }
+            applied = append(applied, *e)
+        }
+    }
+    return applied, errors.Join(errs...)
+}
+
+// escalate records the change before making it, so that of several instances
+// evaluating the same rule only one changes the task. The change applies only
+// while the task still has the priority it was found with; otherwise the
+// record is dropped again and escalate fails with ErrPriorityChanged.
+func (s *Escalations) escalate(ctx context.Context, rule models.EscalationRule, task models.Task, now time.Time) (*models.Escalation, error) {
+    e := &models.Escalation{
+        ID:       uuid.New(),
+        RuleID:   rule.ID,
+        RuleName: rule.Name,
+        TaskID:   task.ID,
+        From:     task.Priority,
+        To:       rule.RaiseTo,
+        At:       now,
+    }
+    if err := s.Store.Record(ctx, e); err != nil {
+        return nil, err
+    }
+    if _, err := s.Tasks.SetPriority(ctx, task.ID, task.Priority, rule.RaiseTo); err != nil {
+        if forgetErr := s.Store.Forget(ctx, e.ID); forgetErr != nil {
+            return nil, errors.Join(err, forgetErr)
+        }
+        return nil, err
+    }
+    return e, nil
+}
+
+// Run evaluates the rules in every workspace each interval until ctx is
+// cancelled.
+func (s *Escalations) Run(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case now := <-ticker.C:
+            err := s.Tasks.eachWorkspace(ctx, func(ctx context.Context) error {
+                applied, err := s.Evaluate(ctx, now)
+                for _, e := range applied {
+                    slog.InfoContext(ctx, "Escalated task", "task_id", e.TaskID.String(), "rule", e.RuleName, "from", e.From, "to", e.To)
+                }
+                return err
+            })
+            if err != nil && ctx.Err() == nil {
+                slog.ErrorContext(ctx, "Evaluating escalation rules", "error", err)
+            }
+        }
+    }
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "testing"
+    "time"
+)
+
+func TestEscalations(t *testing.T) {
+    admin, _, _ := models.NewUser("ann", time.Now())
+    ctx := auth.WithUser(context.Background(), admin)
+    repo := data.NewRepo(nil, "", true)
+    s := NewEscalations(data.NewEscalationRepo(nil, "", true), NewTasks(repo, data.NewUserRepo(nil, "", true), nil))
+
+    now := time.Now()
+    add := func(status models.State, overdue time.Duration) *models.Task {
+        due := now.Add(-overdue)
+        task := models.NewTask("t", "", status, models.Low, &due, due.Add(-time.Hour))
+        if err := repo.Create(ctx, task); err != nil {
+            t.Fatal(err)
+        }
+        return task
+    }
+    late := add(models.Pending, 72*time.Hour)
+    add(models.Pending, 24*time.Hour)
+    add(models.Completed, 120*time.Hour)
+
+    for _, r := range []models.EscalationRule{
+        {Name: "low after 2d", Priority: models.Low, OverdueBy: models.Duration(48 * time.Hour), RaiseTo: models.Medium},
+        {Name: "medium after 1d", Priority: models.Medium, OverdueBy: models.Duration(24 * time.Hour), RaiseTo: models.High},
+    } {
+        if _, err := s.CreateRule(ctx, r); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // The rules run oldest first, so the late task climbs both steps at once.
+    applied, err := s.Evaluate(ctx, now)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(applied) != 2 || applied[0].TaskID != late.ID || applied[1].From != models.Medium || applied[1].To != models.High {
+        t.Fatalf("Evaluate = %+v; want the late task raised to medium, then high", applied)
+    }
+
+    reverted, err := s.Revert(ctx, applied[1].ID.String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if reverted.RevertedAt == nil || *reverted.RevertedBy != admin.ID {
+        t.Errorf("Revert = %+v; want it marked reverted by the caller", reverted)
+    }
+    if task, _ := repo.GetById(ctx, late.ID.String()); task.Priority != models.Medium {
+        t.Errorf("priority after revert = %s; want medium", task.Priority)
+    }
+    if _, err := s.Revert(ctx, applied[1].ID.String()); !errors.Is(err, models.ErrAlreadyReverted) {
+        t.Errorf("second Revert error = %v; want ErrAlreadyReverted", err)
+    }
+
+    // A reverted escalation is not made again.
+    if again, err := s.Evaluate(ctx, now.Add(time.Hour)); err != nil || len(again) != 0 {
+        t.Errorf("Evaluate after revert = %+v, %v; want nothing", again, err)
+    }
+
+    list, err := s.List(ctx, late.ID.String())
+    if err != nil || len(list) != 2 {
+        t.Fatalf("List = %+v, %v; want both escalations", list, err)
+    }
+
+    // Once someone else changes the priority, the first escalation stays.
+    if _, err := s.Tasks.Update(ctx, late.ID.String(), models.Task{Priority: models.High}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Revert(ctx, applied[0].ID.String()); !errors.Is(err, models.ErrConflict) {
+        t.Errorf("Revert after a manual change error = %v; want a conflict", err)
+    }
+}
+
+// TestEscalateStale checks that an escalation does not overwrite a priority
+// changed after the task was found, and leaves no record behind.
+func TestEscalateStale(t *testing.T) {
+    ctx := context.Background()
+    repo := data.NewRepo(nil, "", true)
+    s := NewEscalations(data.NewEscalationRepo(nil, "", true), NewTasks(repo, data.NewUserRepo(nil, "", true), nil))
+    now := time.Now()
+    due := now.Add(-72 * time.Hour)
+    found := models.NewTask("t", "", models.Pending, models.Low, &due, due.Add(-time.Hour))
+    if err := repo.Create(ctx, found); err != nil {
+        t.Fatal(err)
+    }
+    rule, err := s.CreateRule(ctx, models.EscalationRule{Name: "low after 2d", Priority: models.Low, OverdueBy: models.Duration(48 * time.Hour), RaiseTo: models.Medium})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := s.Tasks.Update(ctx, found.ID.String(), models.Task{Priority: models.High}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.escalate(ctx, *rule, *found, now); !errors.Is(err, models.ErrPriorityChanged) {
+        t.Errorf("escalate with a stale task error = %v; want ErrPriorityChanged", err)
+    }
+    if task, _ := repo.GetById(ctx, found.ID.String()); task.Priority != models.High {
+        t.Errorf("priority = %s; want the manual high", task.Priority)
+    }
+    if list, err := s.List(ctx, found.ID.String()); err != nil || len(list) != 0 {
+        t.Errorf("List = %+v, %v; want no escalations", list, err)
+    }
+}
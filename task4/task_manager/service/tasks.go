@@ -0,0 +1,337 @@
+// Package service holds the task operations shared by every API transport:
+// the REST handlers, the gRPC server and anything added later.
+package service
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/duedate"
+    "task_manager/events"
+    "task_manager/models"
+    "task_manager/rank"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+type Tasks struct {
+    Repo   data.TaskRepository
+    Users  *data.UserRepo
+    Events *events.Broker
+    // Workspaces lets background work reach every workspace; without it
+    // only the default workspace is visited.
+    Workspaces *data.WorkspaceRepo
+}
+
+func NewTasks(repo data.TaskRepository, users *data.UserRepo, broker *events.Broker) *Tasks {
+    return &Tasks{Repo: repo, Users: users, Events: broker}
+}
+
+// Create stores a new pending task owned by the caller in ctx, if any. Only
+// the client-editable fields of task are used; a task without a priority
+// gets the default priority of the workspace, if it has one.
+func (s *Tasks) Create(ctx context.Context, task models.Task) (*models.Task, error) {
+    now := time.Now()
+    task.ID = uuid.New()
+    task.CreatedAt = now
+    task.UpdatedAt = now
+    task.CompletedAt = nil
+    task.TrackedSeconds = 0
+    task.Status = models.Pending
+    task.OwnerID = nil
+    if user, ok := auth.User(ctx); ok {
+        task.OwnerID = &user.ID
+    }
+    if task.Priority == "" {
+        task.Priority = data.Workspace(ctx).Settings.DefaultPriority
+    }
+    if err := task.Validate(); err != nil {
+        return nil, err
+    }
+    if err := s.checkParticipants(ctx, &task); err != nil {
+        return nil, err
+    }
+    last, err := s.Repo.LastRank(ctx, task.Status)
+    if err != nil {
+        return nil, err
+    }
+    task.Rank = rank.After(last)
+    if err := s.Repo.Create(ctx, &task); err != nil {
+        return nil, err
+    }
+    s.publish(events.Created, task)
+    return &task, nil
+}
+
+// Update applies the non-empty fields of changes to the task with id. A task
+// that changes status goes to the end of its new column, if the workspace
+// allows that status.
+func (s *Tasks) Update(ctx context.Context, id string, changes models.Task) (*models.Task, error) {
+    u, err := parseID("id", id)
+    if err != nil {
+        return nil, err
+    }
+    changes.ID = u
+    changes.UpdatedAt = time.Now()
+    changes.OwnerID = nil
+    changes.Rank = ""
+    v := &models.ValidationError{}
+    if changes.Status != "" && !models.ValidStates[changes.Status] {
+        v.Add("status", "must be one of pending, inprogress, completed")
+    }
+    if changes.Priority != "" && !models.ValidPriorities[changes.Priority] {
+        v.Add("priority", "must be one of high, medium, low")
+    }
+    if changes.EstimateSeconds != nil && *changes.EstimateSeconds < 0 {
+        v.Add("estimate_seconds", "must not be negative")
+    }
+    if err := v.Err(); err != nil {
+        return nil, err
+    }
+    if err := checkStatus(ctx, changes.Status); err != nil {
+        return nil, err
+    }
+    if err := s.checkParticipants(ctx, &changes); err != nil {
+        return nil, err
+    }
+    if changes.Status != "" {
+        current, err := s.Repo.GetById(ctx, id)
+        if err != nil {
+            return nil, err
+        }
+        if current.Status != changes.Status {
+            last, err := s.Repo.LastRank(ctx, changes.Status)
+            if err != nil {
+                return nil, err
+            }
+            changes.Rank = rank.After(last)
+        }
+    }
+    if err := s.Repo.Update(ctx, id, changes); err != nil {
+        return nil, err
+    }
+    return s.changed(ctx, id)
+}
+
+func (s *Tasks) Delete(ctx context.Context, id string) error {
+    if _, err := parseID("id", id); err != nil {
+        return err
+    }
+    task, err := s.Repo.GetById(ctx, id)
+    if err != nil {
+        return err
+    }
+    if err := s.Repo.Delete(ctx, id); err != nil {
+        return err
+    }
+    s.publish(events.Deleted, *task)
+    return nil
+}
+
+func (s *Tasks) Get(ctx context.Context, id string) (*models.Task, error) {
+    if _, err := parseID("id", id); err != nil {
+        return nil, err
+    }
+    return s.Repo.GetById(ctx, id)
+}
+
+func (s *Tasks) List(ctx context.Context) ([]models.Task, error) {
+    return s.Repo.GetAll(ctx)
+}
+
+// ListAll lists the tasks of every workspace, for figures about the whole
+// deployment.
+func (s *Tasks) ListAll(ctx context.Context) ([]models.Task, error) {
+    var all []models.Task
+    err := s.eachWorkspace(ctx, func(ctx context.Context) error {
+        tasks, err := s.Repo.GetAll(ctx)
+        all = append(all, tasks...)
+        return err
+    })
+    return all, err
+}
+
+// DueDate reads a due date given as RFC 3339 or as an expression such as
+// "tomorrow 5pm", in the caller's time zone; see package duedate.
+func (s *Tasks) DueDate(ctx context.Context, expr string) (time.Time, error) {
+    loc := time.UTC
+    if user, ok := auth.User(ctx); ok {
+        loc = user.Location()
+    }
+    t, err := duedate.Parse(expr, time.Now(), loc)
+    if errors.Is(err, duedate.ErrAmount) {
+        return time.Time{}, models.NewValidationError("due_date", `must count at least one unit after "in", and no more than about 292 years`)
+    }
+    if err != nil {
+        return time.Time{}, models.NewValidationError("due_date", `must be RFC 3339 or an expression such as "tomorrow 5pm", "next friday" or "in 3 days"`)
+    }
+    return t, nil
+}
+
+// MaxPageSize bounds how many tasks Find returns at once.
+const MaxPageSize = 100
+
+// Find returns one page of the tasks matching f and the number of matches.
+func (s *Tasks) Find(ctx context.Context, f data.TaskFilter) ([]models.Task, int, error) {
+    v := &models.ValidationError{}
+    if f.Status != "" && !models.ValidStates[f.Status] {
+        v.Add("status", "must be one of pending, inprogress, completed")
+    }
+    if f.Priority != "" && !models.ValidPriorities[f.Priority] {
+        v.Add("priority", "must be one of high, medium, low")
+    }
+    if f.Offset < 0 {
+        v.Add("offset", "must not be negative")
+    }
+    if f.Limit < 1 || f.Limit > MaxPageSize {
+        v.Add("limit", fmt.Sprintf("must be between 1 and %d", MaxPageSize))
+    }
+    if err := v.Err(); err != nil {
+        return nil, 0, err
+    }
+    return s.Repo.Find(ctx, f)
+}
+
+// MaxStatsDays bounds the window of Stats and of TimeTracking.Report.
+const MaxStatsDays = 366
+
+// Stats summarises the tasks, with the time-based figures limited to the UTC
+// days from through to.
+func (s *Tasks) Stats(ctx context.Context, from, to time.Time) (*models.TaskStats, error) {
+    w := data.StatsWindow{From: from, To: to, Now: time.Now()}
+    if err := checkWindow(w); err != nil {
+        return nil, err
+    }
+    return s.Repo.Stats(ctx, w)
+}
+
+// checkWindow bounds a window to between one and MaxStatsDays days.
+func checkWindow(w data.StatsWindow) error {
+    days := int(w.To.Sub(w.From).Hours()/24) + 1
+    if days < 1 {
+        return models.NewValidationError("from", "must not be after to")
+    }
+    if days > MaxStatsDays {
+        return models.NewValidationError("from", fmt.Sprintf("must be at most %d days before to", MaxStatsDays))
+    }
+    return nil
+}
+
+// Assigned lists the tasks assigned to userID.
+func (s *Tasks) Assigned(ctx context.Context, userID uuid.UUID) ([]models.Task, error) {
+    return s.Repo.GetByAssignee(ctx, userID)
+}
+
+func (s *Tasks) AddAssignees(ctx context.Context, id string, userIDs []uuid.UUID) (*models.Task, error) {
+    return s.addParticipants(ctx, id, userIDs, s.Repo.AddAssignees)
+}
+
+func (s *Tasks) RemoveAssignee(ctx context.Context, id string, userID uuid.UUID) (*models.Task, error) {
+    return s.removeParticipant(ctx, id, userID, s.Repo.RemoveAssignee)
+}
+
+func (s *Tasks) AddWatchers(ctx context.Context, id string, userIDs []uuid.UUID) (*models.Task, error) {
+    return s.addParticipants(ctx, id, userIDs, s.Repo.AddWatchers)
+}
+
+func (s *Tasks) RemoveWatcher(ctx context.Context, id string, userID uuid.UUID) (*models.Task, error) {
+    return s.removeParticipant(ctx, id, userID, s.Repo.RemoveWatcher)
+}
+
+func (s *Tasks) addParticipants(ctx context.Context, id string, userIDs []uuid.UUID, add func(context.Context, string, []uuid.UUID, time.Time) error) (*models.Task, error) {
+    if _, err := parseID("id", id); err != nil {
+        return nil, err
+    }
+    if len(userIDs) == 0 {
+        return nil, models.NewValidationError("user_ids", "is required")
+    }
+    if err := s.checkUsers(ctx, "user_ids", userIDs); err != nil {
+        return nil, err
+    }
+    if err := add(ctx, id, userIDs, time.Now()); err != nil {
+        return nil, err
+    }
+    return s.changed(ctx, id)
+}
+
+func (s *Tasks) removeParticipant(ctx context.Context, id string, userID uuid.UUID, remove func(context.Context, string, uuid.UUID, time.Time) error) (*models.Task, error) {
+    if _, err := parseID("id", id); err != nil {
+        return nil, err
+    }
+    if err := remove(ctx, id, userID, time.Now()); err != nil {
+        return nil, err
+    }
+    return s.changed(ctx, id)
+}
+
+// SetPriority changes the priority of the task with id to to, provided it is
+// still from, and announces the change. Otherwise it fails with
+// ErrPriorityChanged.
+func (s *Tasks) SetPriority(ctx context.Context, id uuid.UUID, from, to models.Importance) (*models.Task, error) {
+    if err := s.Repo.SetPriority(ctx, id, from, to, time.Now()); err != nil {
+        return nil, err
+    }
+    return s.changed(ctx, id.String())
+}
+
+// changed reloads a task after a write and announces it.
+func (s *Tasks) changed(ctx context.Context, id string) (*models.Task, error) {
+    task, err := s.Repo.GetById(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    s.publish(events.Updated, *task)
+    return task, nil
+}
+
+func (s *Tasks) publish(t events.Type, task models.Task) {
+    if s.Events != nil {
+        s.Events.Publish(events.Event{Type: t, Task: task, At: time.Now()})
+    }
+}
+
+func (s *Tasks) checkParticipants(ctx context.Context, task *models.Task) error {
+    if err := s.checkUsers(ctx, "assignees", task.Assignees); err != nil {
+        return err
+    }
+    return s.checkUsers(ctx, "watchers", task.Watchers)
+}
+
+func (s *Tasks) checkUsers(ctx context.Context, field string, ids []uuid.UUID) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    missing, err := s.Users.Missing(ctx, ids)
+    if err != nil {
+        return err
+    }
+    if len(missing) > 0 {
+        unknown := make([]string, len(missing))
+        for i, id := range missing {
+            unknown[i] = id.String()
+        }
+        return models.NewValidationError(field, "unknown users: "+strings.Join(unknown, ", "))
+    }
+    return nil
+}
+
+// checkStatus rejects a status the workspace of ctx does not allow. Unknown
+// statuses are left to Validate and Update.
+func checkStatus(ctx context.Context, status models.State) error {
+    if !models.ValidStates[status] || data.Workspace(ctx).Settings.Allows(status) {
+        return nil
+    }
+    return models.NewValidationError("status", "is not allowed in this workspace")
+}
+
+func parseID(field, id string) (uuid.UUID, error) {
+    u, err := uuid.Parse(id)
+    if err != nil {
+        return uuid.Nil, models.NewValidationError(field, "must be a UUID")
+    }
+    return u, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "task_manager/data"
+    "task_manager/models"
+    "testing"
+)
+
+func TestUpdateRejectsUnknownValues(t *testing.T) {
+    ctx := context.Background()
+    s := NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), nil)
+    task, err := s.Create(ctx, models.Task{Name: "a", Priority: models.Low})
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, changes := range []models.Task{
+        {Status: "archived"},
+        {Priority: "urgent"},
+    } {
+        if _, err := s.Update(ctx, task.ID.String(), changes); !errors.Is(err, models.ErrValidation) {
+            t.Errorf("Update(%+v) = %v; want a validation error", changes, err)
+        }
+    }
+    got, err := s.Get(ctx, task.ID.String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Status != models.Pending || got.Priority != models.Low {
+        t.Errorf("task is %s/%s after rejected updates; want pending/low", got.Status, got.Priority)
+    }
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "sort"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// TimeTracking records the time users spend on tasks, by timer or by hand,
+// and keeps each task's tracked total.
+type TimeTracking struct {
+    Store *data.TimeRepo
+    Tasks *Tasks
+}
+
+func NewTimeTracking(store *data.TimeRepo, tasks *Tasks) *TimeTracking {
+    return &TimeTracking{Store: store, Tasks: tasks}
+}
+
+// Start runs a timer for the caller on the task with id. Each user has at
+// most one running timer; starting another is an ErrTimerRunning.
+func (s *TimeTracking) Start(ctx context.Context, id string) (*models.TimeEntry, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    task, err := s.Tasks.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    e := &models.TimeEntry{
+        ID:      uuid.New(),
+        TaskID:  task.ID,
+        UserID:  user.ID,
+        Start:   time.Now(),
+        Running: true,
+    }
+    if err := s.Store.Start(ctx, e); err != nil {
+        return nil, err
+    }
+    return e, nil
+}
+
+// Stop ends the caller's timer on the task with id and adds its time to the
+// task.
+func (s *TimeTracking) Stop(ctx context.Context, id string) (*models.TimeEntry, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    taskID, err := parseID("id", id)
+    if err != nil {
+        return nil, err
+    }
+    e, err := s.Store.Stop(ctx, user.ID, taskID, time.Now())
+    if err != nil {
+        return nil, err
+    }
+    if err := s.track(ctx, taskID, e.Seconds); err != nil {
+        return nil, err
+    }
+    return e, nil
+}
+
+// Running returns the caller's running timer.
+func (s *TimeTracking) Running(ctx context.Context) (*models.TimeEntry, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return s.Store.Running(ctx, user.ID)
+}
+
+// Add records time the caller spent on the task with id. Of entry only
+// Start, Note and either End or Seconds are used.
+func (s *TimeTracking) Add(ctx context.Context, id string, entry models.TimeEntry) (*models.TimeEntry, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    task, err := s.Tasks.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    e := &models.TimeEntry{
+        ID:     uuid.New(),
+        TaskID: task.ID,
+        UserID: user.ID,
+        Start:  entry.Start,
+        Manual: true,
+        Note:   entry.Note,
+    }
+    v := &models.ValidationError{}
+    switch {
+    case entry.Start.IsZero():
+        v.Add("start", "is required")
+    case entry.End != nil && entry.Seconds != 0:
+        v.Add("seconds", "must not be set together with end")
+    case entry.End != nil:
+        e.Seconds = int64(entry.End.Sub(entry.Start) / time.Second)
+        if e.Seconds <= 0 {
+            v.Add("end", "must be at least a second after start")
+        }
+    default:
+        e.Seconds = entry.Seconds
+        if e.Seconds <= 0 {
+            v.Add("seconds", "must be positive")
+        }
+    }
+    end := e.Start.Add(time.Duration(e.Seconds) * time.Second)
+    e.End = &end
+    if end.After(time.Now()) {
+        v.Add("start", "must not end in the future")
+    }
+    if err := v.Err(); err != nil {
+        return nil, err
+    }
+    if err := s.Store.Add(ctx, e); err != nil {
+        return nil, err
+    }
+    if err := s.track(ctx, task.ID, e.Seconds); err != nil {
+        return nil, err
+    }
+    return e, nil
+}
+
+// List returns the entries of the task with id, latest first.
+func (s *TimeTracking) List(ctx context.Context, id string) ([]models.TimeEntry, error) {
+    task, err := s.Tasks.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    return s.Store.ForTask(ctx, task.ID)
+}
+
+// Delete removes one of the caller's entries on the task with id and takes
+// its time off the task.
+func (s *TimeTracking) Delete(ctx context.Context, id, entryID string) error {
+    user, err := caller(ctx)
+    if err != nil {
+        return err
+    }
+    taskID, err := parseID("id", id)
+    if err != nil {
+        return err
+    }
+    eID, err := parseID("entryId", entryID)
+    if err != nil {
+        return err
+    }
+    entries, err := s.Store.ForTask(ctx, taskID)
+    if err != nil {
+        return err
+    }
+    var entry *models.TimeEntry
+    for i := range entries {
+        if entries[i].ID == eID {
+            entry = &entries[i]
+        }
+    }
+    if entry == nil {
+        return models.ErrEntryNotFound
+    }
+    if entry.UserID != user.ID {
+        return models.NewError(models.ErrForbidden, "only the user who tracked the time may delete it")
+    }
+    deleted, err := s.Store.Delete(ctx, eID)
+    if err != nil {
+        return err
+    }
+    return s.track(ctx, taskID, -deleted.Seconds)
+}
+
+// Report sums the finished entries that started on the UTC days from through
+// to by task, project and day.
+func (s *TimeTracking) Report(ctx context.Context, from, to time.Time) (*models.TimeReport, error) {
+    w := data.StatsWindow{From: from, To: to, Now: time.Now()}
+    if err := checkWindow(w); err != nil {
+        return nil, err
+    }
+    totals, err := s.Store.Totals(ctx, w)
+    if err != nil {
+        return nil, err
+    }
+    var ids []uuid.UUID
+    perTask := map[uuid.UUID]int64{}
+    perDay := map[string]int64{}
+    for _, t := range totals {
+        if _, ok := perTask[t.TaskID]; !ok {
+            ids = append(ids, t.TaskID)
+        }
+        perTask[t.TaskID] += t.Seconds
+        perDay[t.Date] += t.Seconds
+    }
+    tasks := map[uuid.UUID]models.Task{}
+    if len(ids) > 0 {
+        found, err := s.Tasks.Repo.GetByIds(ctx, ids)
+        if err != nil {
+            return nil, err
+        }
+        for _, t := range found {
+            tasks[t.ID] = t
+        }
+    }
+
+    first := from.UTC().Truncate(24 * time.Hour)
+    last := to.UTC().Truncate(24 * time.Hour)
+    report := &models.TimeReport{
+        From:      first.Format(time.DateOnly),
+        To:        last.Format(time.DateOnly),
+        ByTask:    []models.TaskTime{},
+        ByProject: []models.ProjectTime{},
+        ByDay:     []models.DayTime{},
+    }
+    perProject := map[string]int64{}
+    for _, id := range ids {
+        tt := models.TaskTime{TaskID: id, Seconds: perTask[id]}
+        if t, ok := tasks[id]; ok {
+            tt.Name = t.Name
+            tt.TrackedSeconds = t.TrackedSeconds
+            tt.EstimateSeconds = t.EstimateSeconds
+            if t.Project != nil {
+                tt.Project = *t.Project
+            }
+        }
+        report.TotalSeconds += tt.Seconds
+        report.ByTask = append(report.ByTask, tt)
+        perProject[tt.Project] += tt.Seconds
+    }
+    sort.Slice(report.ByTask, func(i, j int) bool {
+        a, b := report.ByTask[i], report.ByTask[j]
+        if a.Seconds != b.Seconds {
+            return a.Seconds > b.Seconds
+        }
+        return a.TaskID.String() < b.TaskID.String()
+    })
+    for project, n := range perProject {
+        report.ByProject = append(report.ByProject, models.ProjectTime{Project: project, Seconds: n})
+    }
+    sort.Slice(report.ByProject, func(i, j int) bool {
+        a, b := report.ByProject[i], report.ByProject[j]
+        if a.Seconds != b.Seconds {
+            return a.Seconds > b.Seconds
+        }
+        return a.Project < b.Project
+    })
+    for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+        key := d.Format(time.DateOnly)
+        report.ByDay = append(report.ByDay, models.DayTime{Date: key, Seconds: perDay[key]})
+    }
+    return report, nil
+}
+
+// track adds seconds to a task's total and announces the change. Time kept
+// on a task deleted since is dropped.
+func (s *TimeTracking) track(ctx context.Context, taskID uuid.UUID, seconds int64) error {
+    if seconds == 0 {
+        return nil
+    }
+    err := s.Tasks.Repo.AddTracked(ctx, taskID, seconds, time.Now())
+    if errors.Is(err, models.ErrTaskNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    _, err = s.Tasks.changed(ctx, taskID.String())
+    return err
+}
+
+func caller(ctx context.Context) (*models.User, error) {
+    user, ok := auth.User(ctx)
+    if !ok {
+        return nil, models.NewError(models.ErrUnauthorized, "authentication required")
+    }
+    return user, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "testing"
+    "time"
+)
+
+func TestTimeTracking(t *testing.T) {
+    user, _, _ := models.NewUser("ann", time.Now())
+    ctx := auth.WithUser(context.Background(), user)
+    tasks := NewTasks(data.NewRepo(nil, "", true), data.NewUserRepo(nil, "", true), nil)
+    s := NewTimeTracking(data.NewTimeRepo(nil, "", true), tasks)
+
+    project, estimate := "docs", int64(3*3600)
+    spec, _ := tasks.Create(ctx, models.Task{Name: "spec", Priority: models.High, Project: &project, EstimateSeconds: &estimate})
+    chores, _ := tasks.Create(ctx, models.Task{Name: "chores", Priority: models.Low})
+
+    // One running timer per user, whichever the task.
+    if _, err := s.Start(ctx, spec.ID.String()); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Start(ctx, chores.ID.String()); !errors.Is(err, models.ErrTimerRunning) {
+        t.Errorf("second Start error = %v; want ErrTimerRunning", err)
+    }
+    if _, err := s.Stop(ctx, spec.ID.String()); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Start(ctx, chores.ID.String()); err != nil {
+        t.Errorf("Start after Stop: %v", err)
+    }
+
+    at := func(s string) time.Time {
+        v, _ := time.Parse(time.RFC3339, s)
+        return v
+    }
+    end := at("2020-01-02T12:00:00Z")
+    for _, e := range []struct {
+        id    string
+        entry models.TimeEntry
+    }{
+        {spec.ID.String(), models.TimeEntry{Start: at("2020-01-01T09:00:00Z"), Seconds: 2 * 3600}},
+        {spec.ID.String(), models.TimeEntry{Start: at("2020-01-02T09:00:00Z"), End: &end}},
+        {chores.ID.String(), models.TimeEntry{Start: at("2020-01-02T10:00:00Z"), Seconds: 600}},
+        {chores.ID.String(), models.TimeEntry{Start: at("2020-02-01T10:00:00Z"), Seconds: 600}},
+    } {
+        if _, err := s.Add(ctx, e.id, e.entry); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if task, _ := tasks.Get(ctx, spec.ID.String()); task.TrackedSeconds != 5*3600 {
+        t.Errorf("tracked = %d; want %d", task.TrackedSeconds, 5*3600)
+    }
+
+    report, err := s.Report(ctx, at("2020-01-01T00:00:00Z"), at("2020-01-03T00:00:00Z"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    wantTasks := []models.TaskTime{
+        {TaskID: spec.ID, Name: "spec", Project: "docs", Seconds: 5 * 3600, TrackedSeconds: 5 * 3600, EstimateSeconds: &estimate},
+        {TaskID: chores.ID, Name: "chores", Seconds: 600, TrackedSeconds: 1200},
+    }
+    wantProjects := []models.ProjectTime{{Project: "docs", Seconds: 5 * 3600}, {Project: "", Seconds: 600}}
+    wantDays := []models.DayTime{{Date: "2020-01-01", Seconds: 2 * 3600}, {Date: "2020-01-02", Seconds: 3*3600 + 600}, {Date: "2020-01-03"}}
+    if report.TotalSeconds != 5*3600+600 || !reflect.DeepEqual(report.ByTask, wantTasks) ||
+        !reflect.DeepEqual(report.ByProject, wantProjects) || !reflect.DeepEqual(report.ByDay, wantDays) {
+        t.Errorf("report = %+v", report)
+    }
+
+    // The running timer is listed first, then the entry from February.
+    entries, _ := s.List(ctx, chores.ID.String())
+    if len(entries) != 3 || !entries[0].Running {
+        t.Fatalf("List = %+v; want the running timer first", entries)
+    }
+    if err := s.Delete(ctx, chores.ID.String(), entries[1].ID.String()); err != nil {
+        t.Fatal(err)
+    }
+    if task, _ := tasks.Get(ctx, chores.ID.String()); task.TrackedSeconds != 600 {
+        t.Errorf("tracked after delete = %d; want 600", task.TrackedSeconds)
+    }
+}
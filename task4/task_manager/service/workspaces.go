@@ -0,0 +1,187 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// Workspaces manages the workspaces tasks are kept apart in and who may use
+// them. Every method acts as the caller in ctx.
+type Workspaces struct {
+    Store *data.WorkspaceRepo
+    Users *data.UserRepo
+}
+
+func NewWorkspaces(store *data.WorkspaceRepo, users *data.UserRepo) *Workspaces {
+    return &Workspaces{Store: store, Users: users}
+}
+
+// Create stores a new workspace with the caller as its only owner. Only the
+// name and settings of ws are used.
+func (s *Workspaces) Create(ctx context.Context, ws models.Workspace) (*models.Workspace, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    ws.ID = uuid.New()
+    ws.Members = []models.Member{{UserID: user.ID, Role: models.RoleOwner}}
+    ws.CreatedAt = now
+    ws.UpdatedAt = now
+    if err := ws.Validate(); err != nil {
+        return nil, err
+    }
+    if err := s.Store.Create(ctx, &ws); err != nil {
+        return nil, err
+    }
+    return &ws, nil
+}
+
+// List returns the workspaces the caller is a member of.
+func (s *Workspaces) List(ctx context.Context) ([]models.Workspace, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return s.Store.ForUser(ctx, user.ID)
+}
+
+// Get returns the workspace with id. To anyone but its members it does not
+// exist.
+func (s *Workspaces) Get(ctx context.Context, id string) (*models.Workspace, error) {
+    ws, _, err := s.member(ctx, "id", id)
+    return ws, err
+}
+
+// Resolve returns the workspace a request made with ctx works in: the
+// default workspace for an empty id, else the workspace with id if the
+// caller is a member.
+func (s *Workspaces) Resolve(ctx context.Context, id string) (*models.Workspace, error) {
+    if id == "" {
+        return models.DefaultWorkspace(), nil
+    }
+    ws, _, err := s.member(ctx, "workspace_id", id)
+    return ws, err
+}
+
+// Update replaces the name and settings of the workspace with id. Only
+// owners may change them.
+func (s *Workspaces) Update(ctx context.Context, id string, changes models.Workspace) (*models.Workspace, error) {
+    ws, err := s.owned(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    ws.Name = changes.Name
+    ws.Settings = changes.Settings
+    ws.UpdatedAt = time.Now()
+    if err := ws.Validate(); err != nil {
+        return nil, err
+    }
+    if err := s.Store.Update(ctx, ws); err != nil {
+        return nil, err
+    }
+    return ws, nil
+}
+
+// SetMember adds the user in m to the workspace with id, or changes their
+// role. Only owners may change members, and the last owner may not be
+// demoted.
+func (s *Workspaces) SetMember(ctx context.Context, id string, m models.Member) (*models.Workspace, error) {
+    ws, err := s.owned(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if !models.ValidRoles[m.Role] {
+        return nil, models.NewValidationError("role", "must be one of owner, member")
+    }
+    missing, err := s.Users.Missing(ctx, []uuid.UUID{m.UserID})
+    if err != nil {
+        return nil, err
+    }
+    if len(missing) > 0 {
+        return nil, models.NewValidationError("user_id", "unknown user")
+    }
+    if err := s.Store.SetMember(ctx, ws.ID, m, time.Now()); err != nil {
+        return nil, err
+    }
+    return s.Store.GetById(ctx, ws.ID)
+}
+
+// RemoveMember takes userID out of the workspace with id. Owners may remove
+// anyone and members themselves, as long as an owner remains.
+func (s *Workspaces) RemoveMember(ctx context.Context, id string, userID uuid.UUID) (*models.Workspace, error) {
+    ws, role, err := s.member(ctx, "id", id)
+    if err != nil {
+        return nil, err
+    }
+    if user, _ := auth.User(ctx); role != models.RoleOwner && user.ID != userID {
+        return nil, models.NewError(models.ErrForbidden, "only owners may remove other members")
+    }
+    if err := s.Store.RemoveMember(ctx, ws.ID, userID, time.Now()); err != nil {
+        return nil, err
+    }
+    return s.Store.GetById(ctx, ws.ID)
+}
+
+// member loads the workspace with id, reporting a bad id under field, and
+// returns the caller's role in it.
+func (s *Workspaces) member(ctx context.Context, field, id string) (*models.Workspace, models.Role, error) {
+    user, err := caller(ctx)
+    if err != nil {
+        return nil, "", err
+    }
+    u, err := parseID(field, id)
+    if err != nil {
+        return nil, "", err
+    }
+    ws, err := s.Store.GetById(ctx, u)
+    if err != nil {
+        return nil, "", err
+    }
+    role, ok := ws.Role(user.ID)
+    if !ok {
+        return nil, "", models.ErrWorkspaceNotFound
+    }
+    return ws, role, nil
+}
+
+func (s *Workspaces) owned(ctx context.Context, id string) (*models.Workspace, error) {
+    ws, role, err := s.member(ctx, "id", id)
+    if err != nil {
+        return nil, err
+    }
+    if role != models.RoleOwner {
+        return nil, models.NewError(models.ErrForbidden, "only owners may change a workspace")
+    }
+    return ws, nil
+}
+
+// eachWorkspace calls fn with ctx scoped to the default workspace and then to
+// every stored one, for work done on behalf of all of them. It carries on
+// past failures and returns them joined.
+func (s *Tasks) eachWorkspace(ctx context.Context, fn func(context.Context) error) error {
+    all := []models.Workspace{*models.DefaultWorkspace()}
+    if s.Workspaces != nil {
+        stored, err := s.Workspaces.All(ctx)
+        if err != nil {
+            return err
+        }
+        all = append(all, stored...)
+    }
+    var errs []error
+    for i := range all {
+        if err := fn(data.WithWorkspace(ctx, &all[i])); err != nil {
+            errs = append(errs, err)
+        }
+        if ctx.Err() != nil {
+            break
+        }
+    }
+    return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "task_manager/auth"
+    "task_manager/data"
+    "task_manager/models"
+    "testing"
+    "time"
+)
+
+func TestWorkspaceIsolation(t *testing.T) {
+    users := data.NewUserRepo(nil, "", true)
+    ann, _, _ := models.NewUser("ann", time.Now())
+    users.Create(context.Background(), ann)
+    ctx := auth.WithUser(context.Background(), ann)
+    tasks := NewTasks(data.NewRepo(nil, "", true), users, nil)
+    tasks.Workspaces = data.NewWorkspaceRepo(nil, "", true)
+    s := NewWorkspaces(tasks.Workspaces, users)
+
+    ws, err := s.Create(ctx, models.Workspace{Name: "ops", Settings: models.WorkspaceSettings{
+        DefaultPriority: models.Medium,
+        AllowedStatuses: []models.State{models.Pending, models.Completed},
+    }})
+    if err != nil {
+        t.Fatal(err)
+    }
+    resolved, err := s.Resolve(ctx, ws.ID.String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    inWS := data.WithWorkspace(ctx, resolved)
+
+    task, err := tasks.Create(inWS, models.Task{Name: "rotate keys"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if task.Priority != models.Medium || task.WorkspaceID != ws.ID {
+        t.Errorf("created task has priority %q in workspace %v; want medium in %v", task.Priority, task.WorkspaceID, ws.ID)
+    }
+    if _, err := tasks.Create(ctx, models.Task{Name: "no priority"}); err == nil {
+        t.Error("Create without a priority in the default workspace succeeded")
+    }
+
+    // Nothing of one workspace is reachable from another.
+    if _, err := tasks.Get(ctx, task.ID.String()); !errors.Is(err, models.ErrTaskNotFound) {
+        t.Errorf("Get from the default workspace error = %v; want ErrTaskNotFound", err)
+    }
+    if err := tasks.Delete(ctx, task.ID.String()); !errors.Is(err, models.ErrTaskNotFound) {
+        t.Errorf("Delete from the default workspace error = %v; want ErrTaskNotFound", err)
+    }
+    if list, _ := tasks.List(ctx); len(list) != 0 {
+        t.Errorf("default workspace lists %d tasks; want 0", len(list))
+    }
+    if all, _ := tasks.ListAll(ctx); len(all) != 1 {
+        t.Errorf("ListAll = %d tasks; want 1", len(all))
+    }
+
+    // Settings limit where tasks may go.
+    if _, err := tasks.Update(inWS, task.ID.String(), models.Task{Status: models.InProgress}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("Update to a disallowed status error = %v; want a validation error", err)
+    }
+    if _, err := tasks.Move(inWS, task.ID.String(), Placement{Status: models.InProgress}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("Move to a disallowed status error = %v; want a validation error", err)
+    }
+    board, err := tasks.Board(inWS)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := board[models.InProgress]; ok || len(board[models.Pending]) != 1 {
+        t.Errorf("board = %v; want only the allowed columns with the task pending", board)
+    }
+}
+
+func TestWorkspaceMembers(t *testing.T) {
+    users := data.NewUserRepo(nil, "", true)
+    ann, _, _ := models.NewUser("ann", time.Now())
+    bob, _, _ := models.NewUser("bob", time.Now())
+    users.Create(context.Background(), ann)
+    users.Create(context.Background(), bob)
+    asAnn := auth.WithUser(context.Background(), ann)
+    asBob := auth.WithUser(context.Background(), bob)
+    s := NewWorkspaces(data.NewWorkspaceRepo(nil, "", true), users)
+
+    ws, err := s.Create(asAnn, models.Workspace{Name: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    id := ws.ID.String()
+    if _, err := s.Resolve(asBob, id); !errors.Is(err, models.ErrWorkspaceNotFound) {
+        t.Errorf("Resolve by a non-member error = %v; want ErrWorkspaceNotFound", err)
+    }
+    if _, err := s.SetMember(asAnn, id, models.Member{UserID: bob.ID, Role: models.RoleMember}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.SetMember(asBob, id, models.Member{UserID: bob.ID, Role: models.RoleOwner}); !errors.Is(err, models.ErrForbidden) {
+        t.Errorf("member promoting themselves error = %v; want ErrForbidden", err)
+    }
+    if _, err := s.SetMember(asAnn, id, models.Member{UserID: ann.ID, Role: models.RoleMember}); !errors.Is(err, models.ErrLastOwner) {
+        t.Errorf("demoting the last owner error = %v; want ErrLastOwner", err)
+    }
+    if list, _ := s.List(asBob); len(list) != 1 {
+        t.Errorf("bob's workspaces = %d; want 1", len(list))
+    }
+    if _, err := s.RemoveMember(asBob, id, bob.ID); err != nil {
+        t.Errorf("leaving: %v", err)
+    }
+    if list, _ := s.List(asBob); len(list) != 0 {
+        t.Errorf("bob's workspaces after leaving = %d; want 0", len(list))
+    }
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const ServiceName = "task_manager"
+
+type Options struct {
+    // Exporter is "none", "stdout" or "file".
+    Exporter    string
+    File        string
+    SampleRatio float64
+}
+
+// Setup installs the global tracer provider and W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(opts Options) (func(context.Context) error, error) {
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+        propagation.TraceContext{},
+        propagation.Baggage{},
+    ))
+    if opts.Exporter == "none" {
+        return func(context.Context) error { return nil }, nil
+    }
+
+    var (
+        w     io.Writer = os.Stdout
+        close           = func() error { return nil }
+    )
+    if opts.Exporter == "file" {
+        f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+        if err != nil {
+            return nil, fmt.Errorf("opening trace file: %w", err)
+        }
+        w, close = f, f.Close
+    }
+    exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+    if err != nil {
+        close()
+        return nil, err
+    }
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+        sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+    )
+    otel.SetTracerProvider(provider)
+
+    return func(ctx context.Context) error {
+        err := provider.Shutdown(ctx)
+        if cerr := close(); err == nil {
+            err = cerr
+        }
+        return err
+    }, nil
+}